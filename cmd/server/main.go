@@ -69,6 +69,21 @@ func main() {
 	}
 	logger.Info("Scheduler started successfully")
 
+	// 备份完整性巡检
+	if err := schedulerService.AddScrubJob(cfg.Backup.ScrubCron, cfg.Backup.ScrubBatch); err != nil {
+		logger.Error("Failed to schedule backup scrub: %v", err)
+	}
+
+	// 存储容量检查
+	if err := schedulerService.AddQuotaJob(cfg.Backup.QuotaCron); err != nil {
+		logger.Error("Failed to schedule storage quota check: %v", err)
+	}
+
+	// 存储对账
+	if err := schedulerService.AddReconcileJob(cfg.Backup.ReconcileCron); err != nil {
+		logger.Error("Failed to schedule storage reconcile: %v", err)
+	}
+
 	// 创建Gin路由（传递调度器服务）
 	router := api.SetupRouter()
 
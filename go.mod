@@ -10,6 +10,9 @@ require (
 	github.com/go-co-op/gocron/v2 v2.19.0
 	github.com/go-sql-driver/mysql v1.9.3
 	github.com/golang-jwt/jwt/v5 v5.3.0
+	github.com/klauspost/compress v1.18.0
+	github.com/pierrec/lz4/v4 v4.1.22
+	github.com/ulikunitz/xz v0.5.12
 	golang.org/x/crypto v0.47.0
 	golang.org/x/sys v0.40.0
 	gopkg.in/gomail.v2 v2.0.0-20160411212932-81ebce5c23df
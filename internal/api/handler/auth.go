@@ -57,3 +57,21 @@ func Login(c *gin.Context) {
 func Logout(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
 }
+
+// isAdmin 检查当前登录用户是否为管理员
+func isAdmin(c *gin.Context) bool {
+	userID, ok := c.Get("user_id")
+	if !ok {
+		return false
+	}
+	id, ok := userID.(float64) // JWT claims中的数字解析为float64
+	if !ok {
+		return false
+	}
+
+	var user model.User
+	if err := database.DB.First(&user, uint(id)).Error; err != nil {
+		return false
+	}
+	return user.Role == "admin" && user.Status == 1
+}
@@ -2,19 +2,24 @@ package handler
 
 import (
 	"context"
+	"mbmanager/internal/backup"
 	"mbmanager/internal/database"
 	"mbmanager/internal/logger"
 	"mbmanager/internal/model"
 	"mbmanager/internal/service"
 	"mbmanager/internal/storage"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -69,6 +74,10 @@ func CreateHost(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Connection test failed: %v", err)})
 		return
 	}
+	if err := validateBackupSource(hostSvc, &host); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := database.DB.Create(&host).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -109,6 +118,10 @@ func UpdateHost(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Connection test failed: %v", err)})
 		return
 	}
+	if err := validateBackupSource(hostSvc, &host); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := database.DB.Save(&host).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -118,6 +131,58 @@ func UpdateHost(c *gin.Context) {
 	c.JSON(http.StatusOK, host)
 }
 
+// validateBackupSource 检查备份来源配置，从库模式要求主机已配置复制
+func validateBackupSource(hostSvc *service.HostService, host *model.Host) error {
+	switch host.BackupSource {
+	case "", "primary":
+		return nil
+	case "replica":
+		status, err := hostSvc.GetReplicaStatus(host)
+		if err != nil {
+			return err
+		}
+		if status == nil {
+			return fmt.Errorf("host is not a replica, backup source cannot be replica")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported backup source: %s", host.BackupSource)
+	}
+}
+
+// GetHostReplicaStatus 获取主机的复制状态
+func GetHostReplicaStatus(c *gin.Context) {
+	id := c.Param("id")
+	var host model.Host
+	if err := database.DB.First(&host, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Host not found"})
+		return
+	}
+
+	status, err := service.NewHostService().GetReplicaStatus(&host)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if status == nil {
+		c.JSON(http.StatusOK, gin.H{"is_replica": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"is_replica":            true,
+		"source_host":           status.SourceHost,
+		"source_port":           status.SourcePort,
+		"source_log_file":       status.SourceLogFile,
+		"source_log_pos":        status.SourceLogPos,
+		"executed_gtid_set":     status.ExecutedGTIDSet,
+		"seconds_behind_source": status.SecondsBehind,
+		"io_running":            status.IORunning,
+		"sql_running":           status.SQLRunning,
+		"last_error":            status.LastError,
+	})
+}
+
 // DeleteHost 删除主机
 func DeleteHost(c *gin.Context) {
 	id := c.Param("id")
@@ -199,6 +264,48 @@ func CreateTask(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if !backup.ValidCompressionType(task.CompressionType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported compression type: " + task.CompressionType})
+		return
+	}
+	if err := backup.ValidateCompressionOptions(task.CompressionType, task.CompressLevel, task.CompressThreads); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if task.EncryptionKeyID != "" {
+		if _, err := service.NewEncryptionService().LoadKey(task.EncryptionKeyID, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if err := service.ValidateTableFilters(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.ValidateHooks(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if service.TaskCommandHooks(&task) != "" && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can configure shell or ssh hooks"})
+		return
+	}
+	if service.TaskRestoreCommands(&task) != "" && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can configure restore commands"})
+		return
+	}
+	if err := service.ValidateRetryPolicy(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.ValidateCopyStorages(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.ValidateRetentionPolicy(&task); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := database.DB.Create(&task).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -227,7 +334,77 @@ func GetTask(c *gin.Context) {
 	c.JSON(http.StatusOK, task)
 }
 
-// UpdateTask 更新任务
+// PreviewTaskRetention 预览保留策略会保留和删除的备份，查询参数可覆盖任务当前的策略，便于修改前预览
+func PreviewTaskRetention(c *gin.Context) {
+	var task model.Task
+	if err := database.DB.First(&task, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task not found"})
+		return
+	}
+
+	policy := service.TaskRetentionPolicy(&task)
+	overrides := map[string]*int{
+		"retention_days": &policy.RetentionDays,
+		"keep_daily":     &policy.KeepDaily,
+		"keep_weekly":    &policy.KeepWeekly,
+		"keep_monthly":   &policy.KeepMonthly,
+		"keep_yearly":    &policy.KeepYearly,
+		"min_keep":       &policy.MinKeep,
+	}
+	for name, field := range overrides {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+			return
+		}
+		*field = n
+	}
+
+	c.JSON(http.StatusOK, service.PlanRetention(&task, policy, time.Now()))
+}
+
+// taskUpdateFields 更新任务时可修改的字段，键为请求中的JSON字段名，值为结构体字段名
+var taskUpdateFields = map[string]string{
+	"name":                "Name",
+	"host_id":             "HostID",
+	"databases":           "Databases",
+	"backup_type":         "BackupType",
+	"schedule_type":       "ScheduleType",
+	"schedule_config":     "ScheduleConfig",
+	"storage_id":          "StorageID",
+	"copy_storages":       "CopyStorages",
+	"retention_days":      "RetentionDays",
+	"keep_daily":          "KeepDaily",
+	"keep_weekly":         "KeepWeekly",
+	"keep_monthly":        "KeepMonthly",
+	"keep_yearly":         "KeepYearly",
+	"min_keep":            "MinKeep",
+	"notification_ids":    "NotificationIDs",
+	"notify_on_success":   "NotifyOnSuccess",
+	"notify_on_failure":   "NotifyOnFailure",
+	"include_tables":      "IncludeTables",
+	"exclude_tables":      "ExcludeTables",
+	"backup_options":      "BackupOptions",
+	"compression_type":    "CompressionType",
+	"compression_level":   "CompressLevel",
+	"compression_threads": "CompressThreads",
+	"encryption_key_id":   "EncryptionKeyID",
+	"verify_policy":       "VerifyPolicy",
+	"hooks":               "Hooks",
+	"streaming":           "Streaming",
+	"max_runtime":         "MaxRuntime",
+	"retry_max_attempts":  "RetryMaxAttempts",
+	"retry_backoff":       "RetryBackoff",
+	"retry_on":            "RetryOn",
+	"status":              "Status",
+}
+
+// UpdateTask 更新任务。只更新请求中提交的可编辑字段（见taskUpdateFields），未提交的字段保持不变；
+// 提交的零值会保存，用于清空钩子、表过滤、副本存储、加密密钥或把重试、保留数量设为0
 func UpdateTask(c *gin.Context) {
 	id := c.Param("id")
 	var task model.Task
@@ -236,19 +413,75 @@ func UpdateTask(c *gin.Context) {
 		return
 	}
 
-	// 绑定更新数据到新的结构体
-	var updateData model.Task
-	if err := c.ShouldBindJSON(&updateData); err != nil {
+	// 请求中的字段覆盖到原任务上，未提交的字段沿用原值，提交的零值（如清空钩子、重试次数设为0）也会保存
+	var fields map[string]interface{}
+	if err := c.ShouldBindBodyWith(&fields, binding.JSON); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	updateData := task
+	if err := c.ShouldBindBodyWith(&updateData, binding.JSON); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	// 保留ID，使用Updates更新
 	updateData.ID = task.ID
-	if err := database.DB.Model(&task).Updates(&updateData).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if !backup.ValidCompressionType(updateData.CompressionType) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported compression type: " + updateData.CompressionType})
+		return
+	}
+	if err := backup.ValidateCompressionOptions(updateData.CompressionType, updateData.CompressLevel, updateData.CompressThreads); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if updateData.EncryptionKeyID != "" {
+		if _, err := service.NewEncryptionService().LoadKey(updateData.EncryptionKeyID, true); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := service.ValidateTableFilters(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.ValidateHooks(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if service.TaskCommandHooks(&updateData) != service.TaskCommandHooks(&task) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can configure shell or ssh hooks"})
 		return
 	}
+	if service.TaskRestoreCommands(&updateData) != service.TaskRestoreCommands(&task) && !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can configure restore commands"})
+		return
+	}
+	if err := service.ValidateRetryPolicy(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.ValidateCopyStorages(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := service.ValidateRetentionPolicy(&updateData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 只更新请求中提交的可编辑字段
+	var columns []string
+	for key, field := range taskUpdateFields {
+		if _, ok := fields[key]; ok {
+			columns = append(columns, field)
+		}
+	}
+	if len(columns) > 0 {
+		if err := database.DB.Model(&task).Select(columns).Updates(&updateData).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
 	// 重新加载任务以获取最新数据
 	if err := database.DB.First(&task, id).Error; err != nil {
@@ -350,6 +583,33 @@ func GetTaskLogs(c *gin.Context) {
 	})
 }
 
+// GetTaskBinlogs 获取任务归档的binlog文件
+func GetTaskBinlogs(c *gin.Context) {
+	id := c.Param("id")
+	var binlogs []model.BinlogFile
+
+	// 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	offset := (page - 1) * pageSize
+
+	var total int64
+	query := database.DB.Model(&model.BinlogFile{}).Where("task_id = ?", id)
+	query.Count(&total)
+
+	if err := query.Order("file_name DESC").Offset(offset).Limit(pageSize).Find(&binlogs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"binlogs":   binlogs,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
 // GetStorages 获取存储列表
 func GetStorages(c *gin.Context) {
 	var storages []model.Storage
@@ -367,6 +627,10 @@ func CreateStorage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := service.ValidateStorageQuota(&storage); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := database.DB.Create(&storage).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -400,6 +664,10 @@ func UpdateStorage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if err := service.ValidateStorageQuota(&storage); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	if err := database.DB.Save(&storage).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -510,6 +778,49 @@ func GetStorageDiskSpace(c *gin.Context) {
 	})
 }
 
+// GetStorageQuota 获取存储当前的容量使用情况和超出量，不做清理
+func GetStorageQuota(c *gin.Context) {
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Storage not found"})
+		return
+	}
+
+	usage, err := service.MeasureStorageUsage(c.Request.Context(), &storageModel)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to measure storage usage: %v", err)})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"storage_id":       storageModel.ID,
+		"quota_bytes":      storageModel.QuotaBytes,
+		"min_free_percent": storageModel.MinFreePercent,
+		"usage":            usage,
+		"excess_bytes":     service.StorageQuotaExcess(&storageModel, usage),
+		"quota_status":     storageModel.QuotaStatus,
+		"quota_checked_at": storageModel.QuotaCheckedAt,
+	})
+}
+
+// EnforceStorageQuota 立即检查存储容量，超出限制时清理最旧的备份
+func EnforceStorageQuota(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid storage ID"})
+		return
+	}
+
+	result, err := service.EnforceStorageQuota(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Quota of storage %d enforced by %s: %s", id, c.GetString("username"), result.Status)
+	c.JSON(http.StatusOK, result)
+}
+
 // GetNotifications 获取通知列表
 func GetNotifications(c *gin.Context) {
 	var notifications []model.Notification
@@ -674,13 +985,113 @@ func GetLog(c *gin.Context) {
 	c.JSON(http.StatusOK, log)
 }
 
-// DeleteLog 删除日志
-func DeleteLog(c *gin.Context) {
-	id := c.Param("id")
-	if err := database.DB.Delete(&model.BackupLog{}, id).Error; err != nil {
+// GetLogProgress 获取备份进度，备份已结束时返回最终状态
+func GetLogProgress(c *gin.Context) {
+	var log model.BackupLog
+	if err := database.DB.First(&log, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Log not found"})
+		return
+	}
+
+	if state, ok := service.GetBackupProgress(log.ID); ok {
+		c.JSON(http.StatusOK, state)
+		return
+	}
+	c.JSON(http.StatusOK, service.FinishedBackupProgress(&log))
+}
+
+// StreamLogProgress 以Server-Sent Events推送备份进度，备份结束时发送done事件后关闭
+func StreamLogProgress(c *gin.Context) {
+	var log model.BackupLog
+	if err := database.DB.First(&log, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Log not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+
+	updates, cancel, ok := service.SubscribeBackupProgress(log.ID)
+	if !ok {
+		c.SSEvent("done", service.FinishedBackupProgress(&log))
+		return
+	}
+	defer cancel()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case state, ok := <-updates:
+			if !ok {
+				// 备份结束，重新读取日志得到最终结果
+				database.DB.First(&log, log.ID)
+				c.SSEvent("done", service.FinishedBackupProgress(&log))
+				return false
+			}
+			c.SSEvent("progress", state)
+			return true
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// CancelLog 取消运行中的备份
+func CancelLog(c *gin.Context) {
+	var log model.BackupLog
+	if err := database.DB.First(&log, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Log not found"})
+		return
+	}
+	if log.Status != "running" && log.NextRetryAt == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Backup is not running"})
+		return
+	}
+
+	// 运行中的备份或等待重试的失败尝试
+	if service.CancelBackup(log.ID) {
+		logger.Info("Cancellation of backup %d requested by %s", log.ID, c.GetString("username"))
+		c.JSON(http.StatusOK, gin.H{"message": "Cancellation requested"})
+		return
+	}
+
+	// 等待重试但没有对应的执行（服务重启前中断），清除重试时间
+	if log.Status != "running" {
+		if err := database.DB.Model(&log).Update("next_retry_at", nil).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Retry is no longer pending"})
+		return
+	}
+
+	// 日志为running但没有对应的执行（服务重启前中断的备份），直接标记为已取消
+	now := time.Now()
+	log.Status = "cancelled"
+	log.ErrorMessage = "backup was interrupted and is no longer running"
+	log.EndTime = &now
+	if err := database.DB.Save(&log).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup is no longer running, marked as cancelled"})
+}
+
+// DeleteLog 删除日志，与删除备份一样同时删除备份文件和副本
+func DeleteLog(c *gin.Context) {
+	var log model.BackupLog
+	if err := database.DB.First(&log, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Log not found"})
+		return
+	}
+	if !deleteBackupLog(c, &log) {
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Log deleted successfully"})
 }
 
@@ -695,27 +1106,47 @@ func DeleteBackup(c *gin.Context) {
 		return
 	}
 
-	// 删除备份文件
+	if !deleteBackupLog(c, &log) {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted successfully"})
+}
+
+// deleteBackupLog 删除备份在主存储和副本存储中的文件及日志记录，失败时写入错误响应并返回false
+func deleteBackupLog(c *gin.Context, log *model.BackupLog) bool {
+	// 增量/差异备份依赖的基础备份不能删除
+	if service.HasDependentBackups(log.ID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Backup is required by incremental backups, delete them first"})
+		return false
+	}
+
+	// 删除主存储和副本存储中的备份文件
 	if log.Status == "success" && log.FilePath != "" {
-		// 加载任务信息以获取存储配置
-		var task model.Task
-		if err := database.DB.Preload("Storage").First(&task, log.TaskID).Error; err == nil {
-			// 删除存储中的文件
-			backupSvc := service.NewBackupService()
-			if err := backupSvc.DeleteBackupFile(&task, log.FilePath); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete backup file: %v", err)})
-				return
+		backupSvc := service.NewBackupService()
+		if err := backupSvc.DeleteBackupArtifacts(log); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete backup file: %v", err)})
+			return false
+		}
+	} else {
+		// 上传后被记为失败且未能清理的备份仍记录着路径，尽量删除残留的文件
+		if log.FilePath != "" {
+			if err := service.NewBackupService().DeleteBackupArtifacts(log); err != nil {
+				logger.Error("Failed to delete artifacts of backup %d: %v", log.ID, err)
 			}
 		}
+		if err := service.DeleteBackupCopies(log.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to delete backup copies: %v", err)})
+			return false
+		}
 	}
 
 	// 删除日志记录
-	if err := database.DB.Delete(&log).Error; err != nil {
+	if err := database.DB.Delete(log).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return false
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted successfully"})
+	return true
 }
 
 // DownloadBackup 下载备份文件
@@ -734,39 +1165,358 @@ func DownloadBackup(c *gin.Context) {
 		return
 	}
 
-	// 加载任务和存储信息
-	var task model.Task
-	if err := database.DB.Preload("Storage").First(&task, log.TaskID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load task"})
+	// 下载文件到临时目录，主存储不可用时从副本存储下载
+	tmpFile := filepath.Join(os.TempDir(), filepath.Base(log.FilePath))
+	ctx := context.Background()
+
+	if err := service.DownloadBackupArtifact(ctx, &log, tmpFile); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to download file: %v", err)})
 		return
 	}
+	defer os.Remove(tmpFile)
 
-	// 解析存储配置
-	var storageConfig map[string]interface{}
-	if err := json.Unmarshal([]byte(task.Storage.Config), &storageConfig); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse storage config"})
+	// 校验下载的文件与备份时记录的校验和一致
+	if log.Checksum != "" {
+		integritySvc := service.NewIntegrityService()
+		checksum, err := backup.FileChecksum(tmpFile)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if checksum != log.Checksum {
+			integritySvc.RecordIntegrity(&log, "corrupted")
+			logger.Error("Checksum mismatch of backup %d: expected %s, got %s", log.ID, log.Checksum, checksum)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Backup file checksum mismatch, the file may be corrupted or tampered"})
+			return
+		}
+		integritySvc.RecordIntegrity(&log, "ok")
+		c.Header("X-Checksum-SHA256", checksum)
+	}
+
+	// 加密备份：管理员下载时透明解密，其他用户或指定raw=true时返回密文
+	downloadFile := tmpFile
+	if log.EncryptionKeyID != "" && c.Query("raw") != "true" && isAdmin(c) {
+		plainFile, err := service.NewEncryptionService().DecryptFile(tmpFile, log.EncryptionKeyID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		defer os.Remove(plainFile)
+		downloadFile = plainFile
+	}
+
+	// 发送文件（按压缩格式设置Content-Type）
+	c.Header("Content-Type", backup.ContentType(downloadFile))
+	c.FileAttachment(downloadFile, filepath.Base(downloadFile))
+}
+
+// RestoreBackup 恢复备份到指定主机
+func RestoreBackup(c *gin.Context) {
+	id := c.Param("id")
+
+	// 解析ID
+	logID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup log ID"})
 		return
 	}
 
-	// 创建存储实例
-	storageInstance, err := storage.NewStorage(task.Storage.Type, storageConfig)
+	var req service.RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req.AllowDecrypt = isAdmin(c)
+
+	restoreSvc := service.NewRestoreService()
+	restoreLog, err := restoreSvc.StartRestore(uint(logID), &req)
+	if errors.Is(err, service.ErrDecryptNotAllowed) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create storage instance"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 下载文件到临时目录
-	tmpFile := filepath.Join(os.TempDir(), filepath.Base(log.FilePath))
-	ctx := context.Background()
+	c.JSON(http.StatusAccepted, restoreLog)
+}
 
-	if err := storageInstance.Download(ctx, log.FilePath, tmpFile); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Failed to download file: %v", err)})
+// VerifyBackupRestore 在沙箱主机中恢复备份并验证
+func VerifyBackupRestore(c *gin.Context) {
+	id := c.Param("id")
+
+	// 解析ID
+	logID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup log ID"})
 		return
 	}
-	defer os.Remove(tmpFile)
 
-	// 发送文件
-	c.FileAttachment(tmpFile, filepath.Base(log.FilePath))
+	var backupLog model.BackupLog
+	if err := database.DB.First(&backupLog, logID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup log not found"})
+		return
+	}
+	if backupLog.VerifyStatus == "verifying" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Backup is already being verified"})
+		return
+	}
+	// 恢复验证会解密加密备份，与下载一样只允许管理员
+	if !isAdmin(c) && service.BackupChainEncrypted(&backupLog) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can verify encrypted backups"})
+		return
+	}
+
+	// 恢复验证耗时较长，在后台执行，结果写入备份日志
+	verifySvc := service.NewVerifyService()
+	go func() {
+		if err := verifySvc.VerifyBackup(context.Background(), uint(logID)); err != nil {
+			logger.Error("Backup %d verification failed: %v", logID, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Backup verification started"})
+}
+
+// VerifyBackupChecksum 通过存储重新读取备份文件并校验SHA-256
+func VerifyBackupChecksum(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup log ID"})
+		return
+	}
+
+	result, err := service.NewIntegrityService().VerifyBackup(c.Request.Context(), uint(id))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetBackupCopies 获取备份在各副本存储中的副本及校验状态
+func GetBackupCopies(c *gin.Context) {
+	var log model.BackupLog
+	if err := database.DB.First(&log, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup log not found"})
+		return
+	}
+
+	var copies []model.BackupCopy
+	database.DB.Where("backup_log_id = ?", log.ID).Order("id").Find(&copies)
+	c.JSON(http.StatusOK, copies)
+}
+
+// GetRestores 获取恢复记录列表
+func GetRestores(c *gin.Context) {
+	var restores []model.RestoreLog
+
+	// 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	offset := (page - 1) * pageSize
+
+	// 筛选参数
+	status := c.Query("status")
+	backupLogID := c.Query("backup_log_id")
+	hostID := c.Query("host_id")
+
+	query := database.DB.Model(&model.RestoreLog{})
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if backupLogID != "" {
+		query = query.Where("backup_log_id = ?", backupLogID)
+	}
+	if hostID != "" {
+		query = query.Where("host_id = ?", hostID)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&restores).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"restores":  restores,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetRestore 获取恢复记录详情
+func GetRestore(c *gin.Context) {
+	id := c.Param("id")
+	var restoreLog model.RestoreLog
+	if err := database.DB.First(&restoreLog, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Restore log not found"})
+		return
+	}
+	c.JSON(http.StatusOK, restoreLog)
+}
+
+// MigrateBackups 创建备份迁移任务，将源存储中符合条件的备份复制或迁移到目标存储
+func MigrateBackups(c *gin.Context) {
+	var req service.MigrationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	job, err := service.NewMigrationService().StartMigration(&req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Migration job %d from storage %d to %d started by %s", job.ID, job.SourceStorageID, job.DestStorageID, c.GetString("username"))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetMigrations 获取迁移任务列表
+func GetMigrations(c *gin.Context) {
+	var jobs []model.MigrationJob
+
+	// 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	offset := (page - 1) * pageSize
+
+	query := database.DB.Model(&model.MigrationJob{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	if err := query.Omit("steps").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"migrations": jobs,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+// GetMigration 获取迁移任务详情
+func GetMigration(c *gin.Context) {
+	var job model.MigrationJob
+	if err := database.DB.First(&job, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Migration job not found"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// StartReconcile 对账存储中的文件与备份目录
+func StartReconcile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid storage ID"})
+		return
+	}
+
+	job, err := service.NewReconcileService().StartReconcile(uint(id), c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Reconcile job %d of storage %d started by %s", job.ID, job.StorageID, c.GetString("username"))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetReconciles 获取对账任务列表
+func GetReconciles(c *gin.Context) {
+	var jobs []model.ReconcileJob
+
+	// 分页参数
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	offset := (page - 1) * pageSize
+
+	query := database.DB.Model(&model.ReconcileJob{})
+	if storageID := c.Query("storage_id"); storageID != "" {
+		query = query.Where("storage_id = ?", storageID)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	if err := query.Omit("steps").Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&jobs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reconciles": jobs,
+		"total":      total,
+		"page":       page,
+		"page_size":  pageSize,
+	})
+}
+
+// GetReconcile 获取对账任务详情及发现的不一致
+func GetReconcile(c *gin.Context) {
+	var job model.ReconcileJob
+	if err := database.DB.First(&job, c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reconcile job not found"})
+		return
+	}
+
+	var findings []model.ReconcileFinding
+	query := database.DB.Where("job_id = ?", job.ID)
+	if kind := c.Query("kind"); kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if resolution := c.Query("resolution"); resolution != "" {
+		query = query.Where("resolution = ?", resolution)
+	}
+	query.Order("id").Find(&findings)
+
+	c.JSON(http.StatusOK, gin.H{
+		"job":      job,
+		"findings": findings,
+	})
+}
+
+// ResolveReconcileFindings 处理对账发现的不一致：领养孤立文件、标记或删除有问题的产物
+func ResolveReconcileFindings(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reconcile job ID"})
+		return
+	}
+
+	var req service.ReconcileResolveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := service.NewReconcileService().ResolveFindings(c.Request.Context(), uint(id), &req, c.GetString("username"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
 }
 
 // GetUsers 获取用户列表
@@ -845,3 +1595,115 @@ func DeleteUser(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
+
+// GetEncryptionKeys 获取加密密钥列表（不包含密钥内容）
+func GetEncryptionKeys(c *gin.Context) {
+	var keys []model.EncryptionKey
+	if err := database.DB.Find(&keys).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateEncryptionKey 登记加密密钥，未提供key_data时生成随机密钥
+func CreateEncryptionKey(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can manage encryption keys"})
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name" binding:"required"`
+		Description string `json:"description"`
+		KeyData     string `json:"key_data"` // base64或hex编码的32字节密钥
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key := &model.EncryptionKey{
+		Name:        req.Name,
+		Description: req.Description,
+		Status:      1,
+	}
+	keyData, err := service.NewEncryptionService().CreateKey(key, req.KeyData)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Encryption key created: %s (%s)", key.Name, key.KeyID)
+
+	// 密钥内容只在创建时返回一次，需要妥善保管
+	c.JSON(http.StatusCreated, gin.H{"key": key, "key_data": keyData})
+}
+
+// UpdateEncryptionKey 更新加密密钥的名称、描述和状态（密钥内容不可修改）
+func UpdateEncryptionKey(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can manage encryption keys"})
+		return
+	}
+
+	id := c.Param("id")
+	var key model.EncryptionKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Encryption key not found"})
+		return
+	}
+
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Status      *int   `json:"status"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updates := map[string]interface{}{"description": req.Description}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Status != nil {
+		updates["status"] = *req.Status
+	}
+	if err := database.DB.Model(&key).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, key)
+}
+
+// DeleteEncryptionKey 删除加密密钥，仍被任务或备份使用时不能删除
+func DeleteEncryptionKey(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only administrators can manage encryption keys"})
+		return
+	}
+
+	id := c.Param("id")
+	var key model.EncryptionKey
+	if err := database.DB.First(&key, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Encryption key not found"})
+		return
+	}
+
+	// 删除密钥后使用该密钥的备份将无法恢复
+	if service.NewEncryptionService().KeyInUse(key.KeyID) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Encryption key is used by tasks or backups"})
+		return
+	}
+
+	if err := database.DB.Delete(&key).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	logger.Info("Encryption key deleted: %s (%s)", key.Name, key.KeyID)
+	c.JSON(http.StatusOK, gin.H{"message": "Encryption key deleted successfully"})
+}
@@ -54,6 +54,7 @@ func SetupRouter() *gin.Engine {
 				hosts.PUT("/:id", handler.UpdateHost)
 				hosts.DELETE("/:id", handler.DeleteHost)
 				hosts.POST("/:id/test", handler.TestHostConnection)
+				hosts.GET("/:id/replica-status", handler.GetHostReplicaStatus)
 			}
 
 			// 任务管理
@@ -66,6 +67,8 @@ func SetupRouter() *gin.Engine {
 				tasks.DELETE("/:id", handler.DeleteTask)
 				tasks.POST("/:id/run", handler.RunTask)
 				tasks.GET("/:id/logs", handler.GetTaskLogs)
+				tasks.GET("/:id/binlogs", handler.GetTaskBinlogs)
+				tasks.GET("/:id/retention/preview", handler.PreviewTaskRetention)
 			}
 
 			// 存储管理
@@ -78,6 +81,9 @@ func SetupRouter() *gin.Engine {
 				storages.DELETE("/:id", handler.DeleteStorage)
 				storages.POST("/:id/test", handler.TestStorageConnection)
 			storages.GET("/:id/diskspace", handler.GetStorageDiskSpace)
+				storages.GET("/:id/quota", handler.GetStorageQuota)
+				storages.POST("/:id/quota/enforce", handler.EnforceStorageQuota)
+				storages.POST("/:id/reconcile", handler.StartReconcile)
 			}
 
 			// 通知管理
@@ -96,14 +102,53 @@ func SetupRouter() *gin.Engine {
 			{
 				logs.GET("", handler.GetLogs)
 				logs.GET("/:id", handler.GetLog)
+				logs.GET("/:id/progress", handler.GetLogProgress)
+				logs.GET("/:id/progress/stream", handler.StreamLogProgress)
+				logs.POST("/:id/cancel", handler.CancelLog)
 				logs.DELETE("/:id", handler.DeleteLog)
 			}
 
 			// 备份文件管理
 			backups := authorized.Group("/backups")
 			{
+				backups.POST("/migrate", handler.MigrateBackups)
 				backups.DELETE("/:id", handler.DeleteBackup)
 				backups.GET("/:id/download", handler.DownloadBackup)
+				backups.GET("/:id/copies", handler.GetBackupCopies)
+				backups.POST("/:id/restore", handler.RestoreBackup)
+				backups.POST("/:id/restore-test", handler.VerifyBackupRestore)
+				backups.POST("/:id/verify", handler.VerifyBackupChecksum)
+			}
+
+			// 恢复记录
+			restores := authorized.Group("/restores")
+			{
+				restores.GET("", handler.GetRestores)
+				restores.GET("/:id", handler.GetRestore)
+			}
+
+			// 备份迁移任务
+			migrations := authorized.Group("/migrations")
+			{
+				migrations.GET("", handler.GetMigrations)
+				migrations.GET("/:id", handler.GetMigration)
+			}
+
+			// 存储对账任务
+			reconciles := authorized.Group("/reconciles")
+			{
+				reconciles.GET("", handler.GetReconciles)
+				reconciles.GET("/:id", handler.GetReconcile)
+				reconciles.POST("/:id/resolve", handler.ResolveReconcileFindings)
+			}
+
+			// 加密密钥
+			encryptionKeys := authorized.Group("/encryption-keys")
+			{
+				encryptionKeys.GET("", handler.GetEncryptionKeys)
+				encryptionKeys.POST("", handler.CreateEncryptionKey)
+				encryptionKeys.PUT("/:id", handler.UpdateEncryptionKey)
+				encryptionKeys.DELETE("/:id", handler.DeleteEncryptionKey)
 			}
 
 			// 用户管理
@@ -0,0 +1,308 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BinlogStreamParams binlog持续归档参数
+type BinlogStreamParams struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	SpoolDir  string // 本地暂存目录
+	StartFile string // 起始binlog文件
+	ServerID  int    // 复制连接使用的server id，0表示使用mysqlbinlog默认值
+	ExtraArgs string // 额外的mysqlbinlog参数
+}
+
+// BinlogFileInfo binlog文件内容摘要
+type BinlogFileInfo struct {
+	FirstEventTime *time.Time
+	LastEventTime  *time.Time
+	PreviousGTIDs  string // 文件开头的Previous-GTIDs
+	GTIDs          string // 文件中包含的GTID范围
+}
+
+var (
+	binlogEventHeaderPattern = regexp.MustCompile(`^#(\d{6})\s+(\d{1,2}:\d{2}:\d{2})\s+server id`)
+	binlogGTIDNextPattern    = regexp.MustCompile(`GTID_NEXT\s*=\s*'([^']+)'`)
+)
+
+// BinlogStreamer 通过mysqlbinlog --stop-never持续拉取binlog
+type BinlogStreamer struct{}
+
+// Stream 运行mysqlbinlog，直到ctx取消或进程退出
+func (s *BinlogStreamer) Stream(ctx context.Context, params *BinlogStreamParams) error {
+	if params.Host == "" || params.Username == "" {
+		return fmt.Errorf("host and username are required")
+	}
+	if params.SpoolDir == "" {
+		return fmt.Errorf("spool directory is required")
+	}
+	if params.StartFile == "" {
+		return fmt.Errorf("start binlog file is required")
+	}
+
+	if err := os.MkdirAll(params.SpoolDir, 0755); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	args := []string{
+		"--read-from-remote-server",
+		fmt.Sprintf("--host=%s", params.Host),
+		fmt.Sprintf("--port=%d", params.Port),
+		fmt.Sprintf("--user=%s", params.Username),
+		fmt.Sprintf("--password=%s", params.Password),
+		"--raw",
+		"--stop-never",
+		fmt.Sprintf("--result-file=%s%c", params.SpoolDir, filepath.Separator),
+	}
+	if params.ServerID > 0 {
+		args = append(args, fmt.Sprintf("--connection-server-id=%d", params.ServerID))
+	}
+	if params.ExtraArgs != "" {
+		args = append(args, strings.Fields(params.ExtraArgs)...)
+	}
+	args = append(args, params.StartFile)
+
+	cmd := exec.CommandContext(ctx, "mysqlbinlog", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("mysqlbinlog failed: %v, stderr: %s", err, stderr.String())
+	}
+
+	return fmt.Errorf("mysqlbinlog exited unexpectedly, stderr: %s", stderr.String())
+}
+
+// SpooledBinlogFiles 返回暂存目录中的binlog文件，按文件名排序
+func SpooledBinlogFiles(spoolDir string) ([]string, error) {
+	entries, err := os.ReadDir(spoolDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		files = append(files, entry.Name())
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// ClosedBinlogFiles 返回暂存目录中已写完的binlog文件（最新的一个仍在写入中）
+func ClosedBinlogFiles(spoolDir string) ([]string, error) {
+	files, err := SpooledBinlogFiles(spoolDir)
+	if err != nil || len(files) == 0 {
+		return nil, err
+	}
+	return files[:len(files)-1], nil
+}
+
+// ParseBinlogFile 解析binlog文件，获取事件时间范围和GTID信息
+func ParseBinlogFile(ctx context.Context, path string) (*BinlogFileInfo, error) {
+	cmd := exec.CommandContext(ctx, "mysqlbinlog", "--no-defaults", "--base64-output=DECODE-ROWS", path)
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start mysqlbinlog: %w", err)
+	}
+
+	info := &BinlogFileInfo{}
+	gtids := newGTIDCollector()
+	inPreviousGTIDs := false
+	var previousGTIDs []string
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Previous-GTIDs事件后紧跟以"# "开头的GTID集合
+		if inPreviousGTIDs {
+			if strings.HasPrefix(line, "# ") && !strings.HasPrefix(line, "# at ") {
+				previousGTIDs = append(previousGTIDs, strings.TrimSpace(strings.TrimPrefix(line, "# ")))
+				continue
+			}
+			inPreviousGTIDs = false
+		}
+
+		if m := binlogEventHeaderPattern.FindStringSubmatch(line); m != nil {
+			if t, err := time.ParseInLocation("060102 15:04:05", m[1]+" "+m[2], time.Local); err == nil {
+				if info.FirstEventTime == nil {
+					info.FirstEventTime = &t
+				}
+				info.LastEventTime = &t
+			}
+			if strings.Contains(line, "Previous-GTIDs") {
+				inPreviousGTIDs = true
+			}
+			continue
+		}
+
+		if m := binlogGTIDNextPattern.FindStringSubmatch(line); m != nil {
+			gtids.add(m[1])
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("mysqlbinlog failed: %v, stderr: %s", err, stderr.String())
+	}
+
+	info.PreviousGTIDs = strings.TrimSuffix(strings.Join(previousGTIDs, ""), ",")
+	info.GTIDs = gtids.String()
+	return info, nil
+}
+
+// ListBinaryLogs 列出服务器上的binlog文件（SHOW BINARY LOGS）
+func ListBinaryLogs(host string, port int, username, password string) ([]string, error) {
+	db, err := openMySQL(host, port, username, password)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SHOW BINARY LOGS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query binary logs: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		var name string
+		values[0] = &name
+		for i := 1; i < len(columns); i++ {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+
+	return files, rows.Err()
+}
+
+// FlushBinaryLogs 让服务器切换到新的binlog文件
+func FlushBinaryLogs(host string, port int, username, password string) error {
+	db, err := openMySQL(host, port, username, password)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("FLUSH BINARY LOGS"); err != nil {
+		return fmt.Errorf("failed to flush binary logs: %w", err)
+	}
+	return nil
+}
+
+// gtidCollector 汇总每个server uuid的GTID区间，binlog中有空洞时保留多个区间
+type gtidCollector struct {
+	intervals map[string][]gtidInterval
+}
+
+func newGTIDCollector() *gtidCollector {
+	return &gtidCollector{intervals: make(map[string][]gtidInterval)}
+}
+
+// add 记录一个GTID（uuid:n 或 uuid:tag:n）
+func (c *gtidCollector) add(gtid string) {
+	sid, n, ok := splitGTID(gtid)
+	if !ok {
+		return // AUTOMATIC / ANONYMOUS
+	}
+
+	// GTID通常按顺序出现，优先尝试扩展最后一个区间
+	intervals := c.intervals[sid]
+	if last := len(intervals) - 1; last >= 0 && n >= intervals[last].start {
+		if n <= intervals[last].end {
+			return
+		}
+		if n == intervals[last].end+1 {
+			intervals[last].end = n
+			return
+		}
+		if n > intervals[last].end+1 {
+			c.intervals[sid] = append(intervals, gtidInterval{start: n, end: n})
+			return
+		}
+	}
+	c.intervals[sid] = mergeGTIDIntervals(append(intervals, gtidInterval{start: n, end: n}))
+}
+
+// String 输出GTID集合，如 uuid:1-100:105,uuid2:5
+func (c *gtidCollector) String() string {
+	sids := make([]string, 0, len(c.intervals))
+	for sid := range c.intervals {
+		sids = append(sids, sid)
+	}
+	sort.Strings(sids)
+
+	parts := make([]string, 0, len(sids))
+	for _, sid := range sids {
+		part := sid
+		for _, interval := range c.intervals[sid] {
+			if interval.start == interval.end {
+				part += fmt.Sprintf(":%d", interval.start)
+			} else {
+				part += fmt.Sprintf(":%d-%d", interval.start, interval.end)
+			}
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, ",")
+}
+
+// mergeGTIDIntervals 排序并合并重叠或相邻的区间
+func mergeGTIDIntervals(intervals []gtidInterval) []gtidInterval {
+	sort.Slice(intervals, func(i, j int) bool { return intervals[i].start < intervals[j].start })
+	merged := intervals[:0]
+	for _, interval := range intervals {
+		if last := len(merged) - 1; last >= 0 && interval.start <= merged[last].end+1 {
+			if interval.end > merged[last].end {
+				merged[last].end = interval.end
+			}
+			continue
+		}
+		merged = append(merged, interval)
+	}
+	return merged
+}
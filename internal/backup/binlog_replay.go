@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BinlogReplayParams binlog重放参数
+type BinlogReplayParams struct {
+	Host          string
+	Port          int
+	Username      string
+	Password      string
+	Files         []string // 本地binlog文件，按顺序
+	StartPosition int64    // 第一个文件的起始位置，0表示从头开始
+	StopDatetime  string   // 停止时间（2006-01-02 15:04:05），为空表示不限制
+	StopPosition  int64    // 最后一个文件的停止位置，0表示不限制
+	Database      string   // 只重放该库的事件，为空表示全部
+	RewriteDB     string   // 库名重写（from->to），为空表示不重写
+}
+
+var binlogAtPattern = regexp.MustCompile(`^# at (\d+)`)
+
+// ReplayBinlogs 使用 mysqlbinlog | mysql 重放binlog，返回执行的命令（隐藏密码）
+func ReplayBinlogs(ctx context.Context, params *BinlogReplayParams) (string, error) {
+	if len(params.Files) == 0 {
+		return "", nil
+	}
+
+	// mysqlbinlog参数
+	binlogArgs := []string{"--no-defaults"}
+	if params.StartPosition > 0 {
+		binlogArgs = append(binlogArgs, fmt.Sprintf("--start-position=%d", params.StartPosition))
+	}
+	if params.StopDatetime != "" {
+		binlogArgs = append(binlogArgs, fmt.Sprintf("--stop-datetime=%s", params.StopDatetime))
+	}
+	if params.StopPosition > 0 {
+		binlogArgs = append(binlogArgs, fmt.Sprintf("--stop-position=%d", params.StopPosition))
+	}
+	if params.Database != "" {
+		binlogArgs = append(binlogArgs, fmt.Sprintf("--database=%s", params.Database))
+	}
+	if params.RewriteDB != "" {
+		binlogArgs = append(binlogArgs, fmt.Sprintf("--rewrite-db=%s", params.RewriteDB))
+	}
+	binlogArgs = append(binlogArgs, params.Files...)
+
+	// mysql参数
+	mysqlArgs := []string{
+		fmt.Sprintf("--host=%s", params.Host),
+		fmt.Sprintf("--port=%d", params.Port),
+		fmt.Sprintf("--user=%s", params.Username),
+		fmt.Sprintf("--password=%s", params.Password),
+	}
+
+	binlogCmd := exec.CommandContext(ctx, "mysqlbinlog", binlogArgs...)
+	mysqlCmd := exec.CommandContext(ctx, "mysql", mysqlArgs...)
+
+	cmdStr := "mysqlbinlog " + strings.Join(binlogArgs, " ") +
+		fmt.Sprintf(" | mysql --host=%s --port=%d --user=%s --password=***", params.Host, params.Port, params.Username)
+
+	pipe, err := binlogCmd.StdoutPipe()
+	if err != nil {
+		return cmdStr, fmt.Errorf("failed to create pipe: %w", err)
+	}
+	mysqlCmd.Stdin = pipe
+
+	var binlogStderr, mysqlStderr bytes.Buffer
+	binlogCmd.Stderr = &binlogStderr
+	mysqlCmd.Stderr = &mysqlStderr
+
+	if err := mysqlCmd.Start(); err != nil {
+		return cmdStr, fmt.Errorf("failed to start mysql: %w", err)
+	}
+	if err := binlogCmd.Start(); err != nil {
+		mysqlCmd.Process.Kill()
+		mysqlCmd.Wait()
+		return cmdStr, fmt.Errorf("failed to start mysqlbinlog: %w", err)
+	}
+
+	binlogErr := binlogCmd.Wait()
+	mysqlErr := mysqlCmd.Wait()
+
+	if binlogErr != nil {
+		return cmdStr, fmt.Errorf("mysqlbinlog failed: %v, stderr: %s", binlogErr, binlogStderr.String())
+	}
+	if mysqlErr != nil {
+		return cmdStr, fmt.Errorf("mysql failed: %v, stderr: %s", mysqlErr, mysqlStderr.String())
+	}
+
+	return cmdStr, nil
+}
+
+// FindGTIDStopPosition 在binlog文件中查找目标GTID事务之后下一个事务的起始位置，
+// 目标事务是文件中最后一个事务时返回0
+func FindGTIDStopPosition(ctx context.Context, path, gtid string) (int64, error) {
+	targetSID, targetN, ok := splitGTID(gtid)
+	if !ok {
+		return 0, fmt.Errorf("invalid GTID: %s", gtid)
+	}
+
+	cmd := exec.CommandContext(ctx, "mysqlbinlog", "--no-defaults", "--base64-output=DECODE-ROWS", path)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start mysqlbinlog: %w", err)
+	}
+	defer cmd.Wait()
+
+	var lastAt int64
+	found := false
+
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if m := binlogAtPattern.FindStringSubmatch(line); m != nil {
+			lastAt, _ = strconv.ParseInt(m[1], 10, 64)
+			continue
+		}
+
+		m := binlogGTIDNextPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		sid, n, ok := splitGTID(m[1])
+		if !ok {
+			continue
+		}
+
+		if found {
+			// 目标事务之后的第一个GTID事件，在它之前停止
+			cmd.Process.Kill()
+			return lastAt, nil
+		}
+		if sid == targetSID && n == targetN {
+			found = true
+		}
+	}
+
+	if !found {
+		return 0, fmt.Errorf("GTID %s not found in %s", gtid, path)
+	}
+	return 0, nil
+}
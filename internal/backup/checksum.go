@@ -0,0 +1,46 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ChecksumExtension 校验和文件扩展名，与备份文件一起存放在存储中
+const ChecksumExtension = ".sha256"
+
+// FileChecksum 计算文件的SHA-256
+func FileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// FormatChecksumFile 生成sha256sum兼容格式的校验和文件内容
+func FormatChecksumFile(checksum, filePath string) string {
+	return fmt.Sprintf("%s  %s\n", checksum, filepath.Base(filePath))
+}
+
+// ParseChecksumFile 解析sha256sum格式的校验和文件，返回第一行的校验和
+func ParseChecksumFile(content string) (string, error) {
+	fields := strings.Fields(content)
+	if len(fields) == 0 || len(fields[0]) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum file")
+	}
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", fmt.Errorf("invalid checksum file: %w", err)
+	}
+	return strings.ToLower(fields[0]), nil
+}
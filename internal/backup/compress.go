@@ -0,0 +1,298 @@
+package backup
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+	"github.com/ulikunitz/xz"
+)
+
+// compressionExtensions 流式压缩类型对应的文件扩展名（zip为归档格式，单独处理）
+var compressionExtensions = map[string]string{
+	"gzip": ".gz",
+	"zstd": ".zst",
+	"lz4":  ".lz4",
+	"xz":   ".xz",
+}
+
+// CompressionExtension 返回压缩类型对应的扩展名，none返回空字符串，未知类型按gzip处理
+func CompressionExtension(compressionType string) string {
+	switch compressionType {
+	case "none":
+		return ""
+	case "zip":
+		return ".zip"
+	}
+	if ext, ok := compressionExtensions[compressionType]; ok {
+		return ext
+	}
+	return ".gz"
+}
+
+// ValidCompressionType 检查压缩类型是否支持
+func ValidCompressionType(compressionType string) bool {
+	if compressionType == "" || compressionType == "none" || compressionType == "zip" {
+		return true
+	}
+	_, ok := compressionExtensions[compressionType]
+	return ok
+}
+
+// ValidateCompressionOptions 按压缩类型检查压缩级别和线程数，0表示默认值：
+// gzip、lz4、xz的级别为1-9，zstd为1-22；只有zstd和lz4支持设置线程数，none和zip不支持设置级别
+func ValidateCompressionOptions(compressionType string, level, threads int) error {
+	if level < 0 {
+		return fmt.Errorf("compression_level must not be negative")
+	}
+	if threads < 0 {
+		return fmt.Errorf("compression_threads must not be negative")
+	}
+
+	maxLevel := 9
+	multiThreaded := false
+	switch compressionType {
+	case "none", "zip":
+		maxLevel = 0
+	case "zstd":
+		maxLevel = 22
+		multiThreaded = true
+	case "lz4":
+		multiThreaded = true
+	}
+	if maxLevel == 0 && level != 0 {
+		return fmt.Errorf("compression_level is not supported for compression type %s", compressionType)
+	}
+	if level > maxLevel {
+		return fmt.Errorf("compression_level for %s must be between 1 and %d", compressionName(compressionType), maxLevel)
+	}
+	if !multiThreaded && threads != 0 {
+		return fmt.Errorf("compression_threads is only supported for zstd and lz4")
+	}
+	return nil
+}
+
+// compressionName 返回用于提示的压缩类型名称，为空时为默认的gzip
+func compressionName(compressionType string) string {
+	if compressionType == "" {
+		return "gzip"
+	}
+	return compressionType
+}
+
+// xzDictCaps xz各级别（1-9）对应的字典大小，与xz命令行的预设一致
+var xzDictCaps = []int{1 << 20, 2 << 20, 4 << 20, 4 << 20, 8 << 20, 8 << 20, 16 << 20, 32 << 20, 64 << 20}
+
+// newCompressWriter 创建流式压缩Writer，level为0时使用默认级别（xz按级别选择字典大小），
+// threads为0时使用CPU核数（zstd, lz4）
+func newCompressWriter(w io.Writer, compressionType string, level, threads int) (io.WriteCloser, error) {
+	switch compressionType {
+	case "zstd":
+		if threads <= 0 {
+			threads = runtime.NumCPU()
+		}
+		opts := []zstd.EOption{zstd.WithEncoderConcurrency(threads)}
+		if level > 0 {
+			opts = append(opts, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)))
+		}
+		return zstd.NewWriter(w, opts...)
+
+	case "lz4":
+		lz4Writer := lz4.NewWriter(w)
+		opts := []lz4.Option{lz4.ConcurrencyOption(threads)}
+		if level > 0 {
+			opts = append(opts, lz4.CompressionLevelOption(lz4CompressionLevel(level)))
+		}
+		if err := lz4Writer.Apply(opts...); err != nil {
+			return nil, fmt.Errorf("invalid lz4 options: %w", err)
+		}
+		return lz4Writer, nil
+
+	case "xz":
+		config := xz.WriterConfig{}
+		if level > 0 && level <= len(xzDictCaps) {
+			config.DictCap = xzDictCaps[level-1]
+		}
+		return config.NewWriter(w)
+
+	default: // gzip
+		if level > 0 {
+			return gzip.NewWriterLevel(w, level)
+		}
+		return gzip.NewWriter(w), nil
+	}
+}
+
+// newStreamCompressor 按压缩类型包装SQL输出流，fileName为产物文件名（zip条目名由其去掉.zip得出），
+// 写入完成后需要调用返回的Closer（不压缩时为nil）
+func newStreamCompressor(w io.Writer, params *BackupParams, fileName string) (io.Writer, io.Closer, error) {
+	switch params.CompressionType {
+	case "none":
+		return w, nil, nil
+	case "zip":
+		zipWriter := zip.NewWriter(w)
+		entry, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     strings.TrimSuffix(fileName, ".zip"),
+			Method:   zip.Deflate,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create zip entry: %w", err)
+		}
+		return entry, zipWriter, nil
+	default: // gzip, zstd, lz4, xz
+		compressWriter, err := newCompressWriter(w, params.CompressionType, params.CompressionLevel, params.CompressionThreads)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create compressor: %w", err)
+		}
+		return compressWriter, compressWriter, nil
+	}
+}
+
+// lz4CompressionLevel 将1-9的级别映射为lz4压缩级别
+func lz4CompressionLevel(level int) lz4.CompressionLevel {
+	levels := []lz4.CompressionLevel{lz4.Fast, lz4.Level1, lz4.Level2, lz4.Level3, lz4.Level4, lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9}
+	if level >= len(levels) {
+		level = len(levels) - 1
+	}
+	return levels[level]
+}
+
+// newDecompressReader 根据文件扩展名创建解压Reader，ext为去掉归档扩展名后的压缩扩展名（.gz/.zst/.lz4/.xz）
+func newDecompressReader(r io.Reader, ext string) (io.ReadCloser, error) {
+	switch ext {
+	case ".gz":
+		return gzip.NewReader(r)
+	case ".zst":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return decoder.IOReadCloser(), nil
+	case ".lz4":
+		return io.NopCloser(lz4.NewReader(r)), nil
+	case ".xz":
+		xzReader, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(xzReader), nil
+	default:
+		return nil, fmt.Errorf("unsupported compression: %s", ext)
+	}
+}
+
+// compressedSuffix 返回文件名中base之后的压缩扩展名，如 backup.sql.zst 对 .sql 返回 .zst
+func compressedSuffix(path, base string) (string, bool) {
+	for _, ext := range compressionExtensions {
+		if strings.HasSuffix(path, base+ext) {
+			return ext, true
+		}
+	}
+	return "", false
+}
+
+// openCompressed 打开压缩文件并返回解压后的数据流
+func openCompressed(path, ext string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	reader, err := newDecompressReader(file, ext)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open compressed stream: %w", err)
+	}
+	return &multiCloser{Reader: reader, closers: []io.Closer{reader, file}}, nil
+}
+
+// compressFileTo 将文件压缩为dst
+func compressFileTo(src, dst, compressionType string, level, threads int) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	writer, err := newCompressWriter(dstFile, compressionType, level, threads)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(writer, srcFile); err != nil {
+		writer.Close()
+		return err
+	}
+	return writer.Close()
+}
+
+// remoteCompressCommand 生成远程服务器上打包并压缩目录的命令（需要远程安装对应压缩工具）
+func remoteCompressCommand(compressionType, sourceDir, target string, level, threads int) string {
+	levelArg := ""
+	if level > 0 {
+		levelArg = fmt.Sprintf(" -%d", level)
+	}
+	threadArg := "0"
+	if threads > 0 {
+		threadArg = fmt.Sprintf("%d", threads)
+	}
+
+	switch compressionType {
+	case "zstd":
+		return fmt.Sprintf("tar -cf - -C %s . | zstd -q -T%s%s -o %s", sourceDir, threadArg, levelArg, target)
+	case "lz4":
+		return fmt.Sprintf("tar -cf - -C %s . | lz4 -q%s > %s", sourceDir, levelArg, target)
+	case "xz":
+		return fmt.Sprintf("tar -cf - -C %s . | xz -T%s%s > %s", sourceDir, threadArg, levelArg, target)
+	default: // gzip，优先使用多线程的pigz
+		return fmt.Sprintf("tar -cf - -C %s . | $(command -v pigz || echo gzip)%s > %s", sourceDir, levelArg, target)
+	}
+}
+
+// ContentType 根据备份文件扩展名返回下载时使用的Content-Type
+func ContentType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".gz"):
+		return "application/gzip"
+	case strings.HasSuffix(name, ".zst"):
+		return "application/zstd"
+	case strings.HasSuffix(name, ".lz4"):
+		return "application/x-lz4"
+	case strings.HasSuffix(name, ".xz"):
+		return "application/x-xz"
+	case strings.HasSuffix(name, ".zip"):
+		return "application/zip"
+	case strings.HasSuffix(name, ".tar"):
+		return "application/x-tar"
+	case strings.HasSuffix(name, ".sql"):
+		return "application/sql"
+	default:
+		return "application/octet-stream"
+	}
+}
+
+// dirSize 计算目录中文件的总大小
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,159 @@
+package backup
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// BinlogCoordinates 备份一致性点对应的binlog坐标
+type BinlogCoordinates struct {
+	File     string // binlog文件名
+	Position int64  // binlog位置
+	GTIDSet  string // 已执行的GTID集合
+}
+
+var (
+	// mysqldump --source-data=2 / --master-data=2 输出的注释
+	dumpChangeSourcePattern = regexp.MustCompile(`(?:MASTER|SOURCE)_LOG_FILE='([^']+)',\s*(?:MASTER|SOURCE)_LOG_POS=(\d+)`)
+	// mysqldump --set-gtid-purged 输出的GTID集合，可能跨多行
+	dumpGTIDPurgedPattern = regexp.MustCompile(`(?s)SET @@GLOBAL\.GTID_PURGED=(?:/\*!80000 '\+'\*/ )?'([^']*)'`)
+
+	// mydumper 旧版metadata格式
+	mydumperLogPattern  = regexp.MustCompile(`(?m)^\s*Log:\s*(\S+)`)
+	mydumperPosPattern  = regexp.MustCompile(`(?m)^\s*Pos:\s*(\d+)`)
+	mydumperGTIDPattern = regexp.MustCompile(`(?m)^\s*GTID:\s*(.*)$`)
+	// mydumper 新版ini格式metadata
+	mydumperFilePattern     = regexp.MustCompile(`(?m)^\s*File\s*=\s*'?([^'\s]+)'?`)
+	mydumperPositionPattern = regexp.MustCompile(`(?m)^\s*Position\s*=\s*'?(\d+)'?`)
+	mydumperGTIDSetPattern  = regexp.MustCompile(`(?m)^\s*Executed_Gtid_Set\s*=\s*'?([^'\n]*)'?`)
+)
+
+// dumpHeaderSize 读取mysqldump输出头部的最大字节数
+const dumpHeaderSize = 1024 * 1024
+
+// parseDumpCoordinates 从mysqldump输出头部解析binlog坐标，未找到时返回nil
+func parseDumpCoordinates(header string) *BinlogCoordinates {
+	coords := &BinlogCoordinates{}
+	if m := dumpChangeSourcePattern.FindStringSubmatch(header); m != nil {
+		coords.File = m[1]
+		coords.Position, _ = strconv.ParseInt(m[2], 10, 64)
+	}
+	if m := dumpGTIDPurgedPattern.FindStringSubmatch(header); m != nil {
+		coords.GTIDSet = normalizeGTIDSet(m[1])
+	}
+	if coords.File == "" && coords.GTIDSet == "" {
+		return nil
+	}
+	return coords
+}
+
+// readDumpHeader 读取SQL文件头部
+func readDumpHeader(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	buf, err := io.ReadAll(io.LimitReader(file, dumpHeaderSize))
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// parseMydumperMetadata 解析mydumper的metadata文件，未找到时返回nil
+func parseMydumperMetadata(content string) *BinlogCoordinates {
+	coords := &BinlogCoordinates{}
+
+	if m := mydumperLogPattern.FindStringSubmatch(content); m != nil {
+		coords.File = m[1]
+		if m := mydumperPosPattern.FindStringSubmatch(content); m != nil {
+			coords.Position, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		if m := mydumperGTIDPattern.FindStringSubmatch(content); m != nil {
+			coords.GTIDSet = normalizeGTIDSet(m[1])
+		}
+		return coords
+	}
+
+	if m := mydumperFilePattern.FindStringSubmatch(content); m != nil {
+		coords.File = m[1]
+		if m := mydumperPositionPattern.FindStringSubmatch(content); m != nil {
+			coords.Position, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		if m := mydumperGTIDSetPattern.FindStringSubmatch(content); m != nil {
+			coords.GTIDSet = normalizeGTIDSet(m[1])
+		}
+		return coords
+	}
+
+	return nil
+}
+
+// parseMysqlshMetadata 解析mysqlsh dump目录中的@.json，未找到时返回nil
+func parseMysqlshMetadata(content []byte) *BinlogCoordinates {
+	var metadata struct {
+		BinlogFile     string `json:"binlogFile"`
+		BinlogPosition int64  `json:"binlogPosition"`
+		GtidExecuted   string `json:"gtidExecuted"`
+	}
+	if err := json.Unmarshal(content, &metadata); err != nil {
+		return nil
+	}
+	if metadata.BinlogFile == "" && metadata.GtidExecuted == "" {
+		return nil
+	}
+	return &BinlogCoordinates{
+		File:     metadata.BinlogFile,
+		Position: metadata.BinlogPosition,
+		GTIDSet:  normalizeGTIDSet(metadata.GtidExecuted),
+	}
+}
+
+// parseXtrabackupBinlogInfo 解析xtrabackup_binlog_info（文件名\t位置\tGTID集合）
+func parseXtrabackupBinlogInfo(content string) *BinlogCoordinates {
+	fields := strings.Fields(content)
+	if len(fields) < 2 {
+		return nil
+	}
+
+	coords := &BinlogCoordinates{File: fields[0]}
+	coords.Position, _ = strconv.ParseInt(fields[1], 10, 64)
+	if len(fields) > 2 {
+		coords.GTIDSet = normalizeGTIDSet(strings.Join(fields[2:], ""))
+	}
+	return coords
+}
+
+// normalizeGTIDSet 去掉GTID集合中的空白和换行
+func normalizeGTIDSet(set string) string {
+	return strings.Join(strings.Fields(set), "")
+}
+
+var (
+	sourceDataFlagOnce sync.Once
+	sourceDataFlag     string
+)
+
+// mysqldumpSourceDataFlag 返回记录binlog坐标的mysqldump参数（8.0.26起为--source-data）
+func mysqldumpSourceDataFlag() string {
+	sourceDataFlagOnce.Do(func() {
+		sourceDataFlag = "--master-data=2"
+
+		output, err := exec.Command("mysqldump", "--help").Output()
+		if err != nil {
+			return
+		}
+		if strings.Contains(string(output), "--source-data") {
+			sourceDataFlag = "--source-data=2"
+		}
+	})
+	return sourceDataFlag
+}
@@ -2,6 +2,7 @@ package backup
 
 import (
 	"context"
+	"io"
 	"time"
 )
 
@@ -15,39 +16,58 @@ type Executor interface {
 	Validate(params *BackupParams) error
 }
 
+// StreamExecutor 支持流式输出的备份执行器，备份数据直接写入存储，不在本地落盘
+type StreamExecutor interface {
+	Executor
+	// StreamFileName 返回产物文件名
+	StreamFileName(params *BackupParams) string
+	// ExecuteStream 执行备份并将压缩后的数据写入w，结果中的FilePath为产物文件名
+	ExecuteStream(ctx context.Context, params *BackupParams, fileName string, w io.Writer) (*BackupResult, error)
+}
+
 // BackupParams 备份参数
 type BackupParams struct {
-	Host            string
-	Port            int
-	Username        string
-	Password        string
-	Databases       []string               // 空表示全部数据库
-	OutputPath      string                 // 输出路径
-	Options         map[string]interface{} // 额外选项
-	CompressionType string                 // none, gzip, zip
-	SSHConfig       *SSHConfig             // xtrabackup需要
+	Host               string
+	Port               int
+	Username           string
+	Password           string
+	Databases          []string               // 空表示全部数据库
+	OutputPath         string                 // 输出路径
+	Options            map[string]interface{} // 额外选项
+	CompressionType    string                 // none, gzip, zip, zstd, lz4, xz
+	CompressionLevel   int                    // 压缩级别，0表示默认
+	CompressionThreads int                    // 压缩线程数，0表示CPU核数
+	SSHConfig          *SSHConfig             // xtrabackup需要
+	TableFilter        *TableFilter           // 表级过滤，nil表示不过滤
+	Progress           BackupProgressFunc     // 进度回调，可为空
 }
 
 // SSHConfig SSH配置
 type SSHConfig struct {
-	Host       string `json:"host"`
-	Port       int    `json:"port"`
-	Username   string `json:"username"`
-	Password   string `json:"password"`
-	PrivateKey string `json:"private_key"`
+	Host           string `json:"host"`
+	Port           int    `json:"port"`
+	Username       string `json:"username"`
+	Password       string `json:"password"`
+	PrivateKey     string `json:"private_key"`
 	XtrabackupPath string `json:"xtrabackup_path"` // xtrabackup工具路径
 }
 
 // BackupResult 备份结果
 type BackupResult struct {
-	FilePath     string
-	FileSize     int64
-	Duration     time.Duration
-	Databases    []string
-	Command      string // 完整的备份命令
-	BackupTime   int    // 备份耗时（秒）
-	TransferTime int    // 传输耗时（秒）
-	Error        error
+	FilePath        string
+	FileSize        int64
+	RawSize         int64 // 压缩前的大小
+	Duration        time.Duration
+	Databases       []string
+	Command         string                 // 完整的备份命令
+	BackupTime      int                    // 备份耗时（秒）
+	TransferTime    int                    // 传输耗时（秒）
+	Coordinates     *BinlogCoordinates     // 备份一致性点的binlog坐标，可能为nil
+	Checkpoints     *XtrabackupCheckpoints // xtrabackup的LSN信息，其他类型为nil
+	EncryptionKeyID string                 // 加密密钥标识，未加密时为空
+	Checksum        string                 // 存储中备份文件的SHA-256
+	ToolVersion     string                 // 备份工具版本
+	Error           error
 }
 
 // NewExecutor 创建备份执行器
@@ -59,6 +79,10 @@ func NewExecutor(backupType string) Executor {
 		return &MydumperExecutor{}
 	case "xtrabackup":
 		return &XtrabackupExecutor{}
+	case "gonative":
+		return &GoNativeExecutor{}
+	case "mysqlsh":
+		return &MysqlshExecutor{}
 	default:
 		return &MysqldumpExecutor{} // 默认使用mysqldump
 	}
@@ -0,0 +1,1007 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	nativeDefaultThreads   = 4
+	nativeDefaultChunkRows = 100000
+	nativeMaxChunks        = 10000       // 单表最多拆分的分块数
+	nativeInsertSize       = 1024 * 1024 // 单条INSERT语句的最大字节数
+)
+
+// GoNativeExecutor 纯Go实现的逻辑备份执行器，不依赖外部命令，输出与mysqldump兼容的SQL
+type GoNativeExecutor struct{}
+
+func (e *GoNativeExecutor) Type() string {
+	return "gonative"
+}
+
+func (e *GoNativeExecutor) Validate(params *BackupParams) error {
+	if params.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if params.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	return nil
+}
+
+func (e *GoNativeExecutor) Execute(ctx context.Context, params *BackupParams) (*BackupResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
+	}
+	if params.OutputPath == "" {
+		return nil, fmt.Errorf("output path is required")
+	}
+
+	// 确保输出目录存在
+	if err := os.MkdirAll(params.OutputPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	fileName := e.StreamFileName(params)
+	finalFile := filepath.Join(params.OutputPath, fileName)
+	file, err := os.Create(finalFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create output file: %w", err)
+	}
+
+	result, err := e.ExecuteStream(ctx, params, fileName, file)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close output file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(finalFile)
+		return nil, err
+	}
+
+	result.FilePath = finalFile
+	result.Duration = time.Since(startTime)
+	return result, nil
+}
+
+// StreamFileName 返回流式备份的产物文件名
+func (e *GoNativeExecutor) StreamFileName(params *BackupParams) string {
+	name := fmt.Sprintf("backup_%s.sql", time.Now().Format("20060102_150405"))
+	return name + CompressionExtension(params.CompressionType)
+}
+
+// ExecuteStream 在一致性快照中导出结构和数据，输出经压缩后直接写入w
+func (e *GoNativeExecutor) ExecuteStream(ctx context.Context, params *BackupParams, fileName string, w io.Writer) (*BackupResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
+	}
+
+	// 统计写出的字节数
+	counter := &countingWriter{w: w}
+
+	// 根据压缩类型包装输出
+	out, closer, err := newStreamCompressor(counter, params, fileName)
+	if err != nil {
+		return nil, err
+	}
+	raw := &countingWriter{w: out}
+
+	dumper, err := newNativeDumper(params, raw)
+	if err != nil {
+		return nil, err
+	}
+	defer dumper.close()
+
+	if err := dumper.dump(ctx); err != nil {
+		return nil, fmt.Errorf("gonative dump failed: %w", err)
+	}
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress output: %w", err)
+		}
+	}
+
+	return &BackupResult{
+		ToolVersion: "mbmanager gonative (server " + dumper.serverVersion + ")",
+		FilePath:    fileName,
+		FileSize:    counter.n,
+		RawSize:     raw.n,
+		Duration:    time.Since(startTime),
+		Databases:   params.Databases,
+		Command:     dumper.command(),
+		Coordinates: dumper.coords,
+	}, nil
+}
+
+// nativeColumn 导出的列，kind决定值的输出格式
+type nativeColumn struct {
+	name string
+	kind int
+}
+
+const (
+	nativeKindString = iota // 转义后加引号
+	nativeKindNumber        // 原样输出
+	nativeKindBinary        // 十六进制输出
+)
+
+// nativeChunk 一个数据分块，where为空表示整张表
+type nativeChunk struct {
+	database string
+	table    string
+	columns  []nativeColumn
+	where    string
+}
+
+// nativeDumper 一次gonative备份的状态，所有工作连接共享同一个一致性快照
+type nativeDumper struct {
+	params        *BackupParams
+	db            *sql.DB
+	conns         []*sql.Conn
+	threads       int
+	chunkRows     int64
+	serverVersion string
+	coords        *BinlogCoordinates
+	views         [][2]string // 已写入占位表、在导出末尾创建的视图（库名、视图名）
+
+	mu       sync.Mutex // 保护out、writeErr和进度
+	out      *bufio.Writer
+	writeErr error
+
+	// 进度
+	written     int64
+	database    string
+	table       string
+	tablesDone  int
+	tablesTotal int
+	chunksLeft  map[string]int // 表名 -> 未完成的分块数
+	lastReport  time.Time
+}
+
+func newNativeDumper(params *BackupParams, w io.Writer) (*nativeDumper, error) {
+	threads := nativeDefaultThreads
+	if v, err := strconv.Atoi(optionString(params.Options, "threads")); err == nil && v > 0 {
+		threads = v
+	}
+	chunkRows := int64(nativeDefaultChunkRows)
+	if v, err := strconv.ParseInt(optionString(params.Options, "chunk_rows"), 10, 64); err == nil && v > 0 {
+		chunkRows = v
+	}
+
+	// 不使用parseTime，按文本协议原样读取各类型的值
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/?charset=utf8mb4",
+		params.Username,
+		params.Password,
+		params.Host,
+		params.Port,
+	)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	db.SetMaxOpenConns(threads + 1)
+
+	return &nativeDumper{
+		params:     params,
+		db:         db,
+		threads:    threads,
+		chunkRows:  chunkRows,
+		out:        bufio.NewWriterSize(w, 256*1024),
+		chunksLeft: make(map[string]int),
+	}, nil
+}
+
+func (d *nativeDumper) close() {
+	for _, conn := range d.conns {
+		conn.Close()
+	}
+	d.db.Close()
+}
+
+// command 返回用于日志的命令描述
+func (d *nativeDumper) command() string {
+	target := "--all-databases"
+	if len(d.params.Databases) > 0 {
+		target = "--databases " + strings.Join(d.params.Databases, " ")
+	}
+	return fmt.Sprintf("gonative --host=%s --port=%d --user=%s --threads=%d --chunk-rows=%d %s",
+		d.params.Host, d.params.Port, d.params.Username, d.threads, d.chunkRows, target)
+}
+
+// snapshot 建立工作连接并在全局读锁下开启一致性快照，同时读取binlog坐标。
+// 没有RELOAD权限无法加锁时退化为单连接快照，不记录坐标
+func (d *nativeDumper) snapshot(ctx context.Context) error {
+	lockConn, err := d.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer lockConn.Close()
+
+	locked := true
+	if _, err := lockConn.ExecContext(ctx, "FLUSH TABLES WITH READ LOCK"); err != nil {
+		locked = false
+		d.threads = 1
+	}
+
+	for i := 0; i < d.threads; i++ {
+		conn, err := d.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		d.conns = append(d.conns, conn)
+
+		for _, stmt := range []string{
+			"SET NAMES utf8mb4",
+			"SET SESSION time_zone = '+00:00'",
+			"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+			"START TRANSACTION WITH CONSISTENT SNAPSHOT",
+		} {
+			if _, err := conn.ExecContext(ctx, stmt); err != nil {
+				return fmt.Errorf("failed to start snapshot (%s): %w", stmt, err)
+			}
+		}
+	}
+
+	if locked {
+		d.coords = queryBinlogStatus(ctx, lockConn)
+		if _, err := lockConn.ExecContext(ctx, "UNLOCK TABLES"); err != nil {
+			return fmt.Errorf("failed to unlock tables: %w", err)
+		}
+	}
+
+	return d.conns[0].QueryRowContext(ctx, "SELECT VERSION()").Scan(&d.serverVersion)
+}
+
+// queryBinlogStatus 读取当前binlog坐标，兼容MySQL 8.4的SHOW BINARY LOG STATUS，未开启binlog时返回nil
+func queryBinlogStatus(ctx context.Context, conn *sql.Conn) *BinlogCoordinates {
+	for _, query := range []string{"SHOW BINARY LOG STATUS", "SHOW MASTER STATUS"} {
+		rows, err := conn.QueryContext(ctx, query)
+		if err != nil {
+			continue
+		}
+		values, err := scanRowStrings(rows)
+		rows.Close()
+		if err != nil || values["File"] == "" {
+			return nil
+		}
+
+		position, _ := strconv.ParseInt(values["Position"], 10, 64)
+		return &BinlogCoordinates{
+			File:     values["File"],
+			Position: position,
+			GTIDSet:  normalizeGTIDSet(values["Executed_Gtid_Set"]),
+		}
+	}
+	return nil
+}
+
+// scanRowStrings 读取结果集的第一行，返回列名到值的映射，没有数据时返回nil
+func scanRowStrings(rows *sql.Rows) (map[string]string, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]string, len(columns))
+	for i, column := range columns {
+		result[column] = values[i].String
+	}
+	return result, nil
+}
+
+// dump 执行完整的导出流程
+func (d *nativeDumper) dump(ctx context.Context) error {
+	if err := d.snapshot(ctx); err != nil {
+		return err
+	}
+
+	databases := d.params.Databases
+	if len(databases) == 0 {
+		var err error
+		if databases, err = d.listDatabases(ctx); err != nil {
+			return err
+		}
+	}
+	if d.params.Progress != nil {
+		d.tablesTotal, _ = countBackupTables(ctx, d.conns[0], databases, d.params.TableFilter)
+	}
+
+	d.writeHeader(databases)
+	for _, dbName := range databases {
+		if err := d.dumpDatabase(ctx, dbName); err != nil {
+			return err
+		}
+	}
+	if err := d.dumpViews(ctx); err != nil {
+		return err
+	}
+	d.writeFooter()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.writeErr != nil {
+		return d.writeErr
+	}
+	return d.out.Flush()
+}
+
+// listDatabases 列出所有非系统库
+func (d *nativeDumper) listDatabases(ctx context.Context) ([]string, error) {
+	rows, err := d.conns[0].QueryContext(ctx, "SHOW DATABASES")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list databases: %w", err)
+	}
+	defer rows.Close()
+
+	var databases []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if !systemDatabases[name] {
+			databases = append(databases, name)
+		}
+	}
+	return databases, rows.Err()
+}
+
+// write 写入输出流，出错后忽略后续写入
+func (d *nativeDumper) write(p []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.writeErr != nil {
+		return d.writeErr
+	}
+	if _, err := d.out.Write(p); err != nil {
+		d.writeErr = fmt.Errorf("failed to write output: %w", err)
+	}
+	d.written += int64(len(p))
+	if time.Since(d.lastReport) >= progressInterval {
+		d.reportLocked()
+	}
+	return d.writeErr
+}
+
+// reportLocked 上报当前进度，调用方需持有mu
+func (d *nativeDumper) reportLocked() {
+	d.lastReport = time.Now()
+	d.params.reportProgress(BackupProgress{
+		Phase:       "dumping",
+		BytesDone:   d.written,
+		Database:    d.database,
+		Table:       d.table,
+		TablesDone:  d.tablesDone,
+		TablesTotal: d.tablesTotal,
+	})
+}
+
+// chunkDone 记录分块完成，表的全部分块完成时计入已完成的表
+func (d *nativeDumper) chunkDone(chunk *nativeChunk) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.database, d.table = chunk.database, chunk.table
+	key := chunk.database + "." + chunk.table
+	d.chunksLeft[key]--
+	if d.chunksLeft[key] == 0 {
+		delete(d.chunksLeft, key)
+		d.tablesDone++
+		d.reportLocked()
+	}
+}
+
+func (d *nativeDumper) writeString(s string) error {
+	return d.write([]byte(s))
+}
+
+// writeHeader 写入与mysqldump一致的会话设置，binlog坐标以注释形式记录
+func (d *nativeDumper) writeHeader(databases []string) {
+	var b strings.Builder
+	b.WriteString("-- mbmanager gonative dump\n--\n")
+	fmt.Fprintf(&b, "-- Host: %s    Database: %s\n", d.params.Host, strings.Join(databases, ","))
+	b.WriteString("-- ------------------------------------------------------\n")
+	fmt.Fprintf(&b, "-- Server version\t%s\n\n", d.serverVersion)
+
+	if d.coords != nil {
+		b.WriteString("--\n-- Position to start replication or point-in-time recovery from\n--\n\n")
+		fmt.Fprintf(&b, "-- CHANGE MASTER TO MASTER_LOG_FILE='%s', MASTER_LOG_POS=%d;\n", d.coords.File, d.coords.Position)
+		if d.coords.GTIDSet != "" {
+			fmt.Fprintf(&b, "-- SET @@GLOBAL.GTID_PURGED=/*!80000 '+'*/ '%s';\n", d.coords.GTIDSet)
+		}
+		b.WriteString("\n")
+	}
+
+	b.WriteString("/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n")
+	b.WriteString("/*!40101 SET @OLD_CHARACTER_SET_RESULTS=@@CHARACTER_SET_RESULTS */;\n")
+	b.WriteString("/*!40101 SET @OLD_COLLATION_CONNECTION=@@COLLATION_CONNECTION */;\n")
+	b.WriteString("/*!50503 SET NAMES utf8mb4 */;\n")
+	b.WriteString("/*!40103 SET @OLD_TIME_ZONE=@@TIME_ZONE */;\n")
+	b.WriteString("/*!40103 SET TIME_ZONE='+00:00' */;\n")
+	b.WriteString("/*!40014 SET @OLD_UNIQUE_CHECKS=@@UNIQUE_CHECKS, UNIQUE_CHECKS=0 */;\n")
+	b.WriteString("/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n")
+	b.WriteString("/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;\n")
+	b.WriteString("/*!40111 SET @OLD_SQL_NOTES=@@SQL_NOTES, SQL_NOTES=0 */;\n")
+	d.writeString(b.String())
+}
+
+// writeFooter 恢复会话设置
+func (d *nativeDumper) writeFooter() {
+	var b strings.Builder
+	b.WriteString("\n/*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;\n")
+	b.WriteString("/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n")
+	b.WriteString("/*!40014 SET FOREIGN_KEY_CHECKS=@OLD_FOREIGN_KEY_CHECKS */;\n")
+	b.WriteString("/*!40014 SET UNIQUE_CHECKS=@OLD_UNIQUE_CHECKS */;\n")
+	b.WriteString("/*!40101 SET CHARACTER_SET_CLIENT=@OLD_CHARACTER_SET_CLIENT */;\n")
+	b.WriteString("/*!40101 SET CHARACTER_SET_RESULTS=@OLD_CHARACTER_SET_RESULTS */;\n")
+	b.WriteString("/*!40101 SET COLLATION_CONNECTION=@OLD_COLLATION_CONNECTION */;\n")
+	b.WriteString("/*!40111 SET SQL_NOTES=@OLD_SQL_NOTES */;\n\n")
+	fmt.Fprintf(&b, "-- Dump completed on %s\n", time.Now().Format("2006-01-02 15:04:05"))
+	d.writeString(b.String())
+}
+
+// dumpDatabase 依次导出库结构、表结构、视图占位表、表数据、触发器、存储过程和事件，
+// 视图可能依赖其他视图、其他库的表或存储函数，真正的定义在所有库导出后统一写入
+func (d *nativeDumper) dumpDatabase(ctx context.Context, dbName string) error {
+	conn := d.conns[0]
+
+	var name, createDB string
+	if err := conn.QueryRowContext(ctx, "SHOW CREATE DATABASE "+quoteIdentifier(dbName)).Scan(&name, &createDB); err != nil {
+		return fmt.Errorf("failed to show create database %s: %w", dbName, err)
+	}
+	createDB = strings.Replace(createDB, "CREATE DATABASE ", "CREATE DATABASE /*!32312 IF NOT EXISTS*/ ", 1)
+	d.writeString(fmt.Sprintf("\n--\n-- Current Database: %s\n--\n\n%s;\n\nUSE %s;\n",
+		quoteIdentifier(dbName), createDB, quoteIdentifier(dbName)))
+
+	tables, views, err := d.listTables(ctx, dbName)
+	if err != nil {
+		return err
+	}
+
+	// 表结构
+	var chunks []*nativeChunk
+	for _, table := range tables {
+		if err := d.dumpTableSchema(ctx, dbName, table); err != nil {
+			return err
+		}
+		tableChunks, err := d.planChunks(ctx, dbName, table)
+		if err != nil {
+			return err
+		}
+		d.mu.Lock()
+		if len(tableChunks) > 0 {
+			d.chunksLeft[dbName+"."+table] = len(tableChunks)
+		} else {
+			d.tablesDone++
+		}
+		d.mu.Unlock()
+		chunks = append(chunks, tableChunks...)
+	}
+
+	// 视图占位表，使引用该视图的其他视图可以先创建
+	for _, view := range views {
+		if err := d.dumpViewPlaceholder(ctx, dbName, view); err != nil {
+			return err
+		}
+		d.views = append(d.views, [2]string{dbName, view})
+	}
+
+	// 表数据
+	if len(chunks) > 0 {
+		d.writeString("\n--\n-- Dumping data for database " + quoteIdentifier(dbName) + "\n--\n\n")
+		if err := d.dumpChunks(ctx, chunks); err != nil {
+			return err
+		}
+	}
+
+	if err := d.dumpTriggers(ctx, dbName); err != nil {
+		return err
+	}
+	if err := d.dumpRoutines(ctx, dbName); err != nil {
+		return err
+	}
+	return d.dumpEvents(ctx, dbName)
+}
+
+// listTables 列出库中的表（已应用表过滤）和视图
+func (d *nativeDumper) listTables(ctx context.Context, dbName string) ([]string, []string, error) {
+	rows, err := d.conns[0].QueryContext(ctx,
+		"SELECT TABLE_NAME, TABLE_TYPE FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME", dbName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tables in %s: %w", dbName, err)
+	}
+	defer rows.Close()
+
+	var tables, views []string
+	for rows.Next() {
+		var name, tableType string
+		if err := rows.Scan(&name, &tableType); err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case tableType == "VIEW":
+			views = append(views, name)
+		case !d.params.TableFilter.Excluded(dbName, name):
+			tables = append(tables, name)
+		}
+	}
+	return tables, views, rows.Err()
+}
+
+// dumpViewPlaceholder 写入与视图列相同的占位视图，与mysqldump一样使用常量列，不依赖任何表
+func (d *nativeDumper) dumpViewPlaceholder(ctx context.Context, dbName, view string) error {
+	rows, err := d.conns[0].QueryContext(ctx,
+		"SELECT COLUMN_NAME FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", dbName, view)
+	if err != nil {
+		return fmt.Errorf("failed to list columns of view %s.%s: %w", dbName, view, err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		columns = append(columns, "1 AS "+quoteIdentifier(name))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		columns = append(columns, "1 AS `1`")
+	}
+
+	return d.writeString(fmt.Sprintf("\n--\n-- Temporary view structure for view %s\n--\n\n"+
+		"DROP TABLE IF EXISTS %s;\n/*!50001 DROP VIEW IF EXISTS %s*/;\n/*!50001 CREATE VIEW %s AS SELECT\n %s */;\n",
+		quoteIdentifier(view), quoteIdentifier(view), quoteIdentifier(view), quoteIdentifier(view), strings.Join(columns, ",\n ")))
+}
+
+// dumpViews 在所有库导出后用真正的视图定义替换占位视图
+func (d *nativeDumper) dumpViews(ctx context.Context) error {
+	currentDB := ""
+	for _, v := range d.views {
+		dbName, view := v[0], v[1]
+		stmt, _, err := d.showCreate(ctx, "SHOW CREATE VIEW "+quoteIdentifier(dbName)+"."+quoteIdentifier(view), "Create View")
+		if err != nil {
+			return err
+		}
+		if dbName != currentDB {
+			d.writeString(fmt.Sprintf("\n--\n-- Final view structure for database %s\n--\n\nUSE %s;\n",
+				quoteIdentifier(dbName), quoteIdentifier(dbName)))
+			currentDB = dbName
+		}
+		d.writeString(fmt.Sprintf("\n--\n-- Final view structure for view %s\n--\n\n/*!50001 DROP VIEW IF EXISTS %s*/;\n%s;\n",
+			quoteIdentifier(view), quoteIdentifier(view), stmt))
+	}
+	return nil
+}
+
+// dumpTableSchema 导出表结构
+func (d *nativeDumper) dumpTableSchema(ctx context.Context, dbName, table string) error {
+	stmt, _, err := d.showCreate(ctx, "SHOW CREATE TABLE "+quoteIdentifier(dbName)+"."+quoteIdentifier(table), "Create Table")
+	if err != nil {
+		return err
+	}
+	return d.writeString(fmt.Sprintf("\n--\n-- Table structure for table %s\n--\n\nDROP TABLE IF EXISTS %s;\n%s;\n",
+		quoteIdentifier(table), quoteIdentifier(table), stmt))
+}
+
+// showCreate 执行SHOW CREATE语句，返回指定列的建表语句和对象的sql_mode
+func (d *nativeDumper) showCreate(ctx context.Context, query, column string) (string, string, error) {
+	rows, err := d.conns[0].QueryContext(ctx, query)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to run %s: %w", query, err)
+	}
+	defer rows.Close()
+
+	values, err := scanRowStrings(rows)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to run %s: %w", query, err)
+	}
+	return values[column], values["sql_mode"], nil
+}
+
+// tableColumns 读取表中需要导出的列，生成列不导出
+func (d *nativeDumper) tableColumns(ctx context.Context, dbName, table string) ([]nativeColumn, error) {
+	rows, err := d.conns[0].QueryContext(ctx,
+		"SELECT COLUMN_NAME, DATA_TYPE, EXTRA FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION",
+		dbName, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns of %s.%s: %w", dbName, table, err)
+	}
+	defer rows.Close()
+
+	var columns []nativeColumn
+	for rows.Next() {
+		var name, dataType, extra string
+		if err := rows.Scan(&name, &dataType, &extra); err != nil {
+			return nil, err
+		}
+		extra = strings.ToUpper(extra)
+		if strings.Contains(extra, "VIRTUAL GENERATED") || strings.Contains(extra, "STORED GENERATED") {
+			continue
+		}
+		columns = append(columns, nativeColumn{name: name, kind: nativeColumnKind(dataType)})
+	}
+	return columns, rows.Err()
+}
+
+// nativeColumnKind 根据数据类型决定值的输出格式
+func nativeColumnKind(dataType string) int {
+	switch strings.ToLower(dataType) {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint",
+		"decimal", "numeric", "float", "double", "real", "year":
+		return nativeKindNumber
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bit",
+		"geometry", "point", "linestring", "polygon", "multipoint", "multilinestring",
+		"multipolygon", "geometrycollection", "geomcollection":
+		return nativeKindBinary
+	default:
+		return nativeKindString
+	}
+}
+
+// planChunks 将表拆分为数据分块：单列整数主键按主键范围拆分，否则整张表为一个分块
+func (d *nativeDumper) planChunks(ctx context.Context, dbName, table string) ([]*nativeChunk, error) {
+	columns, err := d.tableColumns(ctx, dbName, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil
+	}
+	whole := []*nativeChunk{{database: dbName, table: table, columns: columns}}
+
+	rows, err := d.conns[0].QueryContext(ctx,
+		"SELECT k.COLUMN_NAME, c.DATA_TYPE FROM information_schema.KEY_COLUMN_USAGE k "+
+			"JOIN information_schema.COLUMNS c ON c.TABLE_SCHEMA = k.TABLE_SCHEMA AND c.TABLE_NAME = k.TABLE_NAME AND c.COLUMN_NAME = k.COLUMN_NAME "+
+			"WHERE k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ? AND k.CONSTRAINT_NAME = 'PRIMARY'",
+		dbName, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read primary key of %s.%s: %w", dbName, table, err)
+	}
+	var keyColumns, keyTypes []string
+	for rows.Next() {
+		var name, dataType string
+		if err := rows.Scan(&name, &dataType); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keyColumns = append(keyColumns, name)
+		keyTypes = append(keyTypes, strings.ToLower(dataType))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(keyColumns) != 1 || !strings.HasSuffix(keyTypes[0], "int") {
+		return whole, nil
+	}
+	key := quoteIdentifier(keyColumns[0])
+
+	var minValue, maxValue sql.NullString
+	query := fmt.Sprintf("SELECT MIN(%s), MAX(%s) FROM %s.%s", key, key, quoteIdentifier(dbName), quoteIdentifier(table))
+	if err := d.conns[0].QueryRowContext(ctx, query).Scan(&minValue, &maxValue); err != nil {
+		return nil, fmt.Errorf("failed to read key range of %s.%s: %w", dbName, table, err)
+	}
+	if !minValue.Valid {
+		return nil, nil // 空表
+	}
+	low, err1 := strconv.ParseInt(minValue.String, 10, 64)
+	high, err2 := strconv.ParseInt(maxValue.String, 10, 64)
+	if err1 != nil || err2 != nil || high-low < d.chunkRows {
+		return whole, nil // 超出int64范围或数据量小时不拆分
+	}
+
+	step := d.chunkRows
+	if span := (high - low) / nativeMaxChunks; span > step {
+		step = span + 1
+	}
+
+	var chunks []*nativeChunk
+	for start := low; start <= high; start += step {
+		where := fmt.Sprintf("%s >= %d AND %s < %d", key, start, key, start+step)
+		if start > high-step {
+			where = fmt.Sprintf("%s >= %d", key, start)
+		}
+		chunks = append(chunks, &nativeChunk{database: dbName, table: table, columns: columns, where: where})
+		if start > high-step {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+// dumpChunks 使用所有工作连接并行导出数据分块
+func (d *nativeDumper) dumpChunks(ctx context.Context, chunks []*nativeChunk) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+	ch := make(chan *nativeChunk)
+	for _, conn := range d.conns {
+		wg.Add(1)
+		go func(conn *sql.Conn) {
+			defer wg.Done()
+			for chunk := range ch {
+				if ctx.Err() != nil {
+					continue
+				}
+				if err := d.dumpChunk(ctx, conn, chunk); err != nil {
+					errOnce.Do(func() {
+						firstErr = err
+						cancel()
+					})
+					continue
+				}
+				d.chunkDone(chunk)
+			}
+		}(conn)
+	}
+
+feed:
+	for _, chunk := range chunks {
+		select {
+		case ch <- chunk:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(ch)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// dumpChunk 导出一个数据分块，生成不超过nativeInsertSize的多行INSERT语句
+func (d *nativeDumper) dumpChunk(ctx context.Context, conn *sql.Conn, chunk *nativeChunk) error {
+	names := make([]string, len(chunk.columns))
+	for i, column := range chunk.columns {
+		names[i] = quoteIdentifier(column.name)
+	}
+	columnList := strings.Join(names, ",")
+
+	query := fmt.Sprintf("SELECT %s FROM %s.%s", columnList, quoteIdentifier(chunk.database), quoteIdentifier(chunk.table))
+	if chunk.where != "" {
+		query += " WHERE " + chunk.where
+	}
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to read %s.%s: %w", chunk.database, chunk.table, err)
+	}
+	defer rows.Close()
+
+	values := make([]sql.RawBytes, len(chunk.columns))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quoteIdentifier(chunk.table), columnList)
+	var stmt bytes.Buffer
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return fmt.Errorf("failed to read %s.%s: %w", chunk.database, chunk.table, err)
+		}
+
+		if stmt.Len() == 0 {
+			stmt.WriteString(prefix)
+		} else {
+			stmt.WriteByte(',')
+		}
+		stmt.WriteByte('(')
+		for i, value := range values {
+			if i > 0 {
+				stmt.WriteByte(',')
+			}
+			appendSQLValue(&stmt, value, chunk.columns[i].kind)
+		}
+		stmt.WriteByte(')')
+
+		if stmt.Len() >= nativeInsertSize {
+			stmt.WriteString(";\n")
+			if err := d.write(stmt.Bytes()); err != nil {
+				return err
+			}
+			stmt.Reset()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read %s.%s: %w", chunk.database, chunk.table, err)
+	}
+
+	if stmt.Len() > 0 {
+		stmt.WriteString(";\n")
+		return d.write(stmt.Bytes())
+	}
+	return nil
+}
+
+// appendSQLValue 按列类型将文本协议返回的值写为SQL字面量
+func appendSQLValue(buf *bytes.Buffer, value []byte, kind int) {
+	switch {
+	case value == nil:
+		buf.WriteString("NULL")
+	case kind == nativeKindNumber:
+		buf.Write(value)
+	case kind == nativeKindBinary:
+		if len(value) == 0 {
+			buf.WriteString("''")
+			return
+		}
+		buf.WriteString("0x")
+		buf.WriteString(hex.EncodeToString(value))
+	default:
+		buf.WriteByte('\'')
+		for _, c := range value {
+			switch c {
+			case 0:
+				buf.WriteString(`\0`)
+			case '\n':
+				buf.WriteString(`\n`)
+			case '\r':
+				buf.WriteString(`\r`)
+			case '\\':
+				buf.WriteString(`\\`)
+			case '\'':
+				buf.WriteString(`\'`)
+			case '"':
+				buf.WriteString(`\"`)
+			case 0x1a:
+				buf.WriteString(`\Z`)
+			default:
+				buf.WriteByte(c)
+			}
+		}
+		buf.WriteByte('\'')
+	}
+}
+
+// writeRoutine 写入需要切换分隔符和sql_mode的对象定义（触发器、存储过程、事件）
+func (d *nativeDumper) writeRoutine(comment, drop, stmt, sqlMode string) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n--\n-- %s\n--\n\n", comment)
+	if drop != "" {
+		b.WriteString(drop + ";\n")
+	}
+	b.WriteString("/*!50003 SET @saved_sql_mode = @@sql_mode */;\n")
+	fmt.Fprintf(&b, "/*!50003 SET sql_mode = '%s' */;\n", strings.ReplaceAll(sqlMode, "'", "''"))
+	b.WriteString("DELIMITER ;;\n")
+	b.WriteString(stmt + " ;;\n")
+	b.WriteString("DELIMITER ;\n")
+	b.WriteString("/*!50003 SET sql_mode = @saved_sql_mode */;\n")
+	return d.writeString(b.String())
+}
+
+// listNames 执行只返回名称列的查询
+func (d *nativeDumper) listNames(ctx context.Context, query string, args ...interface{}) ([][2]string, error) {
+	rows, err := d.conns[0].QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run %s: %w", query, err)
+	}
+	defer rows.Close()
+
+	var names [][2]string
+	for rows.Next() {
+		var name [2]string
+		if err := rows.Scan(&name[0], &name[1]); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// dumpTriggers 导出触发器，被排除表上的触发器不导出
+func (d *nativeDumper) dumpTriggers(ctx context.Context, dbName string) error {
+	triggers, err := d.listNames(ctx,
+		"SELECT TRIGGER_NAME, EVENT_OBJECT_TABLE FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ? ORDER BY EVENT_OBJECT_TABLE, ACTION_ORDER",
+		dbName)
+	if err != nil {
+		return err
+	}
+	for _, trigger := range triggers {
+		if d.params.TableFilter.Excluded(dbName, trigger[1]) {
+			continue
+		}
+		stmt, sqlMode, err := d.showCreate(ctx,
+			"SHOW CREATE TRIGGER "+quoteIdentifier(dbName)+"."+quoteIdentifier(trigger[0]), "SQL Original Statement")
+		if err != nil {
+			return err
+		}
+		if err := d.writeRoutine("Trigger "+quoteIdentifier(trigger[0]),
+			"DROP TRIGGER IF EXISTS "+quoteIdentifier(trigger[0]), stmt, sqlMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpRoutines 导出存储过程和函数，没有权限读取定义时跳过
+func (d *nativeDumper) dumpRoutines(ctx context.Context, dbName string) error {
+	routines, err := d.listNames(ctx,
+		"SELECT ROUTINE_NAME, ROUTINE_TYPE FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ? ORDER BY ROUTINE_TYPE, ROUTINE_NAME",
+		dbName)
+	if err != nil {
+		return err
+	}
+	for _, routine := range routines {
+		routineType := strings.ToUpper(routine[1]) // PROCEDURE, FUNCTION
+		column, label := "Create Procedure", "Procedure"
+		if routineType == "FUNCTION" {
+			column, label = "Create Function", "Function"
+		}
+		stmt, sqlMode, err := d.showCreate(ctx,
+			"SHOW CREATE "+routineType+" "+quoteIdentifier(dbName)+"."+quoteIdentifier(routine[0]), column)
+		if err != nil {
+			return err
+		}
+		if stmt == "" {
+			continue
+		}
+		if err := d.writeRoutine(label+" "+quoteIdentifier(routine[0]),
+			"/*!50003 DROP "+routineType+" IF EXISTS "+quoteIdentifier(routine[0])+" */", stmt, sqlMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dumpEvents 导出事件
+func (d *nativeDumper) dumpEvents(ctx context.Context, dbName string) error {
+	events, err := d.listNames(ctx,
+		"SELECT EVENT_NAME, EVENT_SCHEMA FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ? ORDER BY EVENT_NAME",
+		dbName)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		stmt, sqlMode, err := d.showCreate(ctx,
+			"SHOW CREATE EVENT "+quoteIdentifier(dbName)+"."+quoteIdentifier(event[0]), "Create Event")
+		if err != nil {
+			return err
+		}
+		if stmt == "" {
+			continue
+		}
+		if err := d.writeRoutine("Event "+quoteIdentifier(event[0]),
+			"/*!50106 DROP EVENT IF EXISTS "+quoteIdentifier(event[0])+" */", stmt, sqlMode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
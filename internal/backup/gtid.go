@@ -0,0 +1,86 @@
+package backup
+
+import (
+	"strconv"
+	"strings"
+)
+
+// gtidInterval GTID区间（闭区间）
+type gtidInterval struct {
+	start int64
+	end   int64
+}
+
+// parseGTIDSet 解析GTID集合，如 uuid:1-5:7,uuid2:tag:1-3，返回 sid(uuid或uuid:tag) -> 区间列表
+func parseGTIDSet(set string) map[string][]gtidInterval {
+	result := make(map[string][]gtidInterval)
+
+	for _, part := range strings.Split(normalizeGTIDSet(set), ",") {
+		segments := strings.Split(part, ":")
+		if len(segments) < 2 {
+			continue
+		}
+
+		uuid := strings.ToLower(segments[0])
+		sid := uuid
+		for _, seg := range segments[1:] {
+			if seg == "" {
+				continue
+			}
+			// 非数字开头的段为GTID标签（MySQL 8.4）
+			if seg[0] < '0' || seg[0] > '9' {
+				sid = uuid + ":" + strings.ToLower(seg)
+				continue
+			}
+
+			bounds := strings.SplitN(seg, "-", 2)
+			start, err := strconv.ParseInt(bounds[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			end := start
+			if len(bounds) == 2 {
+				if end, err = strconv.ParseInt(bounds[1], 10, 64); err != nil {
+					continue
+				}
+			}
+			result[sid] = append(result[sid], gtidInterval{start: start, end: end})
+		}
+	}
+
+	return result
+}
+
+// splitGTID 拆分单个GTID（uuid:n 或 uuid:tag:n）
+func splitGTID(gtid string) (string, int64, bool) {
+	idx := strings.LastIndex(gtid, ":")
+	if idx <= 0 {
+		return "", 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(gtid[idx+1:]), 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return strings.ToLower(strings.TrimSpace(gtid[:idx])), n, true
+}
+
+// GTIDSetContains 判断GTID集合是否包含指定GTID
+func GTIDSetContains(set, gtid string) bool {
+	sid, n, ok := splitGTID(gtid)
+	if !ok {
+		return false
+	}
+
+	for _, interval := range parseGTIDSet(set)[sid] {
+		if n >= interval.start && n <= interval.end {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidGTID 检查GTID格式是否正确
+func ValidGTID(gtid string) bool {
+	_, _, ok := splitGTID(gtid)
+	return ok
+}
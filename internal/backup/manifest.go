@@ -0,0 +1,175 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ManifestExtension 元数据清单文件扩展名，与备份文件一起存放在存储中
+const ManifestExtension = ".manifest.json"
+
+// Manifest 备份元数据清单
+type Manifest struct {
+	ManifestVersion int             `json:"manifest_version"`
+	TaskID          uint            `json:"task_id"`
+	TaskName        string          `json:"task_name"`
+	BackupLogID     uint            `json:"backup_log_id"`
+	HostName        string          `json:"host_name"`
+	BackupType      string          `json:"backup_type"`
+	BackupMode      string          `json:"backup_mode,omitempty"`
+	ParentLogID     uint            `json:"parent_log_id,omitempty"`
+	StartTime       time.Time       `json:"start_time"`
+	EndTime         time.Time       `json:"end_time"`
+	FileName        string          `json:"file_name"`
+	FileSize        int64           `json:"file_size"`
+	RawSize         int64           `json:"raw_size"`
+	Checksum        string          `json:"checksum"`
+	Compression     string          `json:"compression"`
+	EncryptionKeyID string          `json:"encryption_key_id,omitempty"`
+	ServerVersion   string          `json:"server_version"`
+	ToolVersion     string          `json:"tool_version"`
+	Binlog          *ManifestBinlog `json:"binlog,omitempty"`
+	SourceHost      string          `json:"source_host,omitempty"`   // 从库备份时的主库地址
+	SourceBinlog    *ManifestBinlog `json:"source_binlog,omitempty"` // 从库备份时对应的主库坐标
+	LSN             *ManifestLSN    `json:"lsn,omitempty"`
+	Databases       []DatabaseStats `json:"databases"`
+}
+
+// ManifestBinlog 备份一致性点的复制坐标
+type ManifestBinlog struct {
+	File         string `json:"file,omitempty"`
+	Position     int64  `json:"position,omitempty"`
+	GTIDExecuted string `json:"gtid_executed,omitempty"`
+}
+
+// ManifestLSN xtrabackup的LSN范围
+type ManifestLSN struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// DatabaseStats 数据库中各表的统计信息
+type DatabaseStats struct {
+	Name   string       `json:"name"`
+	Tables []TableStats `json:"tables"`
+}
+
+// TableStats 表统计信息，行数来自information_schema，是估算值
+type TableStats struct {
+	Name        string `json:"name"`
+	Engine      string `json:"engine"`
+	RowEstimate int64  `json:"row_estimate"`
+	DataLength  int64  `json:"data_length"`
+}
+
+// ServerInfo 备份源服务器信息
+type ServerInfo struct {
+	Version   string
+	Databases []DatabaseStats
+}
+
+// TableCount 表总数
+func (i *ServerInfo) TableCount() int {
+	count := 0
+	for _, db := range i.Databases {
+		count += len(db.Tables)
+	}
+	return count
+}
+
+// RowEstimate 估算总行数
+func (i *ServerInfo) RowEstimate() int64 {
+	var rows int64
+	for _, db := range i.Databases {
+		for _, table := range db.Tables {
+			rows += table.RowEstimate
+		}
+	}
+	return rows
+}
+
+// Exclude 去掉被表级过滤排除的表
+func (i *ServerInfo) Exclude(filter *TableFilter) {
+	if filter == nil {
+		return
+	}
+	for d := range i.Databases {
+		db := &i.Databases[d]
+		tables := db.Tables[:0]
+		for _, table := range db.Tables {
+			if !filter.Excluded(db.Name, table.Name) {
+				tables = append(tables, table)
+			}
+		}
+		db.Tables = tables
+	}
+}
+
+// CollectServerInfo 采集服务器版本以及数据库中各表的行数估算，databases为空表示全部非系统库
+func CollectServerInfo(host string, port int, username, password string, databases []string) (*ServerInfo, error) {
+	db, err := openMySQL(host, port, username, password)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	info := &ServerInfo{}
+	if err := db.QueryRow("SELECT VERSION()").Scan(&info.Version); err != nil {
+		return nil, fmt.Errorf("failed to query server version: %w", err)
+	}
+
+	if len(databases) == 0 {
+		if databases, err = listUserDatabases(db); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, dbName := range databases {
+		tables, err := listTableStats(db, dbName)
+		if err != nil {
+			return nil, err
+		}
+		info.Databases = append(info.Databases, DatabaseStats{Name: dbName, Tables: tables})
+	}
+
+	return info, nil
+}
+
+// listTableStats 查询库中普通表的引擎、估算行数和数据大小
+func listTableStats(db *sql.DB, dbName string) ([]TableStats, error) {
+	rows, err := db.Query(`SELECT TABLE_NAME, IFNULL(ENGINE, ''), IFNULL(TABLE_ROWS, 0), IFNULL(DATA_LENGTH, 0)
+		FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME`, dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables of %s: %w", dbName, err)
+	}
+	defer rows.Close()
+
+	tables := []TableStats{}
+	for rows.Next() {
+		var table TableStats
+		if err := rows.Scan(&table.Name, &table.Engine, &table.RowEstimate, &table.DataLength); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// localToolVersion 获取本地备份工具的版本（--version输出的第一行）
+func localToolVersion(ctx context.Context, tool string) string {
+	output, err := exec.CommandContext(ctx, tool, "--version").CombinedOutput()
+	if err != nil {
+		return ""
+	}
+	return firstLine(string(output))
+}
+
+// firstLine 返回去除首尾空白后的第一行
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
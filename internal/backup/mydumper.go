@@ -3,14 +3,15 @@ package backup
 import (
 	"archive/tar"
 	"archive/zip"
-	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -56,6 +57,7 @@ func (e *MydumperExecutor) Execute(ctx context.Context, params *BackupParams) (*
 		"-p", params.Password,
 		"-o", outputDir,
 		"--threads", "4",
+		"--verbose", "3", // 输出每张表的导出日志，用于解析进度
 	}
 
 	// 添加额外选项
@@ -70,6 +72,15 @@ func (e *MydumperExecutor) Execute(ctx context.Context, params *BackupParams) (*
 		}
 	}
 
+	// 表级过滤：指定了include时列出要备份的表，否则用正则排除
+	if filter := params.TableFilter; filter != nil {
+		if len(filter.IncludeTables) > 0 {
+			args = append(args, "-T", strings.Join(filter.IncludeTables, ","))
+		} else if len(filter.ExcludeTables) > 0 {
+			args = append(args, "--regex", "^(?!"+tableListRegex(filter.ExcludeTables)+")")
+		}
+	}
+
 	// 执行mydumper命令
 	cmd := exec.CommandContext(ctx, "mydumper", args...)
 
@@ -83,15 +94,30 @@ func (e *MydumperExecutor) Execute(ctx context.Context, params *BackupParams) (*
 		}
 	}
 
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
+	// 解析mydumper日志得到已导出的表，同时定期统计输出目录大小
+	progress := newMydumperProgress(params)
+	cmd.Stderr = progress
 
-	if err := cmd.Run(); err != nil {
+	stop := make(chan struct{})
+	go watchDirSize(params, outputDir, stop, progress.fill)
+	err := cmd.Run()
+	close(stop)
+	if err != nil {
 		os.RemoveAll(outputDir)
-		return nil, fmt.Errorf("mydumper failed: %v, stderr: %s", err, stderr.String())
+		return nil, fmt.Errorf("mydumper failed: %v, stderr: %s", err, progress.tail())
+	}
+
+	// 从metadata文件解析binlog坐标
+	var coords *BinlogCoordinates
+	if metadata, err := os.ReadFile(filepath.Join(outputDir, "metadata")); err == nil {
+		coords = parseMydumperMetadata(string(metadata))
 	}
 
+	// 记录压缩前大小
+	rawSize := dirSize(outputDir)
+
 	// 根据压缩类型处理目录
+	params.reportProgress(BackupProgress{Phase: "packaging", BytesDone: rawSize})
 	var finalPath string
 	switch params.CompressionType {
 	case "none":
@@ -108,12 +134,12 @@ func (e *MydumperExecutor) Execute(ctx context.Context, params *BackupParams) (*
 			os.RemoveAll(outputDir)
 			return nil, fmt.Errorf("failed to create zip: %w", err)
 		}
-	default: // gzip
-		// GZIP压缩（tar.gz）
-		finalPath = outputDir + ".tar.gz"
-		if err := createTarGz(outputDir, finalPath); err != nil {
+	default: // gzip, zstd, lz4, xz
+		// 压缩的tar包（tar.gz, tar.zst, tar.lz4, tar.xz）
+		finalPath = outputDir + ".tar" + CompressionExtension(params.CompressionType)
+		if err := createTarCompressed(outputDir, finalPath, params); err != nil {
 			os.RemoveAll(outputDir)
-			return nil, fmt.Errorf("failed to create tar.gz: %w", err)
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Base(finalPath), err)
 		}
 	}
 
@@ -127,29 +153,88 @@ func (e *MydumperExecutor) Execute(ctx context.Context, params *BackupParams) (*
 	}
 
 	return &BackupResult{
-		FilePath:  finalPath,
-		FileSize:  fileInfo.Size(),
-		Duration:  time.Since(startTime),
-		Databases: params.Databases,
-		Command:   cmdStr,
+		ToolVersion: localToolVersion(ctx, "mydumper"),
+		FilePath:    finalPath,
+		FileSize:    fileInfo.Size(),
+		RawSize:     rawSize,
+		Duration:    time.Since(startTime),
+		Databases:   params.Databases,
+		Command:     cmdStr,
+		Coordinates: coords,
 	}, nil
 }
 
-// createTarGz 将目录打包成tar.gz文件
-func createTarGz(sourceDir, targetFile string) error {
-	// 创建tar.gz文件
+// mydumperTablePattern 匹配mydumper开始导出某张表数据的日志行
+var mydumperTablePattern = regexp.MustCompile("dumping data (?:for|from) `([^`]+)`\\.`([^`]+)`")
+
+// mydumperTablesPattern 匹配新版mydumper日志中的表完成数（Tables: 3/10）
+var mydumperTablesPattern = regexp.MustCompile(`Tables: (\d+)/(\d+)`)
+
+// mydumperProgress 逐行解析mydumper的stderr，记录当前表和已导出的表数量
+type mydumperProgress struct {
+	logLineWriter
+	seen        map[string]bool
+	database    string
+	table       string
+	tablesDone  int
+	tablesTotal int
+}
+
+func newMydumperProgress(params *BackupParams) *mydumperProgress {
+	m := &mydumperProgress{
+		seen:        make(map[string]bool),
+		tablesTotal: estimateTableCount(params),
+	}
+	m.onLine = m.parseLine
+	return m
+}
+
+func (m *mydumperProgress) parseLine(line string) {
+	if match := mydumperTablesPattern.FindStringSubmatch(line); match != nil {
+		m.tablesDone, _ = strconv.Atoi(match[1])
+		m.tablesTotal, _ = strconv.Atoi(match[2])
+	}
+	if match := mydumperTablePattern.FindStringSubmatch(line); match != nil {
+		m.database, m.table = match[1], match[2]
+		key := match[1] + "." + match[2]
+		if !m.seen[key] {
+			m.seen[key] = true
+			// 旧版mydumper没有完成数，按已开始导出的表计算
+			if len(m.seen)-1 > m.tablesDone {
+				m.tablesDone = len(m.seen) - 1
+			}
+		}
+	}
+}
+
+// fill 将解析到的表信息填入进度
+func (m *mydumperProgress) fill(progress *BackupProgress) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	progress.Database = m.database
+	progress.Table = m.table
+	progress.TablesDone = m.tablesDone
+	progress.TablesTotal = m.tablesTotal
+}
+
+// createTarCompressed 将目录打包成按压缩类型压缩的tar文件
+func createTarCompressed(sourceDir, targetFile string, params *BackupParams) error {
+	// 创建压缩文件
 	file, err := os.Create(targetFile)
 	if err != nil {
-		return fmt.Errorf("failed to create tar.gz file: %w", err)
+		return fmt.Errorf("failed to create archive file: %w", err)
 	}
 	defer file.Close()
 
-	// 创建gzip writer
-	gzipWriter := gzip.NewWriter(file)
-	defer gzipWriter.Close()
+	// 创建压缩writer
+	compressWriter, err := newCompressWriter(file, params.CompressionType, params.CompressionLevel, params.CompressionThreads)
+	if err != nil {
+		return fmt.Errorf("failed to create compressor: %w", err)
+	}
+	defer compressWriter.Close()
 
 	// 创建tar writer
-	tarWriter := tar.NewWriter(gzipWriter)
+	tarWriter := tar.NewWriter(compressWriter)
 	defer tarWriter.Close()
 
 	// 遍历目录并添加到tar
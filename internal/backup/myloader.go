@@ -0,0 +1,191 @@
+package backup
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MydumperRestoreExecutor mydumper备份恢复执行器（通过myloader导入）
+type MydumperRestoreExecutor struct{}
+
+// myloaderRestoringPattern 匹配myloader开始导入某个数据文件的日志行
+var myloaderRestoringPattern = regexp.MustCompile(`(?i)restoring .*\.sql`)
+
+func (e *MydumperRestoreExecutor) Type() string {
+	return "mydumper"
+}
+
+func (e *MydumperRestoreExecutor) Validate(params *RestoreParams) error {
+	if params.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if params.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if params.FilePath == "" {
+		return fmt.Errorf("file path is required")
+	}
+	if params.WorkDir == "" {
+		return fmt.Errorf("work directory is required")
+	}
+	return nil
+}
+
+func (e *MydumperRestoreExecutor) Restore(ctx context.Context, params *RestoreParams) (*RestoreResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
+	}
+
+	// 解压备份归档
+	params.reportProgress(0, "extracting archive")
+	dumpDir := filepath.Join(params.WorkDir, "myloader")
+	if err := extractArchive(params.FilePath, dumpDir); err != nil {
+		return nil, fmt.Errorf("failed to extract backup: %w", err)
+	}
+	defer os.RemoveAll(dumpDir)
+
+	// 统计数据文件数量，用于计算进度
+	totalFiles, err := countMydumperDataFiles(dumpDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan backup directory: %w", err)
+	}
+
+	// 构建命令参数
+	threads := "4"
+	if v := optionString(params.Options, "threads"); v != "" {
+		threads = v
+	}
+
+	args := []string{
+		"-h", params.Host,
+		"-P", fmt.Sprintf("%d", params.Port),
+		"-u", params.Username,
+		fmt.Sprintf("--password=%s", params.Password),
+		"-d", dumpDir,
+		"--threads", threads,
+		"--verbose", "3",
+	}
+
+	if overwrite, ok := params.Options["overwrite_tables"].(bool); ok && overwrite {
+		args = append(args, "--overwrite-tables")
+	}
+
+	// 数据库重命名：-s 指定备份中的源库，-B 指定导入的目标库
+	if params.TargetDatabase != "" {
+		sourceDatabase := optionString(params.Options, "source_database")
+		if sourceDatabase == "" && len(params.Databases) == 1 {
+			sourceDatabase = params.Databases[0]
+		}
+		if sourceDatabase != "" {
+			args = append(args, "-s", sourceDatabase)
+		}
+		args = append(args, "-B", params.TargetDatabase)
+	}
+
+	// 执行myloader命令
+	cmd := exec.CommandContext(ctx, "myloader", args...)
+
+	// 构建完整命令字符串（用于日志，隐藏密码）
+	cmdStr := "myloader"
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--password=") {
+			cmdStr += " --password=***"
+		} else {
+			cmdStr += " " + arg
+		}
+	}
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start myloader: %w", err)
+	}
+
+	// 解析myloader输出，统计已导入的数据文件
+	var stderrTail []string
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		restored := 0
+		scanner := bufio.NewScanner(stderrPipe)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := scanner.Text()
+			stderrTail = append(stderrTail, line)
+			if len(stderrTail) > 20 {
+				stderrTail = stderrTail[1:]
+			}
+
+			if myloaderRestoringPattern.MatchString(line) {
+				restored++
+				percent := 99
+				if totalFiles > 0 && restored < totalFiles {
+					percent = restored * 100 / totalFiles
+				}
+				params.reportProgress(percent, fmt.Sprintf("restored %d/%d data files", restored, totalFiles))
+			}
+		}
+	}()
+
+	wg.Wait()
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("myloader failed: %v, stderr: %s", err, strings.Join(stderrTail, "\n"))
+	}
+
+	params.reportProgress(100, "myloader finished")
+
+	return &RestoreResult{
+		Duration: time.Since(startTime),
+		Command:  cmdStr,
+	}, nil
+}
+
+// countMydumperDataFiles 统计mydumper备份目录中的数据文件数量（不含表结构和元数据文件）
+func countMydumperDataFiles(dir string) (int, error) {
+	count := 0
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		name := info.Name()
+		if strings.Contains(name, "-schema") || !strings.Contains(name, ".sql") {
+			return nil
+		}
+		count++
+		return nil
+	})
+	return count, err
+}
+
+// optionString 读取字符串或数字类型的选项值
+func optionString(options map[string]interface{}, key string) string {
+	switch v := options[key].(type) {
+	case string:
+		return v
+	case float64:
+		return fmt.Sprintf("%d", int(v))
+	case int:
+		return fmt.Sprintf("%d", v)
+	case int64:
+		return fmt.Sprintf("%d", v)
+	}
+	return ""
+}
@@ -3,7 +3,6 @@ package backup
 import (
 	"archive/zip"
 	"bytes"
-	"compress/gzip"
 	"context"
 	"fmt"
 	"io"
@@ -46,34 +45,7 @@ func (e *MysqldumpExecutor) Execute(ctx context.Context, params *BackupParams) (
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	// 构建命令参数
-	args := []string{
-		fmt.Sprintf("--host=%s", params.Host),
-		fmt.Sprintf("--port=%d", params.Port),
-		fmt.Sprintf("--user=%s", params.Username),
-		fmt.Sprintf("--password=%s", params.Password),
-		"--single-transaction",
-		"--quick",
-		"--lock-tables=false",
-		"--routines",
-		"--triggers",
-		"--events",
-	}
-
-	// 添加额外选项（命令行参数字符串）
-	if options, ok := params.Options["extra_args"].(string); ok && options != "" {
-		// 分割命令行参数字符串
-		extraArgs := strings.Fields(options)
-		args = append(args, extraArgs...)
-	}
-
-	// 添加数据库
-	if len(params.Databases) > 0 {
-		args = append(args, "--databases")
-		args = append(args, params.Databases...)
-	} else {
-		args = append(args, "--all-databases")
-	}
+	args, cmdStr := e.buildArgs(params)
 
 	// 创建输出文件
 	timestamp := time.Now().Format("20060102_150405")
@@ -81,31 +53,12 @@ func (e *MysqldumpExecutor) Execute(ctx context.Context, params *BackupParams) (
 	var finalFile string
 
 	// 根据压缩类型确定文件扩展名
-	switch params.CompressionType {
-	case "none":
-		outputFile = filepath.Join(params.OutputPath, fmt.Sprintf("backup_%s.sql", timestamp))
-		finalFile = outputFile
-	case "zip":
-		outputFile = filepath.Join(params.OutputPath, fmt.Sprintf("backup_%s.sql", timestamp))
-		finalFile = outputFile + ".zip"
-	default: // gzip
-		outputFile = filepath.Join(params.OutputPath, fmt.Sprintf("backup_%s.sql", timestamp))
-		finalFile = outputFile + ".gz"
-	}
+	outputFile = filepath.Join(params.OutputPath, fmt.Sprintf("backup_%s.sql", timestamp))
+	finalFile = outputFile + CompressionExtension(params.CompressionType)
 
 	// 执行mysqldump命令
 	cmd := exec.CommandContext(ctx, "mysqldump", args...)
 
-	// 构建完整命令字符串（用于日志，隐藏密码）
-	cmdStr := "mysqldump"
-	for _, arg := range args {
-		if strings.Contains(arg, "--password=") {
-			cmdStr += " --password=***"
-		} else {
-			cmdStr += " " + arg
-		}
-	}
-
 	outFile, err := os.Create(outputFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create output file: %w", err)
@@ -113,6 +66,9 @@ func (e *MysqldumpExecutor) Execute(ctx context.Context, params *BackupParams) (
 	defer outFile.Close()
 
 	cmd.Stdout = outFile
+	if params.Progress != nil {
+		cmd.Stdout = io.MultiWriter(outFile, newDumpProgressWriter(params))
+	}
 
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
@@ -122,7 +78,22 @@ func (e *MysqldumpExecutor) Execute(ctx context.Context, params *BackupParams) (
 		return nil, fmt.Errorf("mysqldump failed: %v, stderr: %s", err, stderr.String())
 	}
 
+	// 从输出头部解析binlog坐标
+	var coords *BinlogCoordinates
+	if header, err := readDumpHeader(outputFile); err == nil {
+		coords = parseDumpCoordinates(header)
+	}
+
+	// 记录压缩前大小
+	var rawSize int64
+	if rawInfo, err := os.Stat(outputFile); err == nil {
+		rawSize = rawInfo.Size()
+	}
+
 	// 根据压缩类型处理文件
+	if params.CompressionType != "none" {
+		params.reportProgress(BackupProgress{Phase: "packaging", BytesDone: rawSize})
+	}
 	switch params.CompressionType {
 	case "none":
 		// 不压缩，直接使用原始文件
@@ -133,9 +104,8 @@ func (e *MysqldumpExecutor) Execute(ctx context.Context, params *BackupParams) (
 			return nil, fmt.Errorf("failed to compress file: %w", err)
 		}
 		os.Remove(outputFile)
-	default: // gzip
-		// GZIP压缩
-		if err := compressFile(outputFile, finalFile); err != nil {
+	default: // gzip, zstd, lz4, xz
+		if err := compressFileTo(outputFile, finalFile, params.CompressionType, params.CompressionLevel, params.CompressionThreads); err != nil {
 			os.Remove(outputFile)
 			return nil, fmt.Errorf("failed to compress file: %w", err)
 		}
@@ -149,33 +119,159 @@ func (e *MysqldumpExecutor) Execute(ctx context.Context, params *BackupParams) (
 	}
 
 	return &BackupResult{
-		FilePath:  finalFile,
-		FileSize:  fileInfo.Size(),
-		Duration:  time.Since(startTime),
-		Databases: params.Databases,
-		Command:   cmdStr,
+		ToolVersion: localToolVersion(ctx, "mysqldump"),
+		FilePath:    finalFile,
+		FileSize:    fileInfo.Size(),
+		RawSize:     rawSize,
+		Duration:    time.Since(startTime),
+		Databases:   params.Databases,
+		Command:     cmdStr,
+		Coordinates: coords,
 	}, nil
 }
 
-// compressFile 压缩文件（gzip）
-func compressFile(src, dst string) error {
-	srcFile, err := os.Open(src)
-	if err != nil {
-		return err
+// StreamFileName 返回流式备份的产物文件名
+func (e *MysqldumpExecutor) StreamFileName(params *BackupParams) string {
+	name := fmt.Sprintf("backup_%s.sql", time.Now().Format("20060102_150405"))
+	return name + CompressionExtension(params.CompressionType)
+}
+
+// ExecuteStream 执行mysqldump，输出经压缩后直接写入w，不在本地生成文件
+func (e *MysqldumpExecutor) ExecuteStream(ctx context.Context, params *BackupParams, fileName string, w io.Writer) (*BackupResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
 	}
-	defer srcFile.Close()
 
-	dstFile, err := os.Create(dst)
+	args, cmdStr := e.buildArgs(params)
+
+	// 统计写出的字节数
+	counter := &countingWriter{w: w}
+
+	// 根据压缩类型包装输出
+	out, closer, err := newStreamCompressor(counter, params, fileName)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer dstFile.Close()
 
-	gzipWriter := gzip.NewWriter(dstFile)
-	defer gzipWriter.Close()
+	// 保留输出头部用于解析binlog坐标，同时统计压缩前大小
+	header := &headWriter{limit: dumpHeaderSize}
+	raw := &countingWriter{w: out}
 
-	_, err = io.Copy(gzipWriter, srcFile)
-	return err
+	cmd := exec.CommandContext(ctx, "mysqldump", args...)
+	cmd.Stdout = io.MultiWriter(raw, header)
+	if params.Progress != nil {
+		cmd.Stdout = io.MultiWriter(raw, header, newDumpProgressWriter(params))
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("mysqldump failed: %v, stderr: %s", err, stderr.String())
+	}
+	if closer != nil {
+		if err := closer.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress output: %w", err)
+		}
+	}
+
+	return &BackupResult{
+		ToolVersion: localToolVersion(ctx, "mysqldump"),
+		FilePath:    fileName,
+		FileSize:    counter.n,
+		RawSize:     raw.n,
+		Duration:    time.Since(startTime),
+		Databases:   params.Databases,
+		Command:     cmdStr,
+		Coordinates: parseDumpCoordinates(header.buf.String()),
+	}, nil
+}
+
+// buildArgs 构建mysqldump参数，返回参数列表和隐藏密码的命令字符串
+func (e *MysqldumpExecutor) buildArgs(params *BackupParams) ([]string, string) {
+	args := []string{
+
+		fmt.Sprintf("--host=%s", params.Host),
+		fmt.Sprintf("--port=%d", params.Port),
+		fmt.Sprintf("--user=%s", params.Username),
+		fmt.Sprintf("--password=%s", params.Password),
+		"--single-transaction",
+		"--quick",
+		"--lock-tables=false",
+		"--routines",
+		"--triggers",
+		"--events",
+	}
+
+	// 记录binlog坐标（需要服务器开启binlog）
+	if sourceData, ok := params.Options["source_data"].(bool); ok && sourceData {
+		args = append(args, mysqldumpSourceDataFlag())
+	}
+
+	// 添加额外选项（命令行参数字符串）
+	if options, ok := params.Options["extra_args"].(string); ok && options != "" {
+		// 分割命令行参数字符串
+		extraArgs := strings.Fields(options)
+		args = append(args, extraArgs...)
+	}
+
+	// 排除的表
+	if params.TableFilter != nil {
+		for _, table := range params.TableFilter.ExcludeTables {
+			args = append(args, "--ignore-table="+table)
+		}
+	}
+
+	// 添加数据库
+	if len(params.Databases) > 0 {
+		args = append(args, "--databases")
+		args = append(args, params.Databases...)
+	} else {
+		args = append(args, "--all-databases")
+	}
+
+	// 构建完整命令字符串（用于日志，隐藏密码）
+	cmdStr := "mysqldump"
+	for _, arg := range args {
+		if strings.Contains(arg, "--password=") {
+			cmdStr += " --password=***"
+		} else {
+			cmdStr += " " + arg
+		}
+	}
+
+	return args, cmdStr
+}
+
+// countingWriter 统计写入字节数的Writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// headWriter 只保留前limit字节的Writer
+type headWriter struct {
+	buf   bytes.Buffer
+	limit int
+}
+
+func (h *headWriter) Write(p []byte) (int, error) {
+	if remaining := h.limit - h.buf.Len(); remaining > 0 {
+		if len(p) > remaining {
+			h.buf.Write(p[:remaining])
+		} else {
+			h.buf.Write(p)
+		}
+	}
+	return len(p), nil
 }
 
 // compressFileZip 压缩文件（zip）
@@ -0,0 +1,127 @@
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// MysqldumpRestoreExecutor mysqldump备份恢复执行器（通过mysql客户端导入）
+type MysqldumpRestoreExecutor struct{}
+
+func (e *MysqldumpRestoreExecutor) Type() string {
+	return "mysqldump"
+}
+
+func (e *MysqldumpRestoreExecutor) Validate(params *RestoreParams) error {
+	if params.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if params.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if params.FilePath == "" {
+		return fmt.Errorf("file path is required")
+	}
+	if params.TargetDatabase != "" && !ValidDatabaseName(params.TargetDatabase) {
+		return fmt.Errorf("invalid target database %q", params.TargetDatabase)
+	}
+	return nil
+}
+
+func (e *MysqldumpRestoreExecutor) Restore(ctx context.Context, params *RestoreParams) (*RestoreResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
+	}
+
+	// 打开备份文件（自动识别压缩格式）
+	dump, err := openSQLDump(params.FilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer dump.Close()
+
+	// 构建命令参数
+	args := []string{
+		fmt.Sprintf("--host=%s", params.Host),
+		fmt.Sprintf("--port=%d", params.Port),
+		fmt.Sprintf("--user=%s", params.Username),
+		fmt.Sprintf("--password=%s", params.Password),
+		"--default-character-set=utf8mb4",
+	}
+
+	// 指定目标数据库时，去掉备份中的CREATE DATABASE/USE语句，统一导入到目标库
+	var input io.Reader = dump
+	if params.TargetDatabase != "" {
+		header := fmt.Sprintf("CREATE DATABASE IF NOT EXISTS %s;\nUSE %s;\n",
+			quoteIdentifier(params.TargetDatabase), quoteIdentifier(params.TargetDatabase))
+		input = io.MultiReader(strings.NewReader(header), newDatabaseStatementFilter(dump))
+	}
+
+	// 执行mysql命令
+	cmd := exec.CommandContext(ctx, "mysql", args...)
+	cmd.Stdin = input
+
+	// 构建完整命令字符串（用于日志，隐藏密码）
+	cmdStr := "mysql"
+	for _, arg := range args {
+		if strings.Contains(arg, "--password=") {
+			cmdStr += " --password=***"
+		} else {
+			cmdStr += " " + arg
+		}
+	}
+	cmdStr += " < " + params.FilePath
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("mysql restore failed: %v, stderr: %s", err, stderr.String())
+	}
+
+	return &RestoreResult{
+		Duration: time.Since(startTime),
+		Command:  cmdStr,
+	}, nil
+}
+
+// databaseStatementFilter 过滤mysqldump输出中的CREATE DATABASE和USE语句
+type databaseStatementFilter struct {
+	reader  *bufio.Reader
+	pending []byte
+	err     error
+}
+
+func newDatabaseStatementFilter(r io.Reader) io.Reader {
+	return &databaseStatementFilter{reader: bufio.NewReaderSize(r, 1024*1024)}
+}
+
+func (f *databaseStatementFilter) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 {
+		if f.err != nil {
+			return 0, f.err
+		}
+		line, err := f.reader.ReadBytes('\n')
+		if !isDatabaseStatement(line) {
+			f.pending = line
+		}
+		f.err = err
+	}
+
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+// isDatabaseStatement 判断是否为切换或创建数据库的语句
+func isDatabaseStatement(line []byte) bool {
+	return bytes.HasPrefix(line, []byte("CREATE DATABASE ")) || bytes.HasPrefix(line, []byte("USE `"))
+}
@@ -0,0 +1,212 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// MysqlshExecutor MySQL Shell备份执行器（util.dumpInstance / util.dumpSchemas）
+type MysqlshExecutor struct{}
+
+func (e *MysqlshExecutor) Type() string {
+	return "mysqlsh"
+}
+
+func (e *MysqlshExecutor) Validate(params *BackupParams) error {
+	if params.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if params.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if params.OutputPath == "" {
+		return fmt.Errorf("output path is required")
+	}
+	return nil
+}
+
+func (e *MysqlshExecutor) Execute(ctx context.Context, params *BackupParams) (*BackupResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
+	}
+
+	// 创建输出目录（util.dump*要求目录不存在或为空）
+	timestamp := time.Now().Format("20060102_150405")
+	outputDir := filepath.Join(params.OutputPath, fmt.Sprintf("backup_%s", timestamp))
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	script, err := mysqlshDumpScript(params, outputDir)
+	if err != nil {
+		os.RemoveAll(outputDir)
+		return nil, err
+	}
+	args, cmdStr := mysqlshArgs(params.Host, params.Port, params.Username, params.Password, script)
+
+	// 执行mysqlsh命令
+	cmd := exec.CommandContext(ctx, "mysqlsh", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	// mysqlsh不输出可解析的进度，定期统计输出目录大小
+	stop := make(chan struct{})
+	go watchDirSize(params, outputDir, stop, nil)
+	err = cmd.Run()
+	close(stop)
+	if err != nil {
+		os.RemoveAll(outputDir)
+		return nil, fmt.Errorf("mysqlsh dump failed: %v, stderr: %s", err, stderr.String())
+	}
+
+	// 从@.json解析binlog坐标
+	var coords *BinlogCoordinates
+	if metadata, err := os.ReadFile(filepath.Join(outputDir, "@.json")); err == nil {
+		coords = parseMysqlshMetadata(metadata)
+	}
+
+	// 记录压缩前大小（数据文件已由mysqlsh按chunk压缩）
+	rawSize := dirSize(outputDir)
+
+	// 根据压缩类型打包目录，与mydumper一致
+	params.reportProgress(BackupProgress{Phase: "packaging", BytesDone: rawSize})
+	var finalPath string
+	switch params.CompressionType {
+	case "none":
+		finalPath = outputDir + ".tar"
+		if err := createTar(outputDir, finalPath); err != nil {
+			os.RemoveAll(outputDir)
+			return nil, fmt.Errorf("failed to create tar: %w", err)
+		}
+	case "zip":
+		finalPath = outputDir + ".zip"
+		if err := createZip(outputDir, finalPath); err != nil {
+			os.RemoveAll(outputDir)
+			return nil, fmt.Errorf("failed to create zip: %w", err)
+		}
+	default: // gzip, zstd, lz4, xz
+		finalPath = outputDir + ".tar" + CompressionExtension(params.CompressionType)
+		if err := createTarCompressed(outputDir, finalPath, params); err != nil {
+			os.RemoveAll(outputDir)
+			return nil, fmt.Errorf("failed to create %s: %w", filepath.Base(finalPath), err)
+		}
+	}
+
+	// 删除原始目录
+	os.RemoveAll(outputDir)
+
+	// 获取文件大小
+	fileInfo, err := os.Stat(finalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info: %w", err)
+	}
+
+	return &BackupResult{
+		ToolVersion: localToolVersion(ctx, "mysqlsh"),
+		FilePath:    finalPath,
+		FileSize:    fileInfo.Size(),
+		RawSize:     rawSize,
+		Duration:    time.Since(startTime),
+		Databases:   params.Databases,
+		Command:     cmdStr,
+		Coordinates: coords,
+	}, nil
+}
+
+// mysqlshDumpScript 生成执行util.dumpInstance或util.dumpSchemas的JavaScript语句。
+// 支持的选项：threads（默认4）、chunk_size（bytesPerChunk，默认64M）、compression（mysqlsh内部压缩，默认zstd），
+// dump_options中的键值原样合并到dump选项中
+func mysqlshDumpScript(params *BackupParams, outputDir string) (string, error) {
+	threads := 4
+	if v := optionString(params.Options, "threads"); v != "" {
+		fmt.Sscanf(v, "%d", &threads)
+	}
+	chunkSize := "64M"
+	if v := optionString(params.Options, "chunk_size"); v != "" {
+		chunkSize = v
+	}
+	compression := "zstd"
+	if v := optionString(params.Options, "compression"); v != "" {
+		compression = v
+	}
+
+	options := map[string]interface{}{
+		"threads":       threads,
+		"chunking":      true,
+		"bytesPerChunk": chunkSize,
+		"compression":   compression,
+		"consistent":    true,
+		"showProgress":  false,
+	}
+
+	// 表级过滤
+	if filter := params.TableFilter; filter != nil {
+		if len(filter.IncludeTables) > 0 {
+			options["includeTables"] = mysqlshTableNames(filter.IncludeTables)
+		} else if len(filter.ExcludeTables) > 0 {
+			options["excludeTables"] = mysqlshTableNames(filter.ExcludeTables)
+		}
+	}
+
+	if extra, ok := params.Options["dump_options"].(map[string]interface{}); ok {
+		for key, value := range extra {
+			options[key] = value
+		}
+	}
+
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode dump options: %w", err)
+	}
+	dirJSON, _ := json.Marshal(outputDir)
+
+	if len(params.Databases) > 0 {
+		schemasJSON, _ := json.Marshal(params.Databases)
+		return fmt.Sprintf("util.dumpSchemas(%s, %s, %s)", schemasJSON, dirJSON, optionsJSON), nil
+	}
+	return fmt.Sprintf("util.dumpInstance(%s, %s)", dirJSON, optionsJSON), nil
+}
+
+// mysqlshTableNames 将db.table转换为mysqlsh要求的带反引号的表名
+func mysqlshTableNames(tables []string) []string {
+	names := make([]string, 0, len(tables))
+	for _, table := range tables {
+		dbName, tableName, _ := strings.Cut(table, ".")
+		names = append(names, quoteIdentifier(dbName)+"."+quoteIdentifier(tableName))
+	}
+	return names
+}
+
+// mysqlshArgs 构建以JavaScript模式执行script的mysqlsh参数，返回参数列表和隐藏密码的命令字符串
+func mysqlshArgs(host string, port int, username, password, script string) ([]string, string) {
+	args := []string{
+		fmt.Sprintf("--host=%s", host),
+		fmt.Sprintf("--port=%d", port),
+		fmt.Sprintf("--user=%s", username),
+		fmt.Sprintf("--password=%s", password),
+		"--no-wizard",
+		"--js",
+		"-e", script,
+	}
+
+	// 构建完整命令字符串（用于日志，隐藏密码）
+	cmdStr := "mysqlsh"
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "--password=") {
+			cmdStr += " --password=***"
+		} else {
+			cmdStr += " " + arg
+		}
+	}
+	return args, cmdStr
+}
@@ -0,0 +1,118 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// MysqlshRestoreExecutor mysqlsh备份恢复执行器（通过util.loadDump导入，目标实例需开启local_infile）
+type MysqlshRestoreExecutor struct{}
+
+func (e *MysqlshRestoreExecutor) Type() string {
+	return "mysqlsh"
+}
+
+func (e *MysqlshRestoreExecutor) Validate(params *RestoreParams) error {
+	if params.Host == "" {
+		return fmt.Errorf("host is required")
+	}
+	if params.Username == "" {
+		return fmt.Errorf("username is required")
+	}
+	if params.FilePath == "" {
+		return fmt.Errorf("file path is required")
+	}
+	if params.WorkDir == "" {
+		return fmt.Errorf("work directory is required")
+	}
+	return nil
+}
+
+func (e *MysqlshRestoreExecutor) Restore(ctx context.Context, params *RestoreParams) (*RestoreResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
+	}
+
+	// 解压备份归档
+	params.reportProgress(0, "extracting archive")
+	dumpDir := filepath.Join(params.WorkDir, "mysqlsh")
+	if err := extractArchive(params.FilePath, dumpDir); err != nil {
+		return nil, fmt.Errorf("failed to extract backup: %w", err)
+	}
+	defer os.RemoveAll(dumpDir)
+
+	if _, err := os.Stat(filepath.Join(dumpDir, "@.json")); err != nil {
+		return nil, fmt.Errorf("backup is not a mysqlsh dump: @.json not found")
+	}
+
+	script, err := mysqlshLoadScript(params, dumpDir)
+	if err != nil {
+		return nil, err
+	}
+	args, cmdStr := mysqlshArgs(params.Host, params.Port, params.Username, params.Password, script)
+
+	params.reportProgress(10, "loading dump")
+	params.logStep("running util.loadDump with %s", script)
+
+	// 执行mysqlsh命令
+	cmd := exec.CommandContext(ctx, "mysqlsh", args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("mysqlsh load failed: %v, stderr: %s", err, stderr.String())
+	}
+
+	params.reportProgress(100, "util.loadDump finished")
+
+	return &RestoreResult{
+		Duration: time.Since(startTime),
+		Command:  cmdStr,
+	}, nil
+}
+
+// mysqlshLoadScript 生成执行util.loadDump的JavaScript语句。
+// 支持的选项：threads（默认4）、ignore_existing_objects，load_options中的键值原样合并到load选项中；
+// 指定目标库时通过schema选项导入到该库（仅适用于单库备份）
+func mysqlshLoadScript(params *RestoreParams, dumpDir string) (string, error) {
+	threads := 4
+	if v := optionString(params.Options, "threads"); v != "" {
+		fmt.Sscanf(v, "%d", &threads)
+	}
+
+	options := map[string]interface{}{
+		"threads":       threads,
+		"resetProgress": true,
+		"skipBinlog":    true,
+		"showProgress":  false,
+	}
+	if ignore, ok := params.Options["ignore_existing_objects"].(bool); ok && ignore {
+		options["ignoreExistingObjects"] = true
+	}
+	if params.TargetDatabase != "" {
+		options["schema"] = params.TargetDatabase
+	}
+
+	if extra, ok := params.Options["load_options"].(map[string]interface{}); ok {
+		for key, value := range extra {
+			options[key] = value
+		}
+	}
+
+	optionsJSON, err := json.Marshal(options)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode load options: %w", err)
+	}
+	dirJSON, _ := json.Marshal(dumpDir)
+
+	return fmt.Sprintf("util.loadDump(%s, %s)", dirJSON, optionsJSON), nil
+}
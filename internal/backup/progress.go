@@ -0,0 +1,234 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BackupProgress 备份执行过程中上报的进度
+type BackupProgress struct {
+	Phase       string `json:"phase"`                  // dumping, packaging, downloading, encrypting, uploading
+	BytesDone   int64  `json:"bytes_done"`             // 已导出的数据量（压缩前）
+	Database    string `json:"database,omitempty"`     // 当前正在导出的库
+	Table       string `json:"table,omitempty"`        // 当前正在导出的表（xtrabackup为正在复制的文件）
+	TablesDone  int    `json:"tables_done,omitempty"`  // 已完成的表数量
+	TablesTotal int    `json:"tables_total,omitempty"` // 需要导出的表总数，0表示未知
+	LSN         int64  `json:"lsn,omitempty"`          // xtrabackup当前扫描到的LSN
+	Message     string `json:"message,omitempty"`
+}
+
+// BackupProgressFunc 备份进度回调
+type BackupProgressFunc func(progress BackupProgress)
+
+// progressInterval 字节数变化时上报进度的最小间隔
+const progressInterval = time.Second
+
+// reportProgress 在回调存在时上报进度
+func (p *BackupParams) reportProgress(progress BackupProgress) {
+	if p.Progress != nil {
+		p.Progress(progress)
+	}
+}
+
+// queryer 可执行查询的连接（*sql.DB或*sql.Conn）
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// countBackupTables 统计本次需要备份的表数量（已应用表过滤），databases为空表示全部非系统库
+func countBackupTables(ctx context.Context, q queryer, databases []string, filter *TableFilter) (int, error) {
+	rows, err := q.QueryContext(ctx, "SELECT TABLE_SCHEMA, TABLE_NAME FROM information_schema.TABLES WHERE TABLE_TYPE = 'BASE TABLE'")
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var dbName, table string
+		if err := rows.Scan(&dbName, &table); err != nil {
+			return 0, err
+		}
+		if len(databases) > 0 && !containsString(databases, dbName) {
+			continue
+		}
+		if len(databases) == 0 && systemDatabases[dbName] {
+			continue
+		}
+		if filter.Excluded(dbName, table) {
+			continue
+		}
+		count++
+	}
+	return count, rows.Err()
+}
+
+// estimateTableCount 连接源库统计需要备份的表数量，仅在需要上报进度时查询，失败时返回0
+func estimateTableCount(params *BackupParams) int {
+	if params.Progress == nil {
+		return 0
+	}
+	db, err := openMySQL(params.Host, params.Port, params.Username, params.Password)
+	if err != nil {
+		return 0
+	}
+	defer db.Close()
+
+	count, _ := countBackupTables(context.Background(), db, params.Databases, params.TableFilter)
+	return count
+}
+
+// watchDirSize 定期统计输出目录大小并上报进度，直到stop被关闭；update可在上报前补充其他字段
+func watchDirSize(params *BackupParams, dir string, stop <-chan struct{}, update func(progress *BackupProgress)) {
+	if params.Progress == nil {
+		return
+	}
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			progress := BackupProgress{Phase: "dumping", BytesDone: dirSize(dir)}
+			if update != nil {
+				update(&progress)
+			}
+			params.reportProgress(progress)
+		}
+	}
+}
+
+// dumpProgressWriter 统计SQL输出的字节数，并从mysqldump格式的注释中解析当前的库和表
+type dumpProgressWriter struct {
+	params      *BackupParams
+	bytes       int64
+	database    string
+	table       string
+	tablesDone  int
+	tablesTotal int
+	pending     []byte // 跨越两次写入的注释行
+	lastReport  time.Time
+}
+
+var (
+	dumpMarkerPrefix   = []byte("\n-- ")
+	dumpDatabaseMarker = "-- Current Database: "
+	dumpTableMarker    = "-- Dumping data for table "
+)
+
+func newDumpProgressWriter(params *BackupParams) *dumpProgressWriter {
+	return &dumpProgressWriter{params: params, tablesTotal: estimateTableCount(params)}
+}
+
+func (w *dumpProgressWriter) Write(p []byte) (int, error) {
+	w.bytes += int64(len(p))
+	changed := w.scan(p)
+	if changed || time.Since(w.lastReport) >= progressInterval {
+		w.lastReport = time.Now()
+		w.params.reportProgress(BackupProgress{
+			Phase:       "dumping",
+			BytesDone:   w.bytes,
+			Database:    w.database,
+			Table:       w.table,
+			TablesDone:  w.tablesDone,
+			TablesTotal: w.tablesTotal,
+		})
+	}
+	return len(p), nil
+}
+
+// scan 查找以"-- "开头的注释行，返回当前库或表是否变化
+func (w *dumpProgressWriter) scan(p []byte) bool {
+	changed := false
+	if w.pending != nil {
+		end := bytes.IndexByte(p, '\n')
+		if end < 0 {
+			if len(w.pending) < 4096 {
+				w.pending = append(w.pending, p...)
+			}
+			return false
+		}
+		line := append(w.pending, p[:end]...)
+		w.pending = nil
+		changed = w.parseLine(string(line))
+	}
+
+	for i := 0; i < len(p); {
+		j := bytes.Index(p[i:], dumpMarkerPrefix)
+		if j < 0 {
+			break
+		}
+		lineStart := i + j + 1
+		end := bytes.IndexByte(p[lineStart:], '\n')
+		if end < 0 {
+			w.pending = append([]byte(nil), p[lineStart:]...)
+			break
+		}
+		if w.parseLine(string(p[lineStart : lineStart+end])) {
+			changed = true
+		}
+		i = lineStart + end
+	}
+	return changed
+}
+
+func (w *dumpProgressWriter) parseLine(line string) bool {
+	switch {
+	case strings.HasPrefix(line, dumpDatabaseMarker):
+		w.database = strings.Trim(strings.TrimPrefix(line, dumpDatabaseMarker), "` \r")
+		return true
+	case strings.HasPrefix(line, dumpTableMarker):
+		// 开始导出下一张表的数据时，上一张表已完成
+		if w.table != "" {
+			w.tablesDone++
+		}
+		w.table = strings.Trim(strings.TrimPrefix(line, dumpTableMarker), "` \r")
+		return true
+	}
+	return false
+}
+
+// logLineWriter 按行处理命令输出并保留最近的日志用于错误信息，onLine在持有mu时调用
+type logLineWriter struct {
+	mu      sync.Mutex
+	partial []byte
+	lines   []string
+	onLine  func(line string)
+}
+
+func (l *logLineWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data := append(l.partial, p...)
+	for {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			break
+		}
+		line := string(data[:end])
+		l.lines = append(l.lines, line)
+		if len(l.lines) > 20 {
+			l.lines = l.lines[1:]
+		}
+		if l.onLine != nil {
+			l.onLine(line)
+		}
+		data = data[end+1:]
+	}
+	l.partial = append([]byte(nil), data...)
+	return len(p), nil
+}
+
+// tail 返回最近的日志
+func (l *logLineWriter) tail() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return strings.Join(append(l.lines, string(l.partial)), "\n")
+}
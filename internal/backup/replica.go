@@ -0,0 +1,98 @@
+package backup
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// ReplicaStatus 从库复制状态，兼容SHOW REPLICA STATUS和旧版SHOW SLAVE STATUS的列名
+type ReplicaStatus struct {
+	SourceHost      string // 主库地址
+	SourcePort      int    // 主库端口
+	SourceLogFile   string // SQL线程已执行到的主库binlog文件
+	SourceLogPos    int64  // SQL线程已执行到的主库binlog位置
+	ExecutedGTIDSet string // 从库已执行的GTID集合
+	SecondsBehind   *int64 // 复制延迟（秒），复制未运行时为nil
+	IORunning       bool
+	SQLRunning      bool
+	LastError       string // 最近一次SQL线程错误
+}
+
+// SourceCoordinates 返回从库已执行到的主库binlog坐标
+func (s *ReplicaStatus) SourceCoordinates() *BinlogCoordinates {
+	if s.SourceLogFile == "" {
+		return nil
+	}
+	return &BinlogCoordinates{
+		File:     s.SourceLogFile,
+		Position: s.SourceLogPos,
+		GTIDSet:  normalizeGTIDSet(s.ExecutedGTIDSet),
+	}
+}
+
+// QueryReplicaStatus 查询复制状态，服务器不是从库时返回nil
+func QueryReplicaStatus(db *sql.DB) (*ReplicaStatus, error) {
+	rows, err := db.Query("SHOW REPLICA STATUS")
+	if err != nil {
+		// MySQL 8.0.22之前只支持SHOW SLAVE STATUS
+		rows, err = db.Query("SHOW SLAVE STATUS")
+		if err != nil {
+			return nil, fmt.Errorf("failed to query replica status: %w", err)
+		}
+	}
+	defer rows.Close()
+
+	values, err := scanRowStrings(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replica status: %w", err)
+	}
+	if values == nil {
+		return nil, nil
+	}
+
+	// 新旧版本列名不同，依次尝试
+	get := func(names ...string) string {
+		for _, name := range names {
+			if v, ok := values[name]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+
+	status := &ReplicaStatus{
+		SourceHost:      get("Source_Host", "Master_Host"),
+		SourceLogFile:   get("Relay_Source_Log_File", "Relay_Master_Log_File"),
+		ExecutedGTIDSet: get("Executed_Gtid_Set"),
+		IORunning:       get("Replica_IO_Running", "Slave_IO_Running") == "Yes",
+		SQLRunning:      get("Replica_SQL_Running", "Slave_SQL_Running") == "Yes",
+		LastError:       get("Last_SQL_Error"),
+	}
+	status.SourcePort, _ = strconv.Atoi(get("Source_Port", "Master_Port"))
+	status.SourceLogPos, _ = strconv.ParseInt(get("Exec_Source_Log_Pos", "Exec_Master_Log_Pos"), 10, 64)
+	if lag, err := strconv.ParseInt(get("Seconds_Behind_Source", "Seconds_Behind_Master"), 10, 64); err == nil {
+		status.SecondsBehind = &lag
+	}
+	return status, nil
+}
+
+// StopReplicaSQLThread 停止从库SQL线程
+func StopReplicaSQLThread(db *sql.DB) error {
+	if _, err := db.Exec("STOP REPLICA SQL_THREAD"); err != nil {
+		if _, err := db.Exec("STOP SLAVE SQL_THREAD"); err != nil {
+			return fmt.Errorf("failed to stop replica SQL thread: %w", err)
+		}
+	}
+	return nil
+}
+
+// StartReplicaSQLThread 启动从库SQL线程
+func StartReplicaSQLThread(db *sql.DB) error {
+	if _, err := db.Exec("START REPLICA SQL_THREAD"); err != nil {
+		if _, err := db.Exec("START SLAVE SQL_THREAD"); err != nil {
+			return fmt.Errorf("failed to start replica SQL thread: %w", err)
+		}
+	}
+	return nil
+}
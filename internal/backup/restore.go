@@ -0,0 +1,290 @@
+package backup
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// databaseNamePattern 恢复目标库名只允许常见的标识符字符，避免拼接到SQL和命令行参数中出错
+var databaseNamePattern = regexp.MustCompile(`^[0-9A-Za-z_$-]{1,64}$`)
+
+// ValidDatabaseName 检查恢复目标数据库名是否合法
+func ValidDatabaseName(name string) bool {
+	return databaseNamePattern.MatchString(name)
+}
+
+// RestoreExecutor 恢复执行器接口
+type RestoreExecutor interface {
+	// Restore 执行恢复
+	Restore(ctx context.Context, params *RestoreParams) (*RestoreResult, error)
+	// Type 获取执行器类型
+	Type() string
+	// Validate 验证参数
+	Validate(params *RestoreParams) error
+}
+
+// RestoreParams 恢复参数
+type RestoreParams struct {
+	Host             string
+	Port             int
+	Username         string
+	Password         string
+	TargetDatabase   string                 // 目标数据库，为空表示使用备份文件中的库名
+	FilePath         string                 // 本地备份文件路径
+	IncrementalFiles []string               // xtrabackup增量/差异备份文件，按应用顺序排列
+	WorkDir          string                 // 临时工作目录
+	Databases        []string               // 备份中包含的数据库
+	Options          map[string]interface{} // 额外选项
+	SSHConfig        *SSHConfig             // xtrabackup需要
+	Progress         ProgressFunc           // 进度回调，可为空
+	StepLog          func(message string)   // 步骤日志回调，可为空
+}
+
+// ProgressFunc 进度回调，percent为0-100的进度，message为当前步骤说明
+type ProgressFunc func(percent int, message string)
+
+// reportProgress 在回调存在时上报进度
+func (p *RestoreParams) reportProgress(percent int, message string) {
+	if p.Progress != nil {
+		p.Progress(percent, message)
+	}
+}
+
+// logStep 在回调存在时记录步骤日志
+func (p *RestoreParams) logStep(format string, args ...interface{}) {
+	if p.StepLog != nil {
+		p.StepLog(fmt.Sprintf(format, args...))
+	}
+}
+
+// RestoreResult 恢复结果
+type RestoreResult struct {
+	Duration time.Duration
+	Command  string // 完整的恢复命令
+}
+
+// NewRestoreExecutor 创建恢复执行器
+func NewRestoreExecutor(backupType string) (RestoreExecutor, error) {
+	switch backupType {
+	case "mysqldump", "gonative":
+		return &MysqldumpRestoreExecutor{}, nil
+	case "mydumper":
+		return &MydumperRestoreExecutor{}, nil
+	case "xtrabackup":
+		return &XtrabackupRestoreExecutor{}, nil
+	case "mysqlsh":
+		return &MysqlshRestoreExecutor{}, nil
+	default:
+		return nil, fmt.Errorf("restore is not supported for backup type: %s", backupType)
+	}
+}
+
+// openSQLDump 根据扩展名打开SQL备份文件（.sql, .sql.zip, .sql.gz/.zst/.lz4/.xz），返回解压后的数据流
+func openSQLDump(path string) (io.ReadCloser, error) {
+	if ext, ok := compressedSuffix(path, ".sql"); ok && ext != ".gz" {
+		return openCompressed(path, ext)
+	}
+
+	switch {
+	case strings.HasSuffix(path, ".sql.gz"):
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		return &multiCloser{Reader: gzipReader, closers: []io.Closer{gzipReader, file}}, nil
+
+	case strings.HasSuffix(path, ".sql.zip"):
+		zipReader, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zip file: %w", err)
+		}
+		for _, f := range zipReader.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			entry, err := f.Open()
+			if err != nil {
+				zipReader.Close()
+				return nil, fmt.Errorf("failed to open zip entry: %w", err)
+			}
+			return &multiCloser{Reader: entry, closers: []io.Closer{entry, zipReader}}, nil
+		}
+		zipReader.Close()
+		return nil, fmt.Errorf("zip file contains no SQL file")
+
+	case strings.HasSuffix(path, ".sql"):
+		return os.Open(path)
+
+	default:
+		return nil, fmt.Errorf("unsupported backup file format: %s", path)
+	}
+}
+
+// multiCloser 关闭时依次关闭所有底层资源
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (m *multiCloser) Close() error {
+	var firstErr error
+	for _, c := range m.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// extractArchive 将备份归档（.tar, .zip, .tar.gz/.zst/.lz4/.xz）解压到目标目录
+func extractArchive(archivePath, destDir string) error {
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	if ext, ok := compressedSuffix(archivePath, ".tar"); ok && ext != ".gz" {
+		reader, err := openCompressed(archivePath, ext)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		return extractTar(reader, destDir)
+	}
+
+	switch {
+	case strings.HasSuffix(archivePath, ".tar.gz"):
+		file, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		gzipReader, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer gzipReader.Close()
+
+		return extractTar(gzipReader, destDir)
+
+	case strings.HasSuffix(archivePath, ".tar"):
+		file, err := os.Open(archivePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		return extractTar(file, destDir)
+
+	case strings.HasSuffix(archivePath, ".zip"):
+		return extractZip(archivePath, destDir)
+
+	default:
+		return fmt.Errorf("unsupported archive format: %s", archivePath)
+	}
+}
+
+// extractTar 解压tar数据流
+func extractTar(r io.Reader, destDir string) error {
+	tarReader := tar.NewReader(r)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar: %w", err)
+		}
+
+		target, err := safeJoin(destDir, header.Name)
+		if err != nil {
+			return err
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeFileFrom(target, tarReader); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractZip 解压zip文件
+func extractZip(archivePath, destDir string) error {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open zip file: %w", err)
+	}
+	defer zipReader.Close()
+
+	for _, f := range zipReader.File {
+		target, err := safeJoin(destDir, f.Name)
+		if err != nil {
+			return err
+		}
+
+		if f.FileInfo().IsDir() {
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			continue
+		}
+
+		entry, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to open zip entry: %w", err)
+		}
+		err = writeFileFrom(target, entry)
+		entry.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// safeJoin 拼接归档内路径，防止路径穿越
+func safeJoin(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, filepath.FromSlash(name))
+	if target != filepath.Clean(destDir) && !strings.HasPrefix(target, filepath.Clean(destDir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid path in archive: %s", name)
+	}
+	return target, nil
+}
+
+// writeFileFrom 将数据流写入文件
+func writeFileFrom(path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, r)
+	return err
+}
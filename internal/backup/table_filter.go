@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TableFilter 表级过滤结果，由include/exclude模式和源库实际的库表解析得出
+type TableFilter struct {
+	IncludeTables     []string // 配置了include模式时需要备份的表（db.table）
+	ExcludeTables     []string // 不备份的表（db.table）
+	ExcludeDatabases  []string // 所有表都被排除的库
+	UnmatchedPatterns []string // 没有匹配到任何表的模式
+}
+
+// CompileTablePattern 编译表过滤模式：db.table格式，支持*和?通配符；以~开头时为匹配db.table的正则表达式
+func CompileTablePattern(pattern string) (*regexp.Regexp, error) {
+	pattern = strings.TrimSpace(pattern)
+	if strings.HasPrefix(pattern, "~") {
+		re, err := regexp.Compile(pattern[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid table pattern %q: %w", pattern, err)
+		}
+		return re, nil
+	}
+
+	dbPart, tablePart, ok := strings.Cut(pattern, ".")
+	if !ok || dbPart == "" || tablePart == "" {
+		return nil, fmt.Errorf("invalid table pattern %q, expected db.table", pattern)
+	}
+	return regexp.Compile("^" + wildcardRegex(dbPart) + `\.` + wildcardRegex(tablePart) + "$")
+}
+
+// wildcardRegex 将通配符转换为正则表达式
+func wildcardRegex(s string) string {
+	quoted := regexp.QuoteMeta(s)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	return strings.ReplaceAll(quoted, `\?`, ".")
+}
+
+// ResolveTableFilter 根据模式和库表列表（库名 -> 表名）计算过滤结果，
+// 没有匹配到任何表的模式记录在UnmatchedPatterns中，由调用方决定报错还是告警
+func ResolveTableFilter(tables map[string][]string, include, exclude []string) (*TableFilter, error) {
+	includePatterns, err := compileTablePatterns(include)
+	if err != nil {
+		return nil, err
+	}
+	excludePatterns, err := compileTablePatterns(exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	dbNames := make([]string, 0, len(tables))
+	for dbName := range tables {
+		dbNames = append(dbNames, dbName)
+	}
+	sort.Strings(dbNames)
+
+	matched := make(map[int]bool)
+	filter := &TableFilter{}
+	for _, dbName := range dbNames {
+		excludedCount := 0
+		for _, table := range tables[dbName] {
+			name := dbName + "." + table
+			selected := len(includePatterns) == 0
+			for i, re := range includePatterns {
+				if re.MatchString(name) {
+					selected = true
+					matched[i] = true
+				}
+			}
+			for i, re := range excludePatterns {
+				if re.MatchString(name) {
+					selected = false
+					matched[len(includePatterns)+i] = true
+				}
+			}
+
+			if !selected {
+				filter.ExcludeTables = append(filter.ExcludeTables, name)
+				excludedCount++
+			} else if len(includePatterns) > 0 {
+				filter.IncludeTables = append(filter.IncludeTables, name)
+			}
+		}
+		if excludedCount > 0 && excludedCount == len(tables[dbName]) {
+			filter.ExcludeDatabases = append(filter.ExcludeDatabases, dbName)
+		}
+	}
+
+	for i, pattern := range append(append([]string{}, include...), exclude...) {
+		if !matched[i] {
+			filter.UnmatchedPatterns = append(filter.UnmatchedPatterns, pattern)
+		}
+	}
+	if len(includePatterns) > 0 && len(filter.IncludeTables) == 0 {
+		return nil, fmt.Errorf("table filters exclude all tables")
+	}
+
+	return filter, nil
+}
+
+// compileTablePatterns 编译多个表过滤模式
+func compileTablePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := CompileTablePattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Excluded 返回表是否被排除
+func (f *TableFilter) Excluded(dbName, table string) bool {
+	if f == nil {
+		return false
+	}
+	name := dbName + "." + table
+	for _, excluded := range f.ExcludeTables {
+		if excluded == name {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString 检查列表中是否包含s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// tableListRegex 生成精确匹配db.table列表的正则表达式
+func tableListRegex(tables []string) string {
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = regexp.QuoteMeta(table)
+	}
+	return "^(" + strings.Join(quoted, "|") + ")$"
+}
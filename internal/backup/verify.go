@@ -0,0 +1,199 @@
+package backup
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SchemaSnapshot 数据库表数量和校验和快照，用于恢复验证
+type SchemaSnapshot struct {
+	Databases map[string]*DatabaseSnapshot `json:"databases"`
+}
+
+// DatabaseSnapshot 单个数据库的快照
+type DatabaseSnapshot struct {
+	TableCount int              `json:"table_count"`
+	Checksums  map[string]int64 `json:"checksums,omitempty"` // 表名 -> CHECKSUM TABLE结果
+}
+
+// systemDatabases 不参与验证的系统库
+var systemDatabases = map[string]bool{
+	"information_schema": true,
+	"performance_schema": true,
+	"mysql":              true,
+	"sys":                true,
+}
+
+// CaptureSnapshot 采集数据库的表数量，withChecksum为true时同时计算每张表的CHECKSUM TABLE，
+// databases为空表示全部非系统库
+func CaptureSnapshot(host string, port int, username, password string, databases []string, withChecksum bool) (*SchemaSnapshot, error) {
+	db, err := openMySQL(host, port, username, password)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if len(databases) == 0 {
+		if databases, err = listUserDatabases(db); err != nil {
+			return nil, err
+		}
+	}
+
+	snapshot := &SchemaSnapshot{Databases: make(map[string]*DatabaseSnapshot)}
+	for _, dbName := range databases {
+		tables, err := listBaseTables(db, dbName)
+		if err != nil {
+			return nil, err
+		}
+
+		dbSnapshot := &DatabaseSnapshot{TableCount: len(tables)}
+		if withChecksum {
+			dbSnapshot.Checksums = make(map[string]int64)
+			for _, table := range tables {
+				checksum, err := checksumTable(db, dbName, table)
+				if err != nil {
+					return nil, err
+				}
+				dbSnapshot.Checksums[table] = checksum
+			}
+		}
+		snapshot.Databases[dbName] = dbSnapshot
+	}
+
+	return snapshot, nil
+}
+
+// Exclude 去掉被表级过滤排除的表，使基准与实际备份的内容一致
+func (s *SchemaSnapshot) Exclude(filter *TableFilter) {
+	if filter == nil {
+		return
+	}
+	for _, table := range filter.ExcludeTables {
+		dbName, tableName, _ := strings.Cut(table, ".")
+		dbSnapshot, ok := s.Databases[dbName]
+		if !ok {
+			continue
+		}
+		dbSnapshot.TableCount--
+		delete(dbSnapshot.Checksums, tableName)
+	}
+}
+
+// CompareSnapshot 比较快照，返回差异描述，为空表示一致
+func CompareSnapshot(expected, actual *SchemaSnapshot) []string {
+	var diffs []string
+
+	dbNames := make([]string, 0, len(expected.Databases))
+	for name := range expected.Databases {
+		dbNames = append(dbNames, name)
+	}
+	sort.Strings(dbNames)
+
+	for _, dbName := range dbNames {
+		want := expected.Databases[dbName]
+		got, ok := actual.Databases[dbName]
+		if !ok {
+			diffs = append(diffs, fmt.Sprintf("database %s is missing", dbName))
+			continue
+		}
+		if want.TableCount != got.TableCount {
+			diffs = append(diffs, fmt.Sprintf("database %s: expected %d tables, got %d", dbName, want.TableCount, got.TableCount))
+		}
+
+		tables := make([]string, 0, len(want.Checksums))
+		for table := range want.Checksums {
+			tables = append(tables, table)
+		}
+		sort.Strings(tables)
+
+		for _, table := range tables {
+			gotChecksum, ok := got.Checksums[table]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("table %s.%s is missing", dbName, table))
+				continue
+			}
+			if gotChecksum != want.Checksums[table] {
+				diffs = append(diffs, fmt.Sprintf("table %s.%s: checksum mismatch (expected %d, got %d)", dbName, table, want.Checksums[table], gotChecksum))
+			}
+		}
+	}
+
+	return diffs
+}
+
+// DropDatabases 删除数据库（用于清理沙箱）
+func DropDatabases(host string, port int, username, password string, databases []string) error {
+	db, err := openMySQL(host, port, username, password)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	for _, dbName := range databases {
+		if systemDatabases[dbName] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("DROP DATABASE IF EXISTS %s", quoteIdentifier(dbName))); err != nil {
+			return fmt.Errorf("failed to drop database %s: %w", dbName, err)
+		}
+	}
+	return nil
+}
+
+// listUserDatabases 列出非系统库
+func listUserDatabases(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SHOW DATABASES")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query databases: %w", err)
+	}
+	defer rows.Close()
+
+	var databases []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		if !systemDatabases[name] {
+			databases = append(databases, name)
+		}
+	}
+	return databases, rows.Err()
+}
+
+// listBaseTables 列出库中的普通表（不含视图）
+func listBaseTables(db *sql.DB, dbName string) ([]string, error) {
+	rows, err := db.Query("SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME", dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tables of %s: %w", dbName, err)
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		tables = append(tables, name)
+	}
+	return tables, rows.Err()
+}
+
+// checksumTable 计算表的校验和
+func checksumTable(db *sql.DB, dbName, table string) (int64, error) {
+	var name string
+	var checksum sql.NullInt64
+	query := fmt.Sprintf("CHECKSUM TABLE %s.%s", quoteIdentifier(dbName), quoteIdentifier(table))
+	if err := db.QueryRow(query).Scan(&name, &checksum); err != nil {
+		return 0, fmt.Errorf("failed to checksum %s.%s: %w", dbName, table, err)
+	}
+	return checksum.Int64, nil
+}
+
+// quoteIdentifier 使用反引号转义标识符
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -14,6 +18,40 @@ import (
 // XtrabackupExecutor xtrabackup备份执行器（通过SSH远程执行）
 type XtrabackupExecutor struct{}
 
+// XtrabackupCheckpoints xtrabackup_checkpoints中的LSN信息
+type XtrabackupCheckpoints struct {
+	BackupType string // full-backuped, incremental, full-prepared...
+	FromLSN    int64
+	ToLSN      int64
+}
+
+// parseXtrabackupCheckpoints 解析xtrabackup_checkpoints（key = value格式）
+func parseXtrabackupCheckpoints(content string) *XtrabackupCheckpoints {
+	checkpoints := &XtrabackupCheckpoints{}
+	found := false
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "backup_type":
+			checkpoints.BackupType = value
+		case "from_lsn":
+			checkpoints.FromLSN, _ = strconv.ParseInt(value, 10, 64)
+			found = true
+		case "to_lsn":
+			checkpoints.ToLSN, _ = strconv.ParseInt(value, 10, 64)
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return checkpoints
+}
+
 func (e *XtrabackupExecutor) Type() string {
 	return "xtrabackup"
 }
@@ -76,12 +114,70 @@ func (e *XtrabackupExecutor) Execute(ctx context.Context, params *BackupParams)
 	cmd := fmt.Sprintf("%s --backup --host=%s --port=%d --user=%s --password='%s' --target-dir=%s",
 		xtrabackupPath, params.Host, params.Port, params.Username, params.Password, remoteTmpDir)
 
+	// 增量备份：基于上一个备份的to_lsn，不依赖远程保留基础备份目录
+	if lsn := optionString(params.Options, "incremental_lsn"); lsn != "" {
+		if _, err := strconv.ParseInt(lsn, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid incremental_lsn: %s", lsn)
+		}
+		cmd += fmt.Sprintf(" --incremental-lsn=%s", lsn)
+	}
+
+	// 表级过滤（部分备份）：整库排除用--databases-exclude，其余按表名正则
+	if filter := params.TableFilter; filter != nil {
+		if len(filter.IncludeTables) > 0 {
+			cmd += " --tables=" + shellQuote(tableListRegex(filter.IncludeTables))
+		} else {
+			if len(filter.ExcludeDatabases) > 0 {
+				cmd += " --databases-exclude=" + shellQuote(strings.Join(filter.ExcludeDatabases, " "))
+			}
+			var tables []string
+			for _, table := range filter.ExcludeTables {
+				dbName, _, _ := strings.Cut(table, ".")
+				if !containsString(filter.ExcludeDatabases, dbName) {
+					tables = append(tables, table)
+				}
+			}
+			if len(tables) > 0 {
+				cmd += " --tables-exclude=" + shellQuote(tableListRegex(tables))
+			}
+		}
+	}
+
 	// 执行备份命令
-	if err := e.executeSSHCommand(client, cmd); err != nil {
+	if err := e.runBackupCommand(ctx, client, cmd, remoteTmpDir, params); err != nil {
 		e.executeSSHCommand(client, fmt.Sprintf("rm -rf %s", remoteTmpDir))
 		return nil, fmt.Errorf("xtrabackup backup failed: %w", err)
 	}
 
+	// 读取备份一致性点的binlog坐标
+	var coords *BinlogCoordinates
+	if binlogInfo, err := e.executeSSHCommandOutput(client, fmt.Sprintf("cat %s/xtrabackup_binlog_info", remoteTmpDir)); err == nil {
+		coords = parseXtrabackupBinlogInfo(binlogInfo)
+	}
+
+	// 读取LSN信息，作为后续增量备份的起点
+	var checkpoints *XtrabackupCheckpoints
+	if content, err := e.executeSSHCommandOutput(client, fmt.Sprintf("cat %s/xtrabackup_checkpoints", remoteTmpDir)); err == nil {
+		checkpoints = parseXtrabackupCheckpoints(content)
+	}
+
+	// 记录xtrabackup版本，输出中可能先有警告信息，取版本所在行
+	var toolVersion string
+	if output, err := e.executeSSHCommandOutput(client, fmt.Sprintf("%s --version", xtrabackupPath)); err == nil {
+		if idx := strings.Index(output, "version"); idx >= 0 {
+			lineStart := strings.LastIndex(output[:idx], "\n") + 1
+			toolVersion = firstLine(output[lineStart:])
+		}
+	}
+
+	// 记录压缩前大小
+	var rawSize int64
+	if output, err := e.executeSSHCommandOutput(client, fmt.Sprintf("du -sb %s", remoteTmpDir)); err == nil {
+		if fields := strings.Fields(output); len(fields) > 0 {
+			rawSize, _ = strconv.ParseInt(fields[0], 10, 64)
+		}
+	}
+
 	// 根据压缩类型打包备份文件
 	var backupFile string
 	var tarCmd string
@@ -94,20 +190,26 @@ func (e *XtrabackupExecutor) Execute(ctx context.Context, params *BackupParams)
 		// ZIP压缩
 		backupFile = fmt.Sprintf("%s.zip", remoteTmpDir)
 		tarCmd = fmt.Sprintf("cd %s && zip -r %s .", remoteTmpDir, backupFile)
+	case "zstd", "lz4", "xz":
+		// 通过管道压缩（需要远程服务器安装对应的压缩工具）
+		backupFile = remoteTmpDir + ".tar" + CompressionExtension(params.CompressionType)
+		tarCmd = remoteCompressCommand(params.CompressionType, remoteTmpDir, backupFile, params.CompressionLevel, params.CompressionThreads)
 	default: // gzip
 		// GZIP压缩（tar.gz）
 		backupFile = fmt.Sprintf("%s.tar.gz", remoteTmpDir)
 		tarCmd = fmt.Sprintf("tar -czf %s -C %s .", backupFile, remoteTmpDir)
 	}
 
-	if err := e.executeSSHCommand(client, tarCmd); err != nil {
-		e.executeSSHCommand(client, fmt.Sprintf("rm -rf %s", remoteTmpDir))
+	params.reportProgress(BackupProgress{Phase: "packaging", BytesDone: rawSize})
+	if err := e.executeSSHCommandContext(ctx, client, tarCmd, remoteTmpDir); err != nil {
+		e.executeSSHCommand(client, fmt.Sprintf("rm -rf %s %s", remoteTmpDir, backupFile))
 		return nil, fmt.Errorf("failed to compress backup: %w", err)
 	}
 
 	// 下载备份文件到本地
+	params.reportProgress(BackupProgress{Phase: "downloading", BytesDone: rawSize})
 	localFile := filepath.Join(outputDir, filepath.Base(backupFile))
-	if err := e.downloadFile(client, backupFile, localFile); err != nil {
+	if err := e.downloadFile(ctx, client, backupFile, localFile); err != nil {
 		e.executeSSHCommand(client, fmt.Sprintf("rm -rf %s %s", remoteTmpDir, backupFile))
 		return nil, fmt.Errorf("failed to download backup: %w", err)
 	}
@@ -122,10 +224,14 @@ func (e *XtrabackupExecutor) Execute(ctx context.Context, params *BackupParams)
 	}
 
 	return &BackupResult{
-		FilePath:  localFile,
-		FileSize:  fileInfo.Size(),
-		Duration:  time.Since(startTime),
-		Databases: params.Databases,
+		ToolVersion: toolVersion,
+		FilePath:    localFile,
+		FileSize:    fileInfo.Size(),
+		RawSize:     rawSize,
+		Duration:    time.Since(startTime),
+		Databases:   params.Databases,
+		Coordinates: coords,
+		Checkpoints: checkpoints,
 	}, nil
 }
 
@@ -172,6 +278,136 @@ func (e *XtrabackupExecutor) connectSSH(config *SSHConfig) (*ssh.Client, error)
 	return client, nil
 }
 
+// xtrabackupCopyingPattern 匹配xtrabackup开始复制文件的日志行
+var xtrabackupCopyingPattern = regexp.MustCompile(`Copying (\S+) to `)
+
+// xtrabackupLSNPattern 匹配xtrabackup的redo日志扫描进度
+var xtrabackupLSNPattern = regexp.MustCompile(`log scanned up to \((\d+)\)`)
+
+// xtrabackupProgress 逐行解析xtrabackup的日志，记录正在复制的文件和扫描到的LSN
+type xtrabackupProgress struct {
+	logLineWriter
+	file   string
+	copied int
+	lsn    int64
+}
+
+func (p *xtrabackupProgress) parseLine(line string) {
+	if match := xtrabackupCopyingPattern.FindStringSubmatch(line); match != nil {
+		p.file = match[1]
+	}
+	if strings.HasSuffix(strings.TrimSpace(line), "...done") {
+		p.copied++
+	}
+	if match := xtrabackupLSNPattern.FindStringSubmatch(line); match != nil {
+		p.lsn, _ = strconv.ParseInt(match[1], 10, 64)
+	}
+}
+
+// runBackupCommand 执行xtrabackup备份命令，解析日志并定期统计远程目标目录大小上报进度；
+// ctx取消时结束远程的xtrabackup进程
+func (e *XtrabackupExecutor) runBackupCommand(ctx context.Context, client *ssh.Client, command, targetDir string, params *BackupParams) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	progress := &xtrabackupProgress{}
+	progress.onLine = progress.parseLine
+	session.Stderr = progress
+
+	stop := make(chan struct{})
+	if params.Progress != nil {
+		go func() {
+			ticker := time.NewTicker(5 * time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+
+				var copiedBytes int64
+				if output, err := e.executeSSHCommandOutput(client, fmt.Sprintf("du -sb %s", targetDir)); err == nil {
+					if fields := strings.Fields(output); len(fields) > 0 {
+						copiedBytes, _ = strconv.ParseInt(fields[0], 10, 64)
+					}
+				}
+
+				progress.mu.Lock()
+				report := BackupProgress{
+					Phase:     "dumping",
+					BytesDone: copiedBytes,
+					Table:     progress.file,
+					LSN:       progress.lsn,
+					Message:   fmt.Sprintf("copied %d files", progress.copied),
+				}
+				progress.mu.Unlock()
+				params.reportProgress(report)
+			}
+		}()
+	}
+
+	err = e.runSessionContext(ctx, client, session, command, targetDir)
+	close(stop)
+	if err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("command failed: %v, stderr: %s", err, progress.tail())
+	}
+	return nil
+}
+
+// runSessionContext 在session中执行命令，ctx取消时关闭会话，并结束命令行中包含killPattern的远程进程
+// （关闭SSH会话不一定能结束远程进程）。返回前等待清理完成，ctx取消时返回ctx.Err()
+func (e *XtrabackupExecutor) runSessionContext(ctx context.Context, client *ssh.Client, session *ssh.Session, command, killPattern string) error {
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		select {
+		case <-stop:
+		case <-ctx.Done():
+			session.Signal(ssh.SIGKILL)
+			session.Close()
+			if killPattern != "" {
+				e.executeSSHCommand(client, "pkill -KILL -f -- "+shellQuote(killPattern))
+			}
+		}
+	}()
+
+	err := session.Run(command)
+	close(stop)
+	<-finished
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// executeSSHCommandContext 执行SSH命令，ctx取消时结束命令行中包含killPattern的远程进程
+func (e *XtrabackupExecutor) executeSSHCommandContext(ctx context.Context, client *ssh.Client, command, killPattern string) error {
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+
+	if err := e.runSessionContext(ctx, client, session, command, killPattern); err != nil {
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("command failed: %v, stderr: %s", err, stderr.String())
+	}
+	return nil
+}
+
 // executeSSHCommand 执行SSH命令
 func (e *XtrabackupExecutor) executeSSHCommand(client *ssh.Client, command string) error {
 	session, err := client.NewSession()
@@ -190,23 +426,111 @@ func (e *XtrabackupExecutor) executeSSHCommand(client *ssh.Client, command strin
 	return nil
 }
 
-// downloadFile 通过SCP下载文件
-func (e *XtrabackupExecutor) downloadFile(client *ssh.Client, remotePath, localPath string) error {
+// executeSSHCommandOutput 执行SSH命令并返回标准输出和标准错误的合并内容
+func (e *XtrabackupExecutor) executeSSHCommandOutput(client *ssh.Client, command string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	output, err := session.CombinedOutput(command)
+	if err != nil {
+		return string(output), fmt.Errorf("command failed: %v, output: %s", err, string(output))
+	}
+
+	return string(output), nil
+}
+
+// executeSSHCommandOutputContext 执行SSH命令并返回标准输出和标准错误的合并内容，ctx取消时结束命令行中包含killPattern的远程进程
+func (e *XtrabackupExecutor) executeSSHCommandOutputContext(ctx context.Context, client *ssh.Client, command, killPattern string) (string, error) {
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	// 标准输出和标准错误由不同的goroutine写入
+	var output lockedBuffer
+	session.Stdout = &output
+	session.Stderr = &output
+
+	if err := e.runSessionContext(ctx, client, session, command, killPattern); err != nil {
+		if ctx.Err() != nil {
+			return output.String(), err
+		}
+		return output.String(), fmt.Errorf("command failed: %v, output: %s", err, output.String())
+	}
+	return output.String(), nil
+}
+
+// lockedBuffer 可并发写入的缓冲区
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// uploadFile 通过SSH上传文件
+func (e *XtrabackupExecutor) uploadFile(client *ssh.Client, localPath, remotePath string) error {
+	localFile, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file: %w", err)
+	}
+	defer localFile.Close()
+
 	session, err := client.NewSession()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
 	defer session.Close()
 
-	// 使用cat命令读取远程文件
-	output, err := session.Output(fmt.Sprintf("cat %s", remotePath))
+	var stderr bytes.Buffer
+	session.Stdin = localFile
+	session.Stderr = &stderr
+
+	// 使用cat命令写入远程文件
+	if err := session.Run(fmt.Sprintf("cat > %s", remotePath)); err != nil {
+		return fmt.Errorf("failed to write remote file: %v, stderr: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// downloadFile 通过SSH下载文件，ctx取消时中断下载并删除不完整的本地文件
+func (e *XtrabackupExecutor) downloadFile(ctx context.Context, client *ssh.Client, remotePath, localPath string) error {
+	session, err := client.NewSession()
 	if err != nil {
-		return fmt.Errorf("failed to read remote file: %w", err)
+		return fmt.Errorf("failed to create session: %w", err)
 	}
+	defer session.Close()
 
-	// 写入本地文件
-	if err := os.WriteFile(localPath, output, 0644); err != nil {
-		return fmt.Errorf("failed to write local file: %w", err)
+	localFile, err := os.Create(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to create local file: %w", err)
+	}
+	defer localFile.Close()
+
+	// 使用cat命令读取远程文件，直接写入本地文件
+	session.Stdout = localFile
+	if err := e.runSessionContext(ctx, client, session, fmt.Sprintf("cat %s", remotePath), ""); err != nil {
+		localFile.Close()
+		os.Remove(localPath)
+		if ctx.Err() != nil {
+			return err
+		}
+		return fmt.Errorf("failed to read remote file: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,280 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// XtrabackupRestoreExecutor xtrabackup备份恢复执行器（通过SSH在目标服务器执行prepare和copy-back）
+type XtrabackupRestoreExecutor struct {
+	remote XtrabackupExecutor // 复用SSH连接和命令执行
+}
+
+func (e *XtrabackupRestoreExecutor) Type() string {
+	return "xtrabackup"
+}
+
+func (e *XtrabackupRestoreExecutor) Validate(params *RestoreParams) error {
+	if params.FilePath == "" {
+		return fmt.Errorf("file path is required")
+	}
+	if params.SSHConfig == nil {
+		return fmt.Errorf("SSH config is required for xtrabackup restore")
+	}
+	if params.SSHConfig.Host == "" {
+		return fmt.Errorf("SSH host is required")
+	}
+	if params.SSHConfig.Username == "" {
+		return fmt.Errorf("SSH username is required")
+	}
+	if method := optionString(params.Options, "restore_method"); method != "" && method != "copy-back" && method != "move-back" {
+		return fmt.Errorf("unsupported restore method: %s", method)
+	}
+	return nil
+}
+
+func (e *XtrabackupRestoreExecutor) Restore(ctx context.Context, params *RestoreParams) (*RestoreResult, error) {
+	startTime := time.Now()
+
+	if err := e.Validate(params); err != nil {
+		return nil, err
+	}
+
+	// 恢复选项，由服务层从任务的备份选项中填入
+	datadir := optionString(params.Options, "datadir")
+	if datadir == "" {
+		datadir = "/var/lib/mysql"
+	}
+	method := optionString(params.Options, "restore_method")
+	if method == "" {
+		method = "copy-back"
+	}
+	owner := optionString(params.Options, "owner")
+	if owner == "" {
+		owner = "mysql:mysql"
+	}
+	stopCommand := optionString(params.Options, "stop_command")
+	startCommand := optionString(params.Options, "start_command")
+
+	xtrabackupPath := params.SSHConfig.XtrabackupPath
+	if xtrabackupPath == "" {
+		xtrabackupPath = "xtrabackup" // 默认使用PATH中的xtrabackup
+	}
+
+	// 建立SSH连接
+	params.logStep("connecting to %s@%s", params.SSHConfig.Username, params.SSHConfig.Host)
+	client, err := e.remote.connectSSH(params.SSHConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect SSH: %w", err)
+	}
+	defer client.Close()
+
+	// 在远程服务器创建临时目录，目录名同时用于取消时结束远程进程
+	timestamp := time.Now().Format("20060102_150405")
+	remoteTmpDir := fmt.Sprintf("/tmp/xtrabackup_restore_%s", timestamp)
+	remoteDataDir := remoteTmpDir + "/data"
+	if err := e.run(ctx, params, client, remoteTmpDir, "create remote directory", fmt.Sprintf("mkdir -p %s", remoteDataDir)); err != nil {
+		return nil, err
+	}
+	defer e.remote.executeSSHCommand(client, fmt.Sprintf("rm -rf %s", remoteTmpDir))
+
+	// 上传并解压全量备份
+	params.reportProgress(5, "uploading backup")
+	if err := e.uploadAndExtract(ctx, params, client, params.FilePath, remoteTmpDir, remoteDataDir); err != nil {
+		return nil, err
+	}
+
+	// 上传并解压增量/差异备份
+	var incrementalDirs []string
+	for i, file := range params.IncrementalFiles {
+		params.reportProgress(30, fmt.Sprintf("uploading incremental backup %d/%d", i+1, len(params.IncrementalFiles)))
+		incrementalDir := fmt.Sprintf("%s/inc_%d", remoteTmpDir, i+1)
+		if err := e.run(ctx, params, client, remoteTmpDir, "create incremental directory", fmt.Sprintf("mkdir -p %s", incrementalDir)); err != nil {
+			return nil, err
+		}
+		if err := e.uploadAndExtract(ctx, params, client, file, remoteTmpDir, incrementalDir); err != nil {
+			return nil, err
+		}
+		incrementalDirs = append(incrementalDirs, incrementalDir)
+	}
+
+	// prepare：有增量时全量和中间增量使用--apply-log-only，最后一个增量完成回滚
+	params.reportProgress(45, "preparing backup")
+	prepareCmd := fmt.Sprintf("%s --prepare --target-dir=%s", xtrabackupPath, remoteDataDir)
+	if len(incrementalDirs) > 0 {
+		prepareCmd = fmt.Sprintf("%s --prepare --apply-log-only --target-dir=%s", xtrabackupPath, remoteDataDir)
+	}
+	if err := e.run(ctx, params, client, remoteTmpDir, "prepare", prepareCmd); err != nil {
+		return nil, err
+	}
+	for i, incrementalDir := range incrementalDirs {
+		applyLogOnly := " --apply-log-only"
+		if i == len(incrementalDirs)-1 {
+			applyLogOnly = ""
+		}
+		params.reportProgress(45+15*(i+1)/len(incrementalDirs), fmt.Sprintf("applying incremental backup %d/%d", i+1, len(incrementalDirs)))
+		incrementalCmd := fmt.Sprintf("%s --prepare%s --target-dir=%s --incremental-dir=%s", xtrabackupPath, applyLogOnly, remoteDataDir, incrementalDir)
+		if err := e.run(ctx, params, client, remoteTmpDir, fmt.Sprintf("apply incremental %d", i+1), incrementalCmd); err != nil {
+			return nil, err
+		}
+	}
+
+	// 停止mysqld
+	if stopCommand != "" {
+		params.reportProgress(60, "stopping mysqld")
+		if err := e.run(ctx, params, client, "", "stop mysqld", stopCommand); err != nil {
+			return nil, err
+		}
+	}
+
+	// 从这里开始失败时回滚：还原原datadir并启动mysqld
+	backupDataDir := fmt.Sprintf("%s_bak_%s", datadir, timestamp)
+	restoreCmd, moved, err := e.replaceDatadir(ctx, params, client, xtrabackupPath, remoteTmpDir, remoteDataDir, datadir, backupDataDir, method, owner, stopCommand)
+	if err != nil {
+		e.rollback(params, client, moved, datadir, backupDataDir, stopCommand, startCommand)
+		return nil, err
+	}
+
+	// 启动mysqld
+	if startCommand != "" {
+		params.reportProgress(95, "starting mysqld")
+		if err := e.run(ctx, params, client, "", "start mysqld", startCommand); err != nil {
+			return nil, err
+		}
+	}
+
+	params.reportProgress(100, "restore finished")
+
+	return &RestoreResult{
+		Duration: time.Since(startTime),
+		Command:  restoreCmd,
+	}, nil
+}
+
+// replaceDatadir 移走原datadir，然后copy-back/move-back并修正属主，返回恢复命令以及原datadir是否已被移走
+func (e *XtrabackupRestoreExecutor) replaceDatadir(ctx context.Context, params *RestoreParams, client *ssh.Client, xtrabackupPath, remoteTmpDir, remoteDataDir, datadir, backupDataDir, method, owner, stopCommand string) (string, bool, error) {
+	// 没有执行停止命令时，datadir非空且mysqld仍在运行（pid文件对应的进程存在或有mysqld进程）则不能移走datadir
+	if stopCommand == "" {
+		checkCmd := fmt.Sprintf("if [ -d %[1]s ] && [ -n \"$(ls -A %[1]s)\" ]; then "+
+			"for f in %[1]s/*.pid; do if [ -f \"$f\" ] && [ -d \"/proc/$(cat \"$f\")\" ]; then exit 1; fi; done; "+
+			"if pgrep -x mysqld >/dev/null 2>&1 || pgrep -x mariadbd >/dev/null 2>&1; then exit 1; fi; fi",
+			shellQuote(datadir))
+		if err := e.run(ctx, params, client, "", "check mysqld stopped", checkCmd); err != nil {
+			return "", false, fmt.Errorf("datadir %s is not empty and mysqld appears to be running, configure restore.stop_command or stop mysqld first", datadir)
+		}
+	}
+
+	// datadir非空时先移走，copy-back要求目标目录为空
+	params.reportProgress(65, "preparing datadir")
+	moveAsideCmd := fmt.Sprintf("if [ -d %[1]s ] && [ -n \"$(ls -A %[1]s)\" ]; then mv %[1]s %[2]s || exit 1; fi; mkdir -p %[1]s",
+		shellQuote(datadir), shellQuote(backupDataDir))
+	if err := e.run(ctx, params, client, "", "move existing datadir", moveAsideCmd); err != nil {
+		return "", false, err
+	}
+
+	// copy-back / move-back
+	params.reportProgress(70, method)
+	restoreCmd := fmt.Sprintf("%s --%s --target-dir=%s --datadir=%s", xtrabackupPath, method, remoteDataDir, shellQuote(datadir))
+	if err := e.run(ctx, params, client, remoteTmpDir, method, restoreCmd); err != nil {
+		return "", true, err
+	}
+
+	// 修正属主
+	params.reportProgress(90, "fixing ownership")
+	if err := e.run(ctx, params, client, "", "fix ownership", fmt.Sprintf("chown -R %s %s", shellQuote(owner), shellQuote(datadir))); err != nil {
+		return "", true, err
+	}
+	return restoreCmd, true, nil
+}
+
+// rollback 恢复失败后还原原datadir（原datadir为空时清空恢复了一半的目录）并启动mysqld；
+// moved为false时原datadir未被移动，只需启动mysqld。恢复被取消时ctx已失效，回滚使用独立的上下文
+func (e *XtrabackupRestoreExecutor) rollback(params *RestoreParams, client *ssh.Client, moved bool, datadir, backupDataDir, stopCommand, startCommand string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	if moved {
+		params.reportProgress(95, "rolling back datadir")
+		rollbackCmd := fmt.Sprintf("if [ -d %[2]s ]; then rm -rf %[1]s && mv %[2]s %[1]s; else find %[1]s -mindepth 1 -delete; fi",
+			shellQuote(datadir), shellQuote(backupDataDir))
+		if err := e.run(ctx, params, client, "", "roll back datadir", rollbackCmd); err != nil {
+			// 原datadir未能还原时不启动mysqld，避免在不完整的datadir上启动
+			params.logStep("datadir was not rolled back, mysqld is left stopped; original datadir is at %s", backupDataDir)
+			return
+		}
+	}
+	if stopCommand != "" && startCommand != "" {
+		e.run(ctx, params, client, "", "start mysqld", startCommand)
+	}
+}
+
+// uploadAndExtract 上传备份文件并解压到远程目录
+func (e *XtrabackupRestoreExecutor) uploadAndExtract(ctx context.Context, params *RestoreParams, client *ssh.Client, localPath, remoteTmpDir, destDir string) error {
+	remoteArchive := filepath.ToSlash(filepath.Join(remoteTmpDir, filepath.Base(localPath)))
+	params.logStep("uploading %s to %s", filepath.Base(localPath), remoteArchive)
+	if err := e.remote.uploadFile(client, localPath, remoteArchive); err != nil {
+		return fmt.Errorf("failed to upload backup: %w", err)
+	}
+
+	extractCmd, err := remoteExtractCommand(remoteArchive, destDir)
+	if err != nil {
+		return err
+	}
+	if err := e.run(ctx, params, client, remoteTmpDir, "extract backup", extractCmd); err != nil {
+		return err
+	}
+	e.remote.executeSSHCommand(client, fmt.Sprintf("rm -f %s", remoteArchive))
+	return nil
+}
+
+// run 执行远程命令并记录步骤日志，ctx取消时结束命令行中包含killPattern的远程进程
+func (e *XtrabackupRestoreExecutor) run(ctx context.Context, params *RestoreParams, client *ssh.Client, killPattern, step, command string) error {
+	params.logStep("%s: %s", step, command)
+	output, err := e.remote.executeSSHCommandOutputContext(ctx, client, command, killPattern)
+	if output = strings.TrimSpace(output); output != "" {
+		params.logStep("%s output: %s", step, lastLines(output, 20))
+	}
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", step, err)
+	}
+	return nil
+}
+
+// remoteExtractCommand 根据归档格式生成远程解压命令
+func remoteExtractCommand(archive, destDir string) (string, error) {
+	switch {
+	case strings.HasSuffix(archive, ".tar.gz"):
+		return fmt.Sprintf("tar -xzf %s -C %s", archive, destDir), nil
+	case strings.HasSuffix(archive, ".tar.zst"):
+		return fmt.Sprintf("zstd -dc %s | tar -xf - -C %s", archive, destDir), nil
+	case strings.HasSuffix(archive, ".tar.lz4"):
+		return fmt.Sprintf("lz4 -dc %s | tar -xf - -C %s", archive, destDir), nil
+	case strings.HasSuffix(archive, ".tar.xz"):
+		return fmt.Sprintf("xz -dc %s | tar -xf - -C %s", archive, destDir), nil
+	case strings.HasSuffix(archive, ".tar"):
+		return fmt.Sprintf("tar -xf %s -C %s", archive, destDir), nil
+	case strings.HasSuffix(archive, ".zip"):
+		return fmt.Sprintf("unzip -q -o %s -d %s", archive, destDir), nil
+	default:
+		return "", fmt.Errorf("unsupported archive format: %s", archive)
+	}
+}
+
+// shellQuote 使用单引号转义shell参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// lastLines 返回文本的最后n行
+func lastLines(s string, n int) string {
+	lines := strings.Split(s, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
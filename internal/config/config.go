@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"strconv"
 )
 
 type Config struct {
@@ -20,7 +21,11 @@ type DatabaseConfig struct {
 }
 
 type BackupConfig struct {
-	BasePath string
+	BasePath      string
+	ScrubCron     string // 备份完整性巡检的cron表达式，为空表示不巡检
+	ScrubBatch    int    // 每次巡检最多检查的备份数，0表示全部
+	QuotaCron     string // 存储容量检查的cron表达式，为空表示只在备份后检查
+	ReconcileCron string // 存储对账的cron表达式，为空表示只手动对账
 }
 
 // LoadConfig 加载配置
@@ -34,11 +39,24 @@ func LoadConfig() *Config {
 			Path: getEnv("DB_PATH", "./data/mbmanager.db"),
 		},
 		Backup: BackupConfig{
-			BasePath: getEnv("BACKUP_PATH", "./data/backups"),
+			BasePath:      getEnv("BACKUP_PATH", "./data/backups"),
+			ScrubCron:     getEnv("SCRUB_CRON", "0 4 * * *"),
+			ScrubBatch:    getEnvInt("SCRUB_BATCH", 20),
+			QuotaCron:     getEnv("QUOTA_CRON", "*/30 * * * *"),
+			ReconcileCron: getEnv("RECONCILE_CRON", "0 5 * * 0"),
 		},
 	}
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -32,6 +32,13 @@ func InitDB(dbPath string) error {
 		&model.Notification{},
 		&model.Task{},
 		&model.BackupLog{},
+		&model.BackupCopy{},
+		&model.MigrationJob{},
+		&model.ReconcileJob{},
+		&model.ReconcileFinding{},
+		&model.RestoreLog{},
+		&model.BinlogFile{},
+		&model.EncryptionKey{},
 		&model.User{},
 	)
 	if err != nil {
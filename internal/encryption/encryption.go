@@ -0,0 +1,369 @@
+// Package encryption 备份文件客户端加密（AES-256-GCM分块流式加密）
+//
+// 文件格式：
+//
+//	magic(8) | keyID长度(1) | keyID | nonce前缀(7) | 块大小(4) | 密文块...
+//
+// 每块明文独立使用GCM加密，nonce为 前缀(7) + 块序号(4) + 末块标记(1)，
+// 文件头作为附加数据参与认证，可以发现截断、重排和篡改。
+package encryption
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	// KeySize AES-256密钥长度
+	KeySize = 32
+	// Extension 加密文件扩展名
+	Extension = ".enc"
+
+	magic           = "MBMENC01"
+	noncePrefixSize = 7
+	chunkSize       = 64 * 1024
+	maxChunkSize    = 16 * 1024 * 1024
+)
+
+// ErrKeyMismatch 文件不是用给定密钥加密的
+var ErrKeyMismatch = errors.New("encryption key does not match")
+
+// GenerateKey 生成随机密钥
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return key, nil
+}
+
+// ParseKey 解析base64或hex编码的密钥
+func ParseKey(encoded string) ([]byte, error) {
+	encoded = strings.TrimSpace(encoded)
+	if key, err := base64.StdEncoding.DecodeString(encoded); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	if key, err := hex.DecodeString(encoded); err == nil && len(key) == KeySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("key must be %d bytes encoded in base64 or hex", KeySize)
+}
+
+// EncodeKey 将密钥编码为base64
+func EncodeKey(key []byte) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// KeyID 根据密钥计算密钥标识（SHA-256前8字节），不泄露密钥本身
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(append([]byte("mbmanager-key-id:"), key...))
+	return hex.EncodeToString(sum[:8])
+}
+
+// IsEncrypted 根据文件名判断是否为加密文件
+func IsEncrypted(name string) bool {
+	return strings.HasSuffix(name, Extension)
+}
+
+// EncryptedSize 根据明文大小和密钥标识计算加密后的文件大小
+func EncryptedSize(plainSize int64, keyID string) int64 {
+	chunks := (plainSize + chunkSize - 1) / chunkSize
+	if chunks == 0 {
+		chunks = 1
+	}
+	headerSize := int64(len(magic) + 1 + len(keyID) + noncePrefixSize + 4)
+	return headerSize + plainSize + chunks*16
+}
+
+// newGCM 创建AES-GCM
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("invalid key size %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce 生成第counter块的nonce
+func chunkNonce(prefix []byte, counter uint32, last bool) []byte {
+	nonce := make([]byte, 12)
+	copy(nonce, prefix)
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
+
+// writer 加密Writer
+type writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	prefix  []byte
+	buf     []byte
+	counter uint32
+	closed  bool
+}
+
+// NewWriter 创建加密Writer，写入的数据加密后写到w，必须调用Close写出最后一块
+func NewWriter(w io.Writer, key []byte) (io.WriteCloser, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	keyID := KeyID(key)
+	var header bytes.Buffer
+	header.WriteString(magic)
+	header.WriteByte(byte(len(keyID)))
+	header.WriteString(keyID)
+	header.Write(prefix)
+	binary.Write(&header, binary.BigEndian, uint32(chunkSize))
+
+	if _, err := w.Write(header.Bytes()); err != nil {
+		return nil, err
+	}
+
+	return &writer{
+		w:      w,
+		aead:   aead,
+		header: header.Bytes(),
+		prefix: prefix,
+		buf:    make([]byte, 0, chunkSize),
+	}, nil
+}
+
+func (e *writer) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encryption writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		// 缓冲区满且还有数据时才写出，保证最后一块在Close时带末块标记
+		if len(e.buf) == chunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close 写出最后一块
+func (e *writer) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+func (e *writer) seal(last bool) error {
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.counter, last), e.buf, e.header)
+	e.counter++
+	e.buf = e.buf[:0]
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// Header 加密文件头
+type Header struct {
+	KeyID     string
+	prefix    []byte
+	chunkSize int
+	raw       []byte
+}
+
+// ReadHeader 读取加密文件头
+func ReadHeader(r io.Reader) (*Header, error) {
+	fixed := make([]byte, len(magic)+1)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+	if string(fixed[:len(magic)]) != magic {
+		return nil, fmt.Errorf("not an encrypted backup file")
+	}
+
+	rest := make([]byte, int(fixed[len(magic)])+noncePrefixSize+4)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %w", err)
+	}
+
+	keyIDLen := int(fixed[len(magic)])
+	size := binary.BigEndian.Uint32(rest[keyIDLen+noncePrefixSize:])
+	if size == 0 || size > maxChunkSize {
+		return nil, fmt.Errorf("invalid chunk size %d", size)
+	}
+
+	return &Header{
+		KeyID:     string(rest[:keyIDLen]),
+		prefix:    rest[keyIDLen : keyIDLen+noncePrefixSize],
+		chunkSize: int(size),
+		raw:       append(fixed, rest...),
+	}, nil
+}
+
+// ReadKeyID 读取加密文件的密钥标识
+func ReadKeyID(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	header, err := ReadHeader(file)
+	if err != nil {
+		return "", err
+	}
+	return header.KeyID, nil
+}
+
+// reader 解密Reader
+type reader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	header  *Header
+	sealed  []byte
+	plain   []byte
+	counter uint32
+	done    bool
+}
+
+// NewReader 创建解密Reader，密钥与文件头中的密钥标识不一致时返回ErrKeyMismatch
+func NewReader(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	bufReader := bufio.NewReader(r)
+	header, err := ReadHeader(bufReader)
+	if err != nil {
+		return nil, err
+	}
+	if header.KeyID != KeyID(key) {
+		return nil, fmt.Errorf("%w: file was encrypted with key %s", ErrKeyMismatch, header.KeyID)
+	}
+
+	return &reader{
+		r:      bufReader,
+		aead:   aead,
+		header: header,
+		sealed: make([]byte, header.chunkSize+aead.Overhead()),
+	}, nil
+}
+
+func (d *reader) Read(p []byte) (int, error) {
+	for len(d.plain) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain)
+	d.plain = d.plain[n:]
+	return n, nil
+}
+
+// open 读取并解密下一块
+func (d *reader) open() error {
+	n, err := io.ReadFull(d.r, d.sealed)
+	last := false
+	switch {
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		// 恰好读满一块时，后面没有数据说明是最后一块
+		if _, peekErr := d.r.Peek(1); peekErr == io.EOF {
+			last = true
+		}
+	}
+
+	plain, openErr := d.aead.Open(d.sealed[:0:0], chunkNonce(d.header.prefix, d.counter, last), d.sealed[:n], d.header.raw)
+	if openErr != nil {
+		return fmt.Errorf("failed to decrypt chunk %d: file is corrupted or truncated", d.counter)
+	}
+	d.counter++
+	d.plain = plain
+	d.done = last
+	return nil
+}
+
+// EncryptFile 加密文件
+func EncryptFile(src, dst string, key []byte) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	w, err := NewWriter(dstFile, key)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, srcFile); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// DecryptFile 解密文件
+func DecryptFile(src, dst string, key []byte) error {
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	r, err := NewReader(srcFile, key)
+	if err != nil {
+		return err
+	}
+
+	dstFile, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	if _, err := io.Copy(dstFile, r); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	return nil
+}
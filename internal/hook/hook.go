@@ -0,0 +1,370 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mbmanager/internal/backup"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	PhasePre  = "pre"  // 备份开始前
+	PhasePost = "post" // 备份结束后（无论成功失败）
+
+	defaultTimeout = 60        // 默认超时（秒）
+	maxHookOutput  = 16 * 1024 // 每个钩子保留的最大输出字节数
+)
+
+// Hook 备份前后执行的钩子
+type Hook struct {
+	Name      string            `json:"name"`
+	Phase     string            `json:"phase"`      // pre, post
+	Type      string            `json:"type"`       // sql, shell, ssh, http
+	Command   string            `json:"command"`    // sql: 在主机连接上执行的SQL；shell/ssh: 执行的命令
+	URL       string            `json:"url"`        // http: 请求地址，支持${MBM_*}变量
+	Method    string            `json:"method"`     // http: 请求方法，默认POST
+	Headers   map[string]string `json:"headers"`    // http: 请求头，支持${MBM_*}变量
+	Body      string            `json:"body"`       // http: 请求体，支持${MBM_*}变量
+	SSHConfig *backup.SSHConfig `json:"ssh_config"` // ssh: 连接配置，为空时使用任务的SSH配置
+	Timeout   int               `json:"timeout"`    // 超时（秒），默认60
+	OnFailure string            `json:"on_failure"` // abort（默认）, continue
+}
+
+// Parse 解析任务的钩子配置（JSON数组），为空时返回nil
+func Parse(data string) ([]Hook, error) {
+	if strings.TrimSpace(data) == "" {
+		return nil, nil
+	}
+
+	var hooks []Hook
+	if err := json.Unmarshal([]byte(data), &hooks); err != nil {
+		return nil, fmt.Errorf("invalid hooks: %w", err)
+	}
+	for i := range hooks {
+		if err := hooks[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid hook #%d: %w", i+1, err)
+		}
+	}
+	return hooks, nil
+}
+
+// Validate 检查钩子配置
+func (h *Hook) Validate() error {
+	if h.Phase != PhasePre && h.Phase != PhasePost {
+		return fmt.Errorf("phase must be pre or post")
+	}
+	if h.OnFailure != "" && h.OnFailure != "abort" && h.OnFailure != "continue" {
+		return fmt.Errorf("on_failure must be abort or continue")
+	}
+	if h.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+
+	switch h.Type {
+	case "sql", "shell", "ssh":
+		if strings.TrimSpace(h.Command) == "" {
+			return fmt.Errorf("command is required for %s hook", h.Type)
+		}
+	case "http":
+		if h.URL == "" {
+			return fmt.Errorf("url is required for http hook")
+		}
+	default:
+		return fmt.Errorf("unsupported hook type: %s", h.Type)
+	}
+	return nil
+}
+
+// displayName 返回用于日志的钩子名称
+func (h *Hook) displayName() string {
+	if h.Name != "" {
+		return h.Name
+	}
+	return h.Type
+}
+
+// Target 钩子执行的目标：sql钩子使用MySQL连接，ssh钩子使用SSH配置
+type Target struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	SSHConfig *backup.SSHConfig // 任务的SSH配置，可为nil
+}
+
+// Runner 按阶段执行任务的钩子并汇总输出
+type Runner struct {
+	hooks  []Hook
+	target *Target
+	output strings.Builder
+}
+
+// NewRunner 创建钩子执行器
+func NewRunner(hooks []Hook, target *Target) *Runner {
+	return &Runner{hooks: hooks, target: target}
+}
+
+// Output 返回已执行钩子的汇总输出
+func (r *Runner) Output() string {
+	return r.output.String()
+}
+
+// Run 依次执行指定阶段的钩子，env为传给钩子的MBM_*环境变量。
+// abort策略的钩子失败时停止执行并返回错误，continue策略的失败只记录在输出中
+func (r *Runner) Run(ctx context.Context, phase string, env map[string]string) error {
+	phaseEnv := map[string]string{"MBM_PHASE": phase}
+	for k, v := range env {
+		phaseEnv[k] = v
+	}
+
+	for i := range r.hooks {
+		h := &r.hooks[i]
+		if h.Phase != phase {
+			continue
+		}
+
+		timeout := h.Timeout
+		if timeout == 0 {
+			timeout = defaultTimeout
+		}
+		hookCtx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		start := time.Now()
+		output, err := r.run(hookCtx, h, phaseEnv)
+		cancel()
+
+		status := "ok"
+		if err != nil {
+			status = "failed: " + err.Error()
+		}
+		fmt.Fprintf(&r.output, "[%s] %s (%s) %s in %s\n", phase, h.displayName(), h.Type, status, time.Since(start).Round(time.Millisecond))
+		if output = strings.TrimSpace(output); output != "" {
+			r.output.WriteString(truncateOutput(output) + "\n")
+		}
+
+		if err != nil && h.OnFailure != "continue" {
+			return fmt.Errorf("%s hook %s failed: %w", phase, h.displayName(), err)
+		}
+	}
+	return nil
+}
+
+// run 按类型执行单个钩子，返回输出
+func (r *Runner) run(ctx context.Context, h *Hook, env map[string]string) (string, error) {
+	switch h.Type {
+	case "sql":
+		return r.runSQL(ctx, h)
+	case "shell":
+		return runShell(ctx, h, env)
+	case "ssh":
+		config := h.SSHConfig
+		if config == nil {
+			config = r.target.SSHConfig
+		}
+		if config == nil {
+			return "", fmt.Errorf("ssh hook requires ssh_config on the hook or the task")
+		}
+		return runSSH(ctx, config, h, env)
+	case "http":
+		return runHTTP(ctx, h, env)
+	default:
+		return "", fmt.Errorf("unsupported hook type: %s", h.Type)
+	}
+}
+
+// runSQL 在备份主机上执行SQL
+func (r *Runner) runSQL(ctx context.Context, h *Hook) (string, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/?multiStatements=true",
+		r.target.Username,
+		r.target.Password,
+		r.target.Host,
+		r.target.Port,
+	)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return "", fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer db.Close()
+
+	result, err := db.ExecContext(ctx, h.Command)
+	if err != nil {
+		return "", err
+	}
+	affected, _ := result.RowsAffected()
+	return fmt.Sprintf("rows affected: %d", affected), nil
+}
+
+// runShell 在本地执行shell命令，env追加到当前进程的环境变量中
+func runShell(ctx context.Context, h *Hook, env map[string]string) (string, error) {
+	cmd := exec.CommandContext(ctx, "sh", "-c", h.Command)
+	cmd.WaitDelay = time.Second // 超时后子进程可能仍占用输出管道
+	cmd.Env = os.Environ()
+	for _, key := range sortedKeys(env) {
+		cmd.Env = append(cmd.Env, key+"="+env[key])
+	}
+
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return string(output), fmt.Errorf("timed out")
+	}
+	return string(output), err
+}
+
+// runSSH 通过SSH在远程服务器执行命令，环境变量以export方式传入
+func runSSH(ctx context.Context, config *backup.SSHConfig, h *Hook, env map[string]string) (string, error) {
+	client, err := connectSSH(config)
+	if err != nil {
+		return "", err
+	}
+	defer client.Close()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	var script strings.Builder
+	for _, key := range sortedKeys(env) {
+		fmt.Fprintf(&script, "export %s=%s; ", key, shellQuote(env[key]))
+	}
+	script.WriteString(h.Command)
+
+	var output bytes.Buffer
+	session.Stdout = &output
+	session.Stderr = &output
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(script.String())
+	}()
+
+	select {
+	case err := <-done:
+		return output.String(), err
+	case <-ctx.Done():
+		session.Signal(ssh.SIGKILL)
+		client.Close()
+		<-done
+		return output.String(), fmt.Errorf("timed out")
+	}
+}
+
+// connectSSH 建立SSH连接
+func connectSSH(config *backup.SSHConfig) (*ssh.Client, error) {
+	var authMethods []ssh.AuthMethod
+
+	// 密码认证
+	if config.Password != "" {
+		authMethods = append(authMethods, ssh.Password(config.Password))
+	}
+
+	// 私钥认证
+	if config.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(config.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		authMethods = append(authMethods, ssh.PublicKeys(signer))
+	}
+
+	if len(authMethods) == 0 {
+		return nil, fmt.Errorf("no authentication method provided")
+	}
+
+	sshConfig := &ssh.ClientConfig{
+		User:            config.Username,
+		Auth:            authMethods,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 生产环境应该验证主机密钥
+		Timeout:         30 * time.Second,
+	}
+
+	port := config.Port
+	if port == 0 {
+		port = 22
+	}
+
+	client, err := ssh.Dial("tcp", fmt.Sprintf("%s:%d", config.Host, port), sshConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SSH: %w", err)
+	}
+	return client, nil
+}
+
+// runHTTP 发送HTTP请求，URL、请求头和请求体中的${MBM_*}替换为对应的值，非2xx状态视为失败
+func runHTTP(ctx context.Context, h *Hook, env map[string]string) (string, error) {
+	expand := func(s string) string {
+		return os.Expand(s, func(key string) string {
+			if value, ok := env[key]; ok {
+				return value
+			}
+			return "${" + key + "}"
+		})
+	}
+
+	method := strings.ToUpper(h.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	var body io.Reader
+	if h.Body != "" {
+		body = strings.NewReader(expand(h.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, expand(h.URL), body)
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, value := range h.Headers {
+		req.Header.Set(key, expand(value))
+	}
+	if h.Body != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxHookOutput))
+	output := fmt.Sprintf("HTTP %d %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return output, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return output, nil
+}
+
+// truncateOutput 输出过长时只保留末尾部分
+func truncateOutput(output string) string {
+	if len(output) <= maxHookOutput {
+		return output
+	}
+	return "...(truncated)\n" + output[len(output)-maxHookOutput:]
+}
+
+// shellQuote 使用单引号转义shell参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// sortedKeys 返回按字母排序的键，保证环境变量顺序稳定
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// BackupCopy 备份产物在副本存储中的拷贝
+type BackupCopy struct {
+	ID            uint       `gorm:"primaryKey" json:"id"`
+	BackupLogID   uint       `gorm:"not null;index" json:"backup_log_id"`
+	TaskID        uint       `gorm:"not null;index" json:"task_id"`
+	StorageID     uint       `gorm:"not null;index" json:"storage_id"`
+	StorageType   string     `gorm:"size:20" json:"storage_type"`
+	StorageName   string     `gorm:"size:100" json:"storage_name"`
+	FilePath      string     `gorm:"type:text" json:"file_path"`           // 存储中的路径
+	FileSize      int64      `json:"file_size"`                            // 字节
+	Checksum      string     `gorm:"size:64" json:"checksum"`              // 从副本存储读回后计算的SHA-256
+	Status        string     `gorm:"size:20;not null;index" json:"status"` // success, failed（上传失败或校验和与主存储不一致）, promoted（主存储产物过期后转为主存储）
+	RetentionDays int        `json:"retention_days"`                       // 副本保留天数，0表示与任务相同
+	VerifiedAt    *time.Time `json:"verified_at"`
+	ErrorMessage  string     `gorm:"type:text" json:"error_message"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func (BackupCopy) TableName() string {
+	return "backup_copies"
+}
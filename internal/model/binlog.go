@@ -0,0 +1,29 @@
+package model
+
+import (
+	"time"
+)
+
+// BinlogFile 已归档的binlog文件
+type BinlogFile struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	TaskID         uint       `gorm:"not null;index" json:"task_id"`
+	HostID         uint       `gorm:"not null;index" json:"host_id"`
+	HostName       string     `gorm:"size:100" json:"host_name"`
+	FileName       string     `gorm:"size:255;not null;index" json:"file_name"` // 服务器上的binlog文件名
+	FilePath       string     `gorm:"type:text" json:"file_path"`               // 存储中的路径
+	FileSize       int64      `json:"file_size"`                                // 字节
+	StorageID      uint       `gorm:"index" json:"storage_id"`
+	StorageType    string     `gorm:"size:20" json:"storage_type"`
+	StorageName    string     `gorm:"size:100" json:"storage_name"`
+	FirstEventTime *time.Time `gorm:"index" json:"first_event_time"`
+	LastEventTime  *time.Time `gorm:"index" json:"last_event_time"`
+	PreviousGTIDs  string     `gorm:"type:text" json:"previous_gtids"` // 文件开头的Previous-GTIDs
+	GTIDs          string     `gorm:"type:text" json:"gtids"`          // 文件中包含的GTID范围
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+func (BinlogFile) TableName() string {
+	return "binlog_files"
+}
@@ -0,0 +1,21 @@
+package model
+
+import (
+	"time"
+)
+
+// EncryptionKey 备份加密密钥
+type EncryptionKey struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Name        string    `gorm:"uniqueIndex;size:100;not null" json:"name"`
+	KeyID       string    `gorm:"uniqueIndex;size:32;not null" json:"key_id"` // 密钥标识，由密钥计算得出，记录在备份日志中
+	KeyData     string    `gorm:"type:text;not null" json:"-"`                // base64编码的AES-256密钥，不返回给前端
+	Description string    `gorm:"size:255" json:"description"`
+	Status      int       `gorm:"default:1" json:"status"` // 1:可用于新备份 0:仅用于解密已有备份
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (EncryptionKey) TableName() string {
+	return "encryption_keys"
+}
@@ -6,26 +6,56 @@ import (
 
 // BackupLog 备份日志
 type BackupLog struct {
-	ID           uint       `gorm:"primaryKey" json:"id"`
-	TaskID       uint       `gorm:"not null;index" json:"task_id"`
-	Task         *Task      `gorm:"foreignKey:TaskID" json:"task,omitempty"`
-	TaskName     string     `gorm:"size:100" json:"task_name"`
-	HostName     string     `gorm:"size:100" json:"host_name"`
-	Databases    string     `gorm:"type:text" json:"databases"`
-	BackupType   string     `gorm:"size:20" json:"backup_type"`
-	Status       string     `gorm:"size:20;not null;index" json:"status"` // running, success, failed
-	StartTime    time.Time  `gorm:"not null;index" json:"start_time"`
-	EndTime      *time.Time `json:"end_time"`
-	Duration     int        `json:"duration"`         // 总耗时（秒）
-	BackupTime   int        `json:"backup_time"`      // 备份耗时（秒）
-	TransferTime int        `json:"transfer_time"`    // 传输耗时（秒）
-	FilePath     string     `gorm:"type:text" json:"file_path"`
-	FileSize     int64      `json:"file_size"` // 字节
-	StorageType  string     `gorm:"size:20" json:"storage_type"`
-	StorageName  string     `gorm:"size:100" json:"storage_name"`
-	Command      string     `gorm:"type:text" json:"command"` // 完整的备份命令
-	ErrorMessage string     `gorm:"type:text" json:"error_message"`
-	CreatedAt    time.Time  `json:"created_at"`
+	ID               uint       `gorm:"primaryKey" json:"id"`
+	TaskID           uint       `gorm:"not null;index" json:"task_id"`
+	Task             *Task      `gorm:"foreignKey:TaskID" json:"task,omitempty"`
+	TaskName         string     `gorm:"size:100" json:"task_name"`
+	HostName         string     `gorm:"size:100" json:"host_name"`
+	Databases        string     `gorm:"type:text" json:"databases"`
+	BackupType       string     `gorm:"size:20" json:"backup_type"`
+	RunID            string     `gorm:"size:32;index" json:"run_id"`          // 同一次执行（含重试）的各次尝试共用
+	Attempt          int        `gorm:"default:1" json:"attempt"`             // 第几次尝试，从1开始
+	NextRetryAt      *time.Time `json:"next_retry_at"`                        // 本次尝试失败后等待重试时的下次尝试时间，等待期间可取消
+	Status           string     `gorm:"size:20;not null;index" json:"status"` // running, success, failed, cancelled
+	StartTime        time.Time  `gorm:"not null;index" json:"start_time"`
+	EndTime          *time.Time `json:"end_time"`
+	Duration         int        `json:"duration"`      // 总耗时（秒）
+	BackupTime       int        `json:"backup_time"`   // 备份耗时（秒）
+	TransferTime     int        `json:"transfer_time"` // 传输耗时（秒）
+	FilePath         string     `gorm:"type:text" json:"file_path"`
+	FileSize         int64      `json:"file_size"`                                                 // 字节
+	RawSize          int64      `json:"raw_size"`                                                  // 压缩前大小（字节）
+	CompressionRatio float64    `json:"compression_ratio"`                                         // 压缩比（压缩前/压缩后）
+	EncryptionKeyID  string     `gorm:"size:32;index" json:"encryption_key_id"`                    // 加密密钥标识，为空表示未加密
+	Checksum         string     `gorm:"size:64" json:"checksum"`                                   // 备份文件的SHA-256
+	IntegrityStatus  string     `gorm:"size:20;default:'unchecked';index" json:"integrity_status"` // unchecked, ok, corrupted, missing
+	IntegrityAt      *time.Time `json:"integrity_checked_at"`                                      // 最近一次完整性校验时间
+	StorageID        uint       `gorm:"index" json:"storage_id"`                                   // 备份产物所在的存储，0表示任务的存储（旧备份）
+	StorageType      string     `gorm:"size:20" json:"storage_type"`
+	StorageName      string     `gorm:"size:100" json:"storage_name"`
+	Command          string     `gorm:"type:text" json:"command"`                                // 完整的备份命令
+	BinlogFile       string     `gorm:"size:255" json:"binlog_file"`                             // 备份一致性点的binlog文件
+	BinlogPosition   int64      `json:"binlog_position"`                                         // 备份一致性点的binlog位置
+	GTIDExecuted     string     `gorm:"type:text" json:"gtid_executed"`                          // 备份一致性点已执行的GTID集合
+	BackupSource     string     `gorm:"size:20" json:"backup_source"`                            // primary, replica
+	SourceHost       string     `gorm:"size:255" json:"source_host"`                             // 从库备份时的主库地址
+	SourceBinlogFile string     `gorm:"size:255" json:"source_binlog_file"`                      // 从库备份时对应的主库binlog文件
+	SourceBinlogPos  int64      `json:"source_binlog_position"`                                  // 从库备份时对应的主库binlog位置
+	ServerVersion    string     `gorm:"size:100" json:"server_version"`                          // 备份源MySQL版本
+	ToolVersion      string     `gorm:"size:255" json:"tool_version"`                            // 备份工具版本
+	TableCount       int        `json:"table_count"`                                             // 备份的表数量
+	RowEstimate      int64      `json:"row_estimate"`                                            // 备份的估算总行数
+	BackupMode       string     `gorm:"size:20;default:'full'" json:"backup_mode"`               // full, incremental, differential
+	ParentLogID      uint       `gorm:"index" json:"parent_log_id"`                              // 增量/差异备份依赖的上一个备份，0表示全量
+	FromLSN          int64      `json:"from_lsn"`                                                // xtrabackup起始LSN
+	ToLSN            int64      `json:"to_lsn"`                                                  // xtrabackup结束LSN
+	VerifyStatus     string     `gorm:"size:20;default:'unverified';index" json:"verify_status"` // unverified, verifying, verified, failed
+	VerifyMessage    string     `gorm:"type:text" json:"verify_message"`                         // 验证结果说明
+	VerifiedAt       *time.Time `json:"verified_at"`
+	VerifyBaseline   string     `gorm:"type:text" json:"verify_baseline"` // 备份时采集的表数量和校验和（JSON）
+	HookOutput       string     `gorm:"type:text" json:"hook_output"`     // 备份前后钩子的执行输出
+	ErrorMessage     string     `gorm:"type:text" json:"error_message"`
+	CreatedAt        time.Time  `json:"created_at"`
 }
 
 func (BackupLog) TableName() string {
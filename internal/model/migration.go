@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+)
+
+// MigrationJob 备份迁移任务，将源存储中的备份产物复制或迁移到目标存储
+type MigrationJob struct {
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	SourceStorageID uint       `gorm:"not null;index" json:"source_storage_id"`
+	SourceStorage   string     `gorm:"size:100" json:"source_storage"`
+	DestStorageID   uint       `gorm:"not null;index" json:"dest_storage_id"`
+	DestStorage     string     `gorm:"size:100" json:"dest_storage"`
+	HostName        string     `gorm:"size:100" json:"host_name"` // 筛选条件：备份来源主机
+	TaskID          uint       `json:"task_id"`                   // 筛选条件：任务
+	FromTime        *time.Time `json:"from_time"`                 // 筛选条件：备份开始时间范围
+	ToTime          *time.Time `json:"to_time"`
+	DeleteSource    bool       `json:"delete_source"`                        // 校验通过后删除源存储中的产物，否则源产物作为副本保留
+	Status          string     `gorm:"size:20;not null;index" json:"status"` // running, success, partial（部分失败）, failed
+	Total           int        `json:"total"`                                // 待迁移的产物数
+	Completed       int        `json:"completed"`
+	Failed          int        `json:"failed"`
+	Skipped         int        `json:"skipped"`
+	TotalBytes      int64      `json:"total_bytes"`
+	BytesDone       int64      `json:"bytes_done"`
+	Progress        int        `json:"progress"`                      // 进度（0-100）
+	CurrentFile     string     `gorm:"type:text" json:"current_file"` // 正在迁移的产物
+	Steps           string     `gorm:"type:text" json:"steps"`        // 每个产物的迁移结果
+	CreatedBy       string     `gorm:"size:50" json:"created_by"`
+	ErrorMessage    string     `gorm:"type:text" json:"error_message"`
+	StartTime       time.Time  `gorm:"not null;index" json:"start_time"`
+	EndTime         *time.Time `json:"end_time"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (MigrationJob) TableName() string {
+	return "migration_jobs"
+}
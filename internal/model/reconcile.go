@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+)
+
+// ReconcileJob 存储对账任务，比较存储中的文件与备份目录中的记录
+type ReconcileJob struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	StorageID    uint       `gorm:"not null;index" json:"storage_id"`
+	StorageName  string     `gorm:"size:100" json:"storage_name"`
+	Status       string     `gorm:"size:20;not null;index" json:"status"` // running, success, failed
+	TotalFiles   int        `json:"total_files"`                          // 存储中的文件数
+	TotalEntries int        `json:"total_entries"`                        // 目录中引用该存储的记录数
+	Matched      int        `json:"matched"`
+	Orphans      int        `json:"orphans"`                   // 存储中有但目录中没有的文件
+	Missing      int        `json:"missing"`                   // 目录中有但存储中没有的产物
+	Mismatched   int        `json:"mismatched"`                // 大小与目录记录不一致的产物
+	Skipped      int        `json:"skipped"`                   // 修改时间在宽限期内、可能正在上传的文件
+	CreatedBy    string     `gorm:"size:50" json:"created_by"` // 定时对账时为system
+	Steps        string     `gorm:"type:text" json:"steps"`
+	ErrorMessage string     `gorm:"type:text" json:"error_message"`
+	StartTime    time.Time  `gorm:"not null;index" json:"start_time"`
+	EndTime      *time.Time `json:"end_time"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+func (ReconcileJob) TableName() string {
+	return "reconcile_jobs"
+}
+
+// ReconcileFinding 对账发现的一处不一致
+type ReconcileFinding struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	JobID        uint       `gorm:"not null;index" json:"job_id"`
+	StorageID    uint       `gorm:"not null;index" json:"storage_id"`
+	Kind         string     `gorm:"size:20;not null;index" json:"kind"` // orphan, missing, size_mismatch
+	EntryType    string     `gorm:"size:20" json:"entry_type"`          // 目录记录类型：backup, copy, binlog，孤立文件为空
+	BackupLogID  uint       `gorm:"index" json:"backup_log_id"`
+	BackupCopyID uint       `json:"backup_copy_id"`
+	BinlogFileID uint       `json:"binlog_file_id"`
+	FilePath     string     `gorm:"type:text" json:"file_path"`
+	FileSize     int64      `json:"file_size"`    // 存储中的文件大小，丢失时为0
+	CatalogSize  int64      `json:"catalog_size"` // 目录记录的大小，孤立文件为0
+	ModifiedTime *time.Time `json:"modified_time"`
+	HasManifest  bool       `json:"has_manifest"`                             // 孤立文件旁有元数据清单，可据此恢复目录记录
+	Resolution   string     `gorm:"size:20;not null;index" json:"resolution"` // pending, adopted, marked, deleted, ignored
+	ResolvedBy   string     `gorm:"size:50" json:"resolved_by"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	ErrorMessage string     `gorm:"type:text" json:"error_message"` // 最近一次处理失败的原因
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+func (ReconcileFinding) TableName() string {
+	return "reconcile_findings"
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+)
+
+// RestoreLog 恢复记录
+type RestoreLog struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	BackupLogID    uint       `gorm:"not null;index" json:"backup_log_id"`
+	TaskID         uint       `gorm:"index" json:"task_id"`
+	TaskName       string     `gorm:"size:100" json:"task_name"`
+	SourceHostName string     `gorm:"size:100" json:"source_host_name"` // 备份来源主机
+	HostID         uint       `gorm:"not null;index" json:"host_id"`    // 恢复目标主机
+	HostName       string     `gorm:"size:100" json:"host_name"`
+	TargetDatabase string     `gorm:"size:100" json:"target_database"`
+	BackupType     string     `gorm:"size:20" json:"backup_type"`
+	FilePath       string     `gorm:"type:text" json:"file_path"`
+	RestoreType    string     `gorm:"size:20" json:"restore_type"`          // full, pitr
+	TargetTime     *time.Time `json:"target_time"`                          // 时间点恢复的目标时间
+	TargetGTID     string     `gorm:"size:255" json:"target_gtid"`          // 时间点恢复的目标GTID
+	BinlogFiles    string     `gorm:"type:text" json:"binlog_files"`        // 重放的binlog文件（JSON数组）
+	Status         string     `gorm:"size:20;not null;index" json:"status"` // running, success, failed
+	Progress       int        `json:"progress"`                             // 恢复进度（0-100）
+	CurrentStep    string     `gorm:"size:255" json:"current_step"`         // 当前步骤
+	StartTime      time.Time  `gorm:"not null;index" json:"start_time"`
+	EndTime        *time.Time `json:"end_time"`
+	Duration       int        `json:"duration"`                 // 总耗时（秒）
+	DownloadTime   int        `json:"download_time"`            // 下载耗时（秒）
+	RestoreTime    int        `json:"restore_time"`             // 恢复耗时（秒）
+	ReplayTime     int        `json:"replay_time"`              // binlog重放耗时（秒）
+	Command        string     `gorm:"type:text" json:"command"` // 完整的恢复命令
+	Steps          string     `gorm:"type:text" json:"steps"`   // 步骤日志
+	ErrorMessage   string     `gorm:"type:text" json:"error_message"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (RestoreLog) TableName() string {
+	return "restore_logs"
+}
@@ -6,14 +6,21 @@ import (
 
 // Storage 存储配置
 type Storage struct {
-	ID        uint      `gorm:"primaryKey" json:"id"`
-	Name      string    `gorm:"uniqueIndex;size:100;not null" json:"name"`
-	Type      string    `gorm:"size:20;not null" json:"type"` // local, s3, oss, nas
-	Config    string    `gorm:"type:text;not null" json:"config"` // JSON格式存储配置
-	IsDefault int       `gorm:"default:0" json:"is_default"`
-	Status    int       `gorm:"default:1" json:"status"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID              uint       `gorm:"primaryKey" json:"id"`
+	Name            string     `gorm:"uniqueIndex;size:100;not null" json:"name"`
+	Type            string     `gorm:"size:20;not null" json:"type"` // local, s3, oss, nas
+	Config          string     `gorm:"type:text;not null" json:"config"` // JSON格式存储配置
+	IsDefault       int        `gorm:"default:0" json:"is_default"`
+	Status          int        `gorm:"default:1" json:"status"`
+	QuotaBytes      int64      `gorm:"default:0" json:"quota_bytes"` // 容量配额（字节），存储中的文件总量超过时清理最旧的备份，0表示不限制
+	MinFreePercent  int        `gorm:"default:0" json:"min_free_percent"` // 剩余空间下限（百分比），仅本地、NAS和SSH存储，0表示不限制
+	NotificationIDs string     `gorm:"type:text" json:"notification_ids"` // JSON数组，容量告警的通知渠道，为空时使用以该存储为主存储的任务的通知渠道
+	QuotaStatus     string     `gorm:"size:20" json:"quota_status"` // 最近一次容量检查的结果：ok, pruned（超出后已清理）, exceeded（清理后仍超出）
+	UsedBytes       int64      `json:"used_bytes"` // 最近一次检查时存储中的文件总量
+	FreePercent     float64    `json:"free_percent"` // 最近一次检查时的剩余空间百分比，-1表示不支持
+	QuotaCheckedAt  *time.Time `json:"quota_checked_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 func (Storage) TableName() string {
@@ -11,17 +11,35 @@ type Task struct {
 	HostID           uint       `gorm:"not null;index" json:"host_id"`
 	Host             *Host      `gorm:"foreignKey:HostID" json:"host,omitempty"`
 	Databases        string     `gorm:"type:text" json:"databases"` // JSON数组，要备份的数据库列表
-	BackupType       string     `gorm:"size:20;not null" json:"backup_type"` // mysqldump, mydumper, xtrabackup
-	ScheduleType     string     `gorm:"size:20;not null" json:"schedule_type"` // once, daily, weekly, monthly, cron
+	BackupType       string     `gorm:"size:20;not null" json:"backup_type"` // mysqldump, mydumper, xtrabackup, binlog
+	ScheduleType     string     `gorm:"size:20;not null" json:"schedule_type"` // once, daily, weekly, monthly, cron, binlog（持续归档）
 	ScheduleConfig   string     `gorm:"type:text;not null" json:"schedule_config"` // JSON格式存储调度配置
 	StorageID        uint       `gorm:"not null" json:"storage_id"`
 	Storage          *Storage   `gorm:"foreignKey:StorageID" json:"storage,omitempty"`
+	CopyStorages     string     `gorm:"type:text" json:"copy_storages"` // JSON数组，副本存储及各自的保留天数，如[{"storage_id":2,"retention_days":30}]
 	RetentionDays    int        `gorm:"default:7" json:"retention_days"` // 保留天数
+	KeepDaily        int        `gorm:"default:0" json:"keep_daily"` // GFS保留策略：保留最近N天每天最新的备份
+	KeepWeekly       int        `gorm:"default:0" json:"keep_weekly"` // 保留最近N周每周最新的备份
+	KeepMonthly      int        `gorm:"default:0" json:"keep_monthly"` // 保留最近N个月每月最新的备份
+	KeepYearly       int        `gorm:"default:0" json:"keep_yearly"` // 保留最近N年每年最新的备份
+	MinKeep          int        `gorm:"default:0" json:"min_keep"` // 无论是否过期都保留的最近成功备份数
 	NotificationIDs  string     `gorm:"type:text" json:"notification_ids"` // JSON数组
 	NotifyOnSuccess  int        `gorm:"default:0" json:"notify_on_success"`
 	NotifyOnFailure  int        `gorm:"default:1" json:"notify_on_failure"`
+	IncludeTables    string     `gorm:"type:text" json:"include_tables"` // JSON数组，只备份匹配的表（db.table，支持*?通配符，~开头为正则）
+	ExcludeTables    string     `gorm:"type:text" json:"exclude_tables"` // JSON数组，排除匹配的表，格式同include_tables
 	BackupOptions    string     `gorm:"type:text" json:"backup_options"` // JSON格式存储备份选项
-	CompressionType  string     `gorm:"size:20;default:'gzip'" json:"compression_type"` // none, gzip, zip
+	CompressionType  string     `gorm:"size:20;default:'gzip'" json:"compression_type"` // none, gzip, zip, zstd, lz4, xz
+	CompressLevel    int        `gorm:"default:0" json:"compression_level"` // 压缩级别，0表示默认
+	CompressThreads  int        `gorm:"default:0" json:"compression_threads"` // zstd/lz4压缩线程数，0表示CPU核数
+	EncryptionKeyID  string     `gorm:"size:32" json:"encryption_key_id"` // 加密密钥标识，为空表示不加密
+	VerifyPolicy     string     `gorm:"type:text" json:"verify_policy"` // JSON格式存储恢复验证策略
+	Hooks            string     `gorm:"type:text" json:"hooks"` // JSON数组，备份前后执行的钩子
+	Streaming        int        `gorm:"default:0" json:"streaming"` // 1:流式上传，备份数据不落本地临时文件（仅mysqldump）
+	MaxRuntime       int        `gorm:"default:0" json:"max_runtime"` // 单次执行的最长运行时间（分钟），0表示不限制
+	RetryMaxAttempts int        `gorm:"default:1" json:"retry_max_attempts"` // 最多尝试次数（含首次），1表示不重试
+	RetryBackoff     int        `gorm:"default:60" json:"retry_backoff"` // 首次重试前等待的秒数，之后每次翻倍
+	RetryOn          string     `gorm:"size:100;default:'network,lock,storage'" json:"retry_on"` // 值得重试的错误类别，逗号分隔：network, lock, storage, timeout, all
 	Status           int        `gorm:"default:1;index" json:"status"` // 1:启用 0:禁用
 	LastRunAt        *time.Time `json:"last_run_at"`
 	NextRunAt        *time.Time `gorm:"index" json:"next_run_at"`
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"time"
+)
+
+// incrementalConfig xtrabackup增量备份配置（Task.BackupOptions中的字段）
+type incrementalConfig struct {
+	Mode             string `json:"incremental_mode"`   // full（默认）, incremental, differential
+	FullIntervalDays int    `json:"full_interval_days"` // 全量备份间隔（天），默认7
+}
+
+// parseIncrementalConfig 从任务备份选项解析增量备份配置
+func parseIncrementalConfig(task *model.Task) incrementalConfig {
+	var config incrementalConfig
+	if task.BackupOptions != "" {
+		json.Unmarshal([]byte(task.BackupOptions), &config)
+	}
+	if config.Mode == "" {
+		config.Mode = "full"
+	}
+	if config.FullIntervalDays <= 0 {
+		config.FullIntervalDays = 7
+	}
+	return config
+}
+
+// resolveBackupParent 确定本次备份的模式和依赖的上一个备份，全量备份时parent为nil
+func (s *BackupService) resolveBackupParent(task *model.Task) (string, *model.BackupLog) {
+	if task.BackupType != "xtrabackup" {
+		return "full", nil
+	}
+
+	config := parseIncrementalConfig(task)
+	if config.Mode != "incremental" && config.Mode != "differential" {
+		return "full", nil
+	}
+
+	// 最近一次成功的全量备份，超过全量间隔时重新做全量
+	var lastFull model.BackupLog
+	if err := database.DB.Where("task_id = ? AND status = ? AND backup_mode = ? AND to_lsn > 0", task.ID, "success", "full").
+		Order("start_time DESC").First(&lastFull).Error; err != nil {
+		return "full", nil
+	}
+	if lastFull.StartTime.Before(time.Now().AddDate(0, 0, -config.FullIntervalDays)) {
+		return "full", nil
+	}
+
+	// 差异备份总是基于全量备份
+	if config.Mode == "differential" {
+		return "differential", &lastFull
+	}
+
+	// 增量备份基于当前链上最新的成功备份
+	var latest model.BackupLog
+	if err := database.DB.Where("task_id = ? AND status = ? AND to_lsn > 0 AND start_time >= ?", task.ID, "success", lastFull.StartTime).
+		Order("start_time DESC").First(&latest).Error; err != nil {
+		return "incremental", &lastFull
+	}
+	return "incremental", &latest
+}
+
+// backupChain 返回恢复指定备份所需的备份链（全量备份在前，指定备份在最后）
+func backupChain(backupLog *model.BackupLog) ([]model.BackupLog, error) {
+	chain := []model.BackupLog{*backupLog}
+
+	current := backupLog
+	for current.ParentLogID != 0 {
+		var parent model.BackupLog
+		if err := database.DB.First(&parent, current.ParentLogID).Error; err != nil {
+			return nil, fmt.Errorf("parent backup %d of backup %d not found", current.ParentLogID, current.ID)
+		}
+		if parent.Status != "success" || parent.FilePath == "" {
+			return nil, fmt.Errorf("parent backup %d of backup %d is not available", parent.ID, current.ID)
+		}
+		if len(chain) > 1000 {
+			return nil, fmt.Errorf("backup chain of backup %d is too long", backupLog.ID)
+		}
+		chain = append([]model.BackupLog{parent}, chain...)
+		current = &parent
+	}
+
+	return chain, nil
+}
+
+// BackupChainEncrypted 检查恢复指定备份所需的备份链中是否有加密的备份
+func BackupChainEncrypted(backupLog *model.BackupLog) bool {
+	current := backupLog
+	for i := 0; i <= 1000; i++ {
+		if current.EncryptionKeyID != "" {
+			return true
+		}
+		if current.ParentLogID == 0 {
+			return false
+		}
+		var parent model.BackupLog
+		if err := database.DB.Select("id", "parent_log_id", "encryption_key_id").First(&parent, current.ParentLogID).Error; err != nil {
+			return false
+		}
+		current = &parent
+	}
+	return false
+}
+
+// chainDependencies 返回保留的增量/差异备份仍依赖的所有上游备份ID
+func chainDependencies(keptLogs []model.BackupLog) map[uint]bool {
+	protected := make(map[uint]bool)
+	for _, keptLog := range keptLogs {
+		parentID := keptLog.ParentLogID
+		for parentID != 0 && !protected[parentID] {
+			protected[parentID] = true
+
+			var parent model.BackupLog
+			if err := database.DB.Select("id", "parent_log_id").First(&parent, parentID).Error; err != nil {
+				break
+			}
+			parentID = parent.ParentLogID
+		}
+	}
+	return protected
+}
+
+// HasDependentBackups 检查是否有成功的增量/差异备份依赖该备份
+func HasDependentBackups(backupLogID uint) bool {
+	var count int64
+	database.DB.Model(&model.BackupLog{}).Where("parent_log_id = ? AND status = ?", backupLogID, "success").Count(&count)
+	return count > 0
+}
@@ -4,14 +4,22 @@ import (
 	"context"
 	"mbmanager/internal/backup"
 	"mbmanager/internal/database"
+	"mbmanager/internal/encryption"
+	"mbmanager/internal/hook"
 	"mbmanager/internal/model"
 	"mbmanager/internal/notification"
 	"mbmanager/internal/storage"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -23,15 +31,86 @@ func NewBackupService() *BackupService {
 	return &BackupService{}
 }
 
-// ExecuteBackup 执行备份任务
+// ExecuteBackup 执行备份任务，失败时按任务的重试策略重试，只对最终结果发送通知
 func (s *BackupService) ExecuteBackup(ctx context.Context, task *model.Task) error {
-	log.Printf("Starting backup task: %s (ID: %d)", task.Name, task.ID)
+	runID := newRunID()
+	maxAttempts := task.RetryMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	for attempt := 1; ; attempt++ {
+		backupLog, err := s.executeAttempt(ctx, task, runID, attempt)
+		if err == nil {
+			// 发送成功通知
+			if task.NotifyOnSuccess == 1 {
+				s.sendNotification(task, backupLog)
+			}
+			return nil
+		}
+
+		// 存储错误可能是空间已满，按容量限制清理旧备份，超出时告警
+		class := classifyBackupError(err)
+		if class == retryClassStorage {
+			enforceTaskQuotas(ctx, task)
+		}
+		if attempt >= maxAttempts || backupLog.Status == "cancelled" || !shouldRetry(task, class) {
+			// 发送失败通知（用户主动取消的不通知）
+			if task.NotifyOnFailure == 1 && backupLog.Status == "failed" {
+				s.sendNotification(task, backupLog)
+			}
+			return err
+		}
+
+		delay := retryDelay(task, attempt)
+		log.Printf("Backup task %s attempt %d/%d failed (%s error), retrying in %s: %v",
+			task.Name, attempt, maxAttempts, class, delay, err)
+		backupLog.ErrorMessage += fmt.Sprintf(" (attempt %d/%d, %s error, retrying in %s)", attempt, maxAttempts, class, delay)
+		nextRetryAt := time.Now().Add(delay)
+		database.DB.Model(backupLog).Updates(map[string]interface{}{"error_message": backupLog.ErrorMessage, "next_retry_at": nextRetryAt})
+
+		// 等待重试期间保持登记，取消本次尝试的日志即可终止后续重试
+		if !s.waitRetry(ctx, task, backupLog, delay) {
+			return err
+		}
+	}
+}
+
+// waitRetry 等待重试间隔，期间登记为任务的运行中执行；被取消或ctx结束时返回false
+func (s *BackupService) waitRetry(ctx context.Context, task *model.Task, backupLog *model.BackupLog, delay time.Duration) bool {
+	waitCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	execution := registerExecution(task.ID, backupLog.ID, cancel)
+	defer execution.unregister()
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-waitCtx.Done():
+	}
+
+	updates := map[string]interface{}{"next_retry_at": nil}
+	if execution.isCancelled() {
+		backupLog.ErrorMessage += " (retry cancelled)"
+		updates["error_message"] = backupLog.ErrorMessage
+		log.Printf("Retry of backup task %s cancelled", task.Name)
+	}
+	database.DB.Model(backupLog).Updates(updates)
+	return waitCtx.Err() == nil
+}
+
+// executeAttempt 执行一次备份，runID和attempt标识所属的执行及第几次尝试，返回本次的备份日志
+func (s *BackupService) executeAttempt(ctx context.Context, task *model.Task, runID string, attempt int) (*model.BackupLog, error) {
+	log.Printf("Starting backup task: %s (ID: %d, attempt %d)", task.Name, task.ID, attempt)
 
 	// 创建备份日志
 	backupLog := &model.BackupLog{
 		TaskID:     task.ID,
 		TaskName:   task.Name,
 		BackupType: task.BackupType,
+		RunID:      runID,
+		Attempt:    attempt,
 		Status:     "running",
 		StartTime:  time.Now(),
 	}
@@ -42,9 +121,10 @@ func (s *BackupService) ExecuteBackup(ctx context.Context, task *model.Task) err
 		backupLog.Status = "failed"
 		backupLog.ErrorMessage = fmt.Sprintf("Failed to load host: %v", err)
 		s.saveLog(backupLog)
-		return err
+		return backupLog, err
 	}
 	backupLog.HostName = host.Name
+	backupLog.BackupSource = host.BackupSource
 
 	// 解析数据库列表
 	var databases []string
@@ -55,13 +135,88 @@ func (s *BackupService) ExecuteBackup(ctx context.Context, task *model.Task) err
 	}
 	backupLog.Databases = task.Databases
 
+	// xtrabackup增量/差异备份依赖上一个备份的LSN
+	mode, parent := s.resolveBackupParent(task)
+	backupLog.BackupMode = mode
+	if parent != nil {
+		backupLog.ParentLogID = parent.ID
+	}
+
 	// 保存初始日志
 	if err := database.DB.Create(backupLog).Error; err != nil {
 		log.Printf("Failed to create backup log: %v", err)
 	}
 
-	// 执行备份
-	result, err := s.performBackup(ctx, task, &host, databases)
+	// 跟踪执行进度，登记取消函数（前置钩子和备份执行可被取消，超过最长运行时间时自动取消）
+	progress := startBackupProgress(backupLog)
+	runCtx, cancel := context.WithCancel(ctx)
+	if task.MaxRuntime > 0 {
+		runCtx, cancel = context.WithTimeout(ctx, time.Duration(task.MaxRuntime)*time.Minute)
+	}
+	defer cancel()
+	execution := registerExecution(task.ID, backupLog.ID, cancel)
+	defer execution.unregister()
+
+	// 执行前置钩子，abort策略的钩子失败时不再备份
+	hooks, err := newHookRunner(task, &host)
+	if err == nil && hooks != nil {
+		err = hooks.Run(runCtx, hook.PhasePre, hookEnv(task, &host, backupLog, nil, nil))
+	}
+
+	// 从库模式下检查复制延迟，按配置停止SQL线程
+	var replica *replicaBackup
+	if err == nil {
+		replica, err = prepareReplicaBackup(&host)
+	}
+
+	// 按源库当前的库表解析表过滤模式，然后执行备份
+	var filter *backup.TableFilter
+	var result *backup.BackupResult
+	var copies []model.BackupCopy
+	if err == nil {
+		filter, err = resolveTableFilter(task, &host, databases)
+	}
+	// 保存任务时已校验过模式，备份时表可能已被删除或改名，只记录告警
+	if filter != nil {
+		for _, pattern := range filter.UnmatchedPatterns {
+			log.Printf("Warning: table pattern %q of task %s matches no table", pattern, task.Name)
+		}
+	}
+	if err == nil {
+		result, copies, err = s.performBackup(runCtx, task, &host, databases, parent, filter, progress)
+	}
+	if err != nil && execution.isCancelled() {
+		err = ErrBackupCancelled
+	} else if err != nil && runCtx.Err() == context.DeadlineExceeded {
+		err = fmt.Errorf("%w of %d minutes", ErrBackupTimeout, task.MaxRuntime)
+	}
+
+	// 无论备份是否成功都恢复从库复制，并记录从库视角的主库坐标
+	if replica != nil {
+		sourceCoords, replicaErr := replica.finish()
+		if replicaErr != nil {
+			log.Printf("Failed to restart replication on %s: %v", host.Name, replicaErr)
+			if err == nil {
+				err = fmt.Errorf("backup finished but failed to restart replication: %w", replicaErr)
+			}
+		}
+		if sourceCoords != nil {
+			backupLog.SourceHost = fmt.Sprintf("%s:%d", replica.status.SourceHost, replica.status.SourcePort)
+			backupLog.SourceBinlogFile = sourceCoords.File
+			backupLog.SourceBinlogPos = sourceCoords.Position
+			if result != nil && result.Coordinates == nil && sourceCoords.GTIDSet != "" {
+				result.Coordinates = &backup.BinlogCoordinates{GTIDSet: sourceCoords.GTIDSet}
+			}
+		}
+	}
+
+	// 执行后置钩子（无论备份是否成功），abort策略的钩子失败时本次备份记为失败
+	if hooks != nil {
+		if hookErr := hooks.Run(ctx, hook.PhasePost, hookEnv(task, &host, backupLog, result, err)); hookErr != nil && err == nil {
+			err = hookErr
+		}
+		backupLog.HookOutput = hooks.Output()
+	}
 
 	// 更新日志
 	endTime := time.Now()
@@ -70,65 +225,97 @@ func (s *BackupService) ExecuteBackup(ctx context.Context, task *model.Task) err
 
 	if err != nil {
 		backupLog.Status = "failed"
+		if errors.Is(err, ErrBackupCancelled) {
+			backupLog.Status = "cancelled"
+		}
 		backupLog.ErrorMessage = err.Error()
-		database.DB.Save(backupLog)
-
-		// 发送失败通知
-		if task.NotifyOnFailure == 1 {
-			s.sendNotification(task, backupLog)
+		if result != nil {
+			// 后置钩子失败等情况下备份文件已上传，失败的备份不会被保留策略清理，删除主存储和副本中的产物；
+			// 删除失败时保留路径和副本记录，便于手动清理
+			backupLog.FilePath = result.FilePath
+			backupLog.FileSize = result.FileSize
+			saveBackupCopies(backupLog.ID, copies)
+			if deleteErr := s.DeleteBackupArtifacts(backupLog); deleteErr != nil {
+				log.Printf("Failed to delete artifacts of failed backup %d: %v", backupLog.ID, deleteErr)
+			} else {
+				backupLog.FilePath = ""
+				backupLog.FileSize = 0
+			}
 		}
-
-		return err
+		database.DB.Save(backupLog)
+		progress.finish(backupLog.Status)
+		return backupLog, err
 	}
 
 	// 备份成功
 	backupLog.Status = "success"
 	backupLog.FilePath = result.FilePath
 	backupLog.FileSize = result.FileSize
+	backupLog.RawSize = result.RawSize
+	if result.RawSize > 0 && result.FileSize > 0 {
+		backupLog.CompressionRatio = math.Round(float64(result.RawSize)/float64(result.FileSize)*100) / 100
+	}
+	backupLog.EncryptionKeyID = result.EncryptionKeyID
+	backupLog.Checksum = result.Checksum
 	backupLog.Command = result.Command
 	backupLog.BackupTime = result.BackupTime
 	backupLog.TransferTime = result.TransferTime
+	if result.Coordinates != nil {
+		backupLog.BinlogFile = result.Coordinates.File
+		backupLog.BinlogPosition = result.Coordinates.Position
+		backupLog.GTIDExecuted = result.Coordinates.GTIDSet
+	}
+	if result.Checkpoints != nil {
+		backupLog.FromLSN = result.Checkpoints.FromLSN
+		backupLog.ToLSN = result.Checkpoints.ToLSN
+	}
 
 	// 加载存储信息
 	var storageModel model.Storage
 	if err := database.DB.First(&storageModel, task.StorageID).Error; err == nil {
+		backupLog.StorageID = storageModel.ID
 		backupLog.StorageType = storageModel.Type
 		backupLog.StorageName = storageModel.Name
 	}
 
+	// 记录源库元数据并上传元数据清单
+	s.writeManifest(ctx, task, &host, databases, filter, backupLog, result)
+
 	database.DB.Save(backupLog)
+	saveBackupCopies(backupLog.ID, copies)
+	progress.finish(backupLog.Status)
 
 	// 更新任务状态
 	now := time.Now()
 	task.LastRunAt = &now
 	database.DB.Save(task)
 
-	// 清理过期备份
-	s.cleanupExpiredBackups(task)
+	// 采集恢复验证基准
+	NewVerifyService().AfterBackup(task, &host, backupLog)
 
-	// 发送成功通知
-	if task.NotifyOnSuccess == 1 {
-		s.sendNotification(task, backupLog)
-	}
+	// 清理过期备份，然后检查存储容量
+	s.cleanupExpiredBackups(task)
+	enforceTaskQuotas(ctx, task)
 
 	log.Printf("Backup task completed: %s (ID: %d)", task.Name, task.ID)
-	return nil
+	return backupLog, nil
 }
 
-// performBackup 执行备份
-func (s *BackupService) performBackup(ctx context.Context, task *model.Task, host *model.Host, databases []string) (*backup.BackupResult, error) {
+// performBackup 执行备份，parent不为nil时基于其LSN做增量备份，filter不为nil时只备份过滤后的表，执行进度上报到progress；
+// 同时返回各副本存储的上传结果
+func (s *BackupService) performBackup(ctx context.Context, task *model.Task, host *model.Host, databases []string, parent *model.BackupLog, filter *backup.TableFilter, progress *backupProgressTracker) (*backup.BackupResult, []model.BackupCopy, error) {
 	// 创建临时目录
 	tmpDir := filepath.Join("./data/tmp", fmt.Sprintf("backup_%d_%d", task.ID, time.Now().Unix()))
 	if err := os.MkdirAll(tmpDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		return nil, nil, fmt.Errorf("failed to create temp directory: %w", err)
 	}
 	defer os.RemoveAll(tmpDir) // 清理临时目录
 
 	// 解析备份选项
 	backupOptions := make(map[string]interface{})
 	if task.BackupOptions != "" {
-		// 对于xtrabackup，尝试解析为JSON（包含SSH配置）
-		if task.BackupType == "xtrabackup" {
+		// 对于xtrabackup、gonative和mysqlsh，尝试解析为JSON（SSH配置、线程数等）
+		if task.BackupType == "xtrabackup" || task.BackupType == "gonative" || task.BackupType == "mysqlsh" {
 			if err := json.Unmarshal([]byte(task.BackupOptions), &backupOptions); err != nil {
 				// 如果解析失败，将其作为命令行参数
 				backupOptions["extra_args"] = task.BackupOptions
@@ -139,54 +326,98 @@ func (s *BackupService) performBackup(ctx context.Context, task *model.Task, hos
 		}
 	}
 
+	// mysqldump在开启binlog的服务器上记录binlog坐标，用于时间点恢复
+	if task.BackupType == "mysqldump" {
+		if enabled, err := NewHostService().IsBinlogEnabled(host); err == nil && enabled {
+			backupOptions["source_data"] = true
+		}
+	}
+
 	// 准备备份参数
 	params := &backup.BackupParams{
-		Host:            host.Host,
-		Port:            host.Port,
-		Username:        host.Username,
-		Password:        host.Password,
-		Databases:       databases,
-		OutputPath:      tmpDir,
-		Options:         backupOptions,
-		CompressionType: task.CompressionType,
+		Host:               host.Host,
+		Port:               host.Port,
+		Username:           host.Username,
+		Password:           host.Password,
+		Databases:          databases,
+		OutputPath:         tmpDir,
+		Options:            backupOptions,
+		CompressionType:    task.CompressionType,
+		CompressionLevel:   task.CompressLevel,
+		CompressionThreads: task.CompressThreads,
+		TableFilter:        filter,
+		Progress:           progress.update,
 	}
 
 	// 如果是xtrabackup，需要SSH配置
 	if task.BackupType == "xtrabackup" {
-		if sshConfig, ok := backupOptions["ssh_config"].(map[string]interface{}); ok {
-			params.SSHConfig = &backup.SSHConfig{
-				Host:           getStringValue(sshConfig, "host"),
-				Port:           getIntValue(sshConfig, "port"),
-				Username:       getStringValue(sshConfig, "username"),
-				Password:       getStringValue(sshConfig, "password"),
-				PrivateKey:     getStringValue(sshConfig, "private_key"),
-				XtrabackupPath: getStringValue(sshConfig, "xtrabackup_path"),
-			}
+		params.SSHConfig = parseSSHConfig(backupOptions)
+		if parent != nil {
+			backupOptions["incremental_lsn"] = parent.ToLSN
 		}
 	}
 
+	// 加载加密密钥（先于备份执行，避免密钥不可用时白白备份）
+	var encryptionKey []byte
+	if task.EncryptionKeyID != "" {
+		key, err := NewEncryptionService().LoadKey(task.EncryptionKeyID, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		encryptionKey = key
+	}
+
 	// 创建备份执行器
 	executor := backup.NewExecutor(task.BackupType)
 
+	// 流式备份：备份输出直接上传到存储
+	if streamExecutor, ok := executor.(backup.StreamExecutor); ok && task.Streaming == 1 {
+		return s.performStreamBackup(ctx, task, streamExecutor, params, host.Name, encryptionKey)
+	}
+
 	// 执行备份并记录时间
 	backupStartTime := time.Now()
 	result, err := executor.Execute(ctx, params)
 	backupDuration := int(time.Since(backupStartTime).Seconds())
 
 	if err != nil {
-		return nil, fmt.Errorf("backup execution failed: %w", err)
+		return nil, nil, fmt.Errorf("backup execution failed: %w", err)
 	}
 
 	// 记录备份耗时
 	result.BackupTime = backupDuration
 
-	// 上传到存储并记录时间
+	// 压缩后加密
+	if encryptionKey != nil {
+		progress.setPhase("encrypting")
+		if err := encryptResult(result, encryptionKey, task.EncryptionKeyID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// 计算最终产物的校验和
+	checksum, err := backup.FileChecksum(result.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to compute checksum: %w", err)
+	}
+	result.Checksum = checksum
+
+	// 上传到主存储并记录时间，同时并行上传到副本存储
+	progress.setPhase("uploading")
+	copiesCh := make(chan []model.BackupCopy, 1)
+	go func() {
+		copyPath := filepath.Join(host.Name, filepath.Base(result.FilePath))
+		copiesCh <- s.uploadCopies(ctx, task, result.FilePath, copyPath, checksum, tmpDir)
+	}()
 	transferStartTime := time.Now()
-	remotePath, err := s.uploadToStorage(task, result.FilePath, host.Name)
+	remotePath, err := s.uploadToStorage(ctx, task, result.FilePath, host.Name, checksum)
 	transferDuration := int(time.Since(transferStartTime).Seconds())
+	copies := <-copiesCh
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to upload backup: %w", err)
+		// 主存储上传失败时备份失败，副本不再保留
+		discardCopies(copies)
+		return nil, nil, fmt.Errorf("failed to upload backup: %w", err)
 	}
 
 	// 记录传输耗时
@@ -195,11 +426,93 @@ func (s *BackupService) performBackup(ctx context.Context, task *model.Task, hos
 	// 更新结果中的文件路径为存储路径
 	result.FilePath = remotePath
 
-	return result, nil
+	return result, copies, nil
+}
+
+// performStreamBackup 执行流式备份，备份与上传同时进行，encryptionKey不为nil时在上传前加密；
+// 主存储上传完成后再写入副本存储
+func (s *BackupService) performStreamBackup(ctx context.Context, task *model.Task, executor backup.StreamExecutor, params *backup.BackupParams, hostName string, encryptionKey []byte) (*backup.BackupResult, []model.BackupCopy, error) {
+	// 加载存储配置
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, task.StorageID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load storage: %w", err)
+	}
+	storageInstance, err := newStorageInstance(&storageModel)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fileName := executor.StreamFileName(params)
+	remotePath := filepath.Join(hostName, fileName)
+	if encryptionKey != nil {
+		remotePath += encryption.Extension
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	uploadErrCh := make(chan error, 1)
+	go func() {
+		err := storageInstance.UploadStream(ctx, pipeReader, remotePath)
+		// 上传失败时让备份进程的写入立即返回错误
+		pipeReader.CloseWithError(err)
+		uploadErrCh <- err
+	}()
+
+	// 写入管道的同时计算校验和；加密时备份输出经加密后再写入
+	hash := sha256.New()
+	var output io.Writer = io.MultiWriter(pipeWriter, hash)
+	var encryptWriter io.WriteCloser
+	if encryptionKey != nil {
+		encryptWriter, err = encryption.NewWriter(output, encryptionKey)
+		if err != nil {
+			pipeWriter.CloseWithError(err)
+			<-uploadErrCh
+			return nil, nil, fmt.Errorf("failed to create encryptor: %w", err)
+		}
+		output = encryptWriter
+	}
+
+	startTime := time.Now()
+	result, execErr := executor.ExecuteStream(ctx, params, fileName, output)
+	if execErr == nil && encryptWriter != nil {
+		execErr = encryptWriter.Close()
+	}
+	if execErr != nil {
+		pipeWriter.CloseWithError(execErr)
+	} else {
+		pipeWriter.Close()
+	}
+	uploadErr := <-uploadErrCh
+
+	if execErr != nil {
+		// 无论上传是否报错都可能留下不完整的产物；备份被取消时ctx已失效，使用新的上下文清理
+		storageInstance.Delete(context.Background(), remotePath)
+		return nil, nil, fmt.Errorf("backup execution failed: %w", execErr)
+	}
+	if uploadErr != nil {
+		return nil, nil, fmt.Errorf("failed to upload backup: %w", uploadErr)
+	}
+
+	// 备份和传输同时进行，耗时全部计入备份耗时
+	result.BackupTime = int(time.Since(startTime).Seconds())
+	result.FilePath = remotePath
+	if encryptionKey != nil {
+		result.FileSize = encryption.EncryptedSize(result.FileSize, task.EncryptionKeyID)
+		result.EncryptionKeyID = task.EncryptionKeyID
+	}
+	result.Checksum = hex.EncodeToString(hash.Sum(nil))
+
+	if err := uploadChecksumFile(ctx, storageInstance, remotePath, result.Checksum); err != nil {
+		log.Printf("Failed to upload checksum of %s: %v", remotePath, err)
+	}
+
+	// 流式备份没有本地文件，从主存储读回后上传到副本存储
+	copies := s.uploadStreamCopies(ctx, task, storageInstance, remotePath, result.Checksum, params.OutputPath)
+
+	return result, copies, nil
 }
 
-// uploadToStorage 上传备份文件到存储，返回远程路径
-func (s *BackupService) uploadToStorage(task *model.Task, localPath string, hostName string) (string, error) {
+// uploadToStorage 上传备份文件及其校验和文件到存储，返回远程路径；ctx取消时删除已上传的部分
+func (s *BackupService) uploadToStorage(ctx context.Context, task *model.Task, localPath string, hostName string, checksum string) (string, error) {
 	// 加载存储配置
 	var storageModel model.Storage
 	if err := database.DB.First(&storageModel, task.StorageID).Error; err != nil {
@@ -225,78 +538,103 @@ func (s *BackupService) uploadToStorage(task *model.Task, localPath string, host
 	)
 
 	// 上传文件
-	ctx := context.Background()
 	if err := storageInstance.Upload(ctx, localPath, remotePath); err != nil {
+		if ctx.Err() != nil {
+			storageInstance.Delete(context.Background(), remotePath)
+		}
 		return "", fmt.Errorf("failed to upload file: %w", err)
 	}
 
+	// 校验和文件上传失败不影响备份，数据库中仍记录了校验和
+	if err := uploadChecksumFile(ctx, storageInstance, remotePath, checksum); err != nil {
+		log.Printf("Failed to upload checksum of %s: %v", remotePath, err)
+	}
+
 	return remotePath, nil
 }
 
-// cleanupExpiredBackups 清理过期备份
-func (s *BackupService) cleanupExpiredBackups(task *model.Task) {
-	if task.RetentionDays <= 0 {
-		return
-	}
-
-	// 计算过期时间
-	expireTime := time.Now().AddDate(0, 0, -task.RetentionDays)
+// uploadChecksumFile 上传sha256sum格式的校验和文件
+func uploadChecksumFile(ctx context.Context, storageInstance storage.Storage, remotePath, checksum string) error {
+	content := backup.FormatChecksumFile(checksum, remotePath)
+	return storageInstance.UploadStream(ctx, strings.NewReader(content), remotePath+backup.ChecksumExtension)
+}
 
-	// 查询过期的备份日志
-	var expiredLogs []model.BackupLog
-	database.DB.Where("task_id = ? AND status = ? AND start_time < ?",
-		task.ID, "success", expireTime).Find(&expiredLogs)
+// cleanupExpiredBackups 按任务的保留策略清理备份。主存储中的产物清理后，仍有单独设置保留天数的有效副本的备份
+// 改由副本保留，副本按各自的保留天数清理
+func (s *BackupService) cleanupExpiredBackups(task *model.Task) {
+	plan := PlanRetention(task, TaskRetentionPolicy(task), time.Now())
 
-	// 删除过期备份文件和日志
-	for _, expiredLog := range expiredLogs {
-		// 删除备份文件（从存储中）
-		if err := s.DeleteBackupFile(task, expiredLog.FilePath); err != nil {
-			log.Printf("Failed to delete backup file: %v", err)
+	// 删除策略之外的备份文件和日志
+	deleted := 0
+	for _, decision := range plan.Delete {
+		var expiredLog model.BackupLog
+		if err := database.DB.First(&expiredLog, decision.BackupLogID).Error; err != nil {
+			continue
+		}
+		removed, err := pruneBackup(&expiredLog)
+		if err != nil {
+			log.Printf("Failed to prune backup %d of task %s: %v", expiredLog.ID, task.Name, err)
+			continue
+		}
+		if removed {
+			deleted++
 		}
-
-		// 删除日志记录
-		database.DB.Delete(&expiredLog)
 	}
 
-	if len(expiredLogs) > 0 {
-		log.Printf("Cleaned up %d expired backups for task %s", len(expiredLogs), task.Name)
+	if deleted > 0 {
+		log.Printf("Cleaned up %d expired backups for task %s", deleted, task.Name)
 	}
+	kept := 0
+	for _, decision := range plan.Keep {
+		if len(decision.Reasons) == 1 && decision.Reasons[0] == keepReasonChain {
+			kept++
+		}
+	}
+	if kept > 0 {
+		log.Printf("Kept %d expired backups of task %s still needed by incremental chains", kept, task.Name)
+	}
+
+	// 清理过期的副本
+	s.cleanupExpiredCopies(task, plan.protected)
 }
 
-// DeleteBackupFile 删除备份文件
-func (s *BackupService) DeleteBackupFile(task *model.Task, filePath string) error {
-	// 加载主机信息
-	var host model.Host
-	if err := database.DB.First(&host, task.HostID).Error; err != nil {
-		return err
+// pruneBackup 删除备份在主存储中的产物，仍有有效副本时提升副本并保留日志，否则删除副本和日志；
+// 返回日志是否被删除。主存储的产物删除失败时不改动目录记录并返回错误，避免留下无记录的文件
+func pruneBackup(backupLog *model.BackupLog) (bool, error) {
+	// 删除主存储中的备份文件
+	storageModel, err := backupLogStorage(backupLog)
+	if err != nil {
+		return false, err
+	}
+	if err := deleteFromStorage(storageModel, backupLog.FilePath); err != nil {
+		return false, fmt.Errorf("failed to delete backup file: %w", err)
 	}
 
-	// 加载存储配置
-	var storageModel model.Storage
-	if err := database.DB.First(&storageModel, task.StorageID).Error; err != nil {
-		return err
+	// 仍有有效副本时保留日志
+	if promoteBackupCopy(backupLog) {
+		return false, nil
 	}
 
-	// 解析存储配置
-	var storageConfig map[string]interface{}
-	if err := json.Unmarshal([]byte(storageModel.Config), &storageConfig); err != nil {
-		return err
+	// 删除副本和日志记录
+	if err := DeleteBackupCopies(backupLog.ID); err != nil {
+		return false, err
 	}
+	if err := database.DB.Delete(backupLog).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
 
-	// 创建存储实例
-	storageInstance, err := storage.NewStorage(storageModel.Type, storageConfig)
+// DeleteBackupArtifacts 删除备份在主存储和各副本存储中的产物及副本记录
+func (s *BackupService) DeleteBackupArtifacts(backupLog *model.BackupLog) error {
+	storageModel, err := backupLogStorage(backupLog)
 	if err != nil {
 		return err
 	}
-
-	// 删除文件（使用主机名而不是task_ID）
-	ctx := context.Background()
-	remotePath := filepath.Join(
-		host.Name,
-		filepath.Base(filePath),
-	)
-
-	return storageInstance.Delete(ctx, remotePath)
+	if err := deleteFromStorage(storageModel, backupLog.FilePath); err != nil {
+		return err
+	}
+	return DeleteBackupCopies(backupLog.ID)
 }
 
 // sendNotification 发送通知
@@ -331,9 +669,11 @@ func (s *BackupService) sendNotification(task *model.Task, backupLog *model.Back
 		ErrorMessage: backupLog.ErrorMessage,
 	}
 
-	message := backupNotif.ToMessage()
+	sendToChannels(notificationIDs, backupNotif.ToMessage())
+}
 
-	// 发送到所有配置的通知渠道
+// sendToChannels 发送通知到指定的通知渠道
+func sendToChannels(notificationIDs []int, message *notification.Message) {
 	for _, notifID := range notificationIDs {
 		var notifModel model.Notification
 		if err := database.DB.First(&notifModel, notifID).Error; err != nil {
@@ -375,6 +715,56 @@ func (s *BackupService) saveLog(backupLog *model.BackupLog) {
 	database.DB.Save(backupLog)
 }
 
+// encryptResult 加密备份产物，替换结果中的文件路径和大小
+func encryptResult(result *backup.BackupResult, key []byte, keyID string) error {
+	encryptedPath := result.FilePath + encryption.Extension
+	if err := encryption.EncryptFile(result.FilePath, encryptedPath, key); err != nil {
+		os.Remove(encryptedPath)
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	os.Remove(result.FilePath)
+
+	fileInfo, err := os.Stat(encryptedPath)
+	if err != nil {
+		return fmt.Errorf("failed to get file info: %w", err)
+	}
+	result.FilePath = encryptedPath
+	result.FileSize = fileInfo.Size()
+	result.EncryptionKeyID = keyID
+	return nil
+}
+
+// newStorageInstance 根据存储配置创建存储实例
+func newStorageInstance(storageModel *model.Storage) (storage.Storage, error) {
+	var storageConfig map[string]interface{}
+	if err := json.Unmarshal([]byte(storageModel.Config), &storageConfig); err != nil {
+		return nil, fmt.Errorf("failed to parse storage config: %w", err)
+	}
+
+	storageInstance, err := storage.NewStorage(storageModel.Type, storageConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create storage: %w", err)
+	}
+
+	return storageInstance, nil
+}
+
+// parseSSHConfig 从选项中解析ssh_config，不存在时返回nil
+func parseSSHConfig(options map[string]interface{}) *backup.SSHConfig {
+	sshConfig, ok := options["ssh_config"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	return &backup.SSHConfig{
+		Host:           getStringValue(sshConfig, "host"),
+		Port:           getIntValue(sshConfig, "port"),
+		Username:       getStringValue(sshConfig, "username"),
+		Password:       getStringValue(sshConfig, "password"),
+		PrivateKey:     getStringValue(sshConfig, "private_key"),
+		XtrabackupPath: getStringValue(sshConfig, "xtrabackup_path"),
+	}
+}
+
 // 辅助函数
 func getStringValue(m map[string]interface{}, key string) string {
 	if v, ok := m[key].(string); ok {
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"mbmanager/internal/storage"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// BinlogService binlog持续归档服务
+type BinlogService struct{}
+
+// NewBinlogService 创建binlog归档服务实例
+func NewBinlogService() *BinlogService {
+	return &BinlogService{}
+}
+
+// binlogTaskConfig binlog归档任务的调度配置（Task.ScheduleConfig）
+type binlogTaskConfig struct {
+	StartFile      string `json:"start_file"`      // 首次归档的起始文件，为空表示从当前文件开始
+	ServerID       int    `json:"server_id"`       // 复制连接使用的server id
+	PollInterval   int    `json:"poll_interval"`   // 检查已关闭文件的间隔（秒）
+	RotateInterval int    `json:"rotate_interval"` // 强制切换binlog的间隔（分钟），0表示不切换
+	ExtraArgs      string `json:"extra_args"`      // 额外的mysqlbinlog参数
+}
+
+// binlogRetryInterval mysqlbinlog异常退出后的重启间隔
+const binlogRetryInterval = 30 * time.Second
+
+// Run 持续归档binlog，mysqlbinlog异常退出后自动重启，直到ctx取消
+func (s *BinlogService) Run(ctx context.Context, taskID uint) {
+	for {
+		err := s.stream(ctx, taskID)
+		if ctx.Err() != nil {
+			log.Printf("Binlog archiving stopped for task %d", taskID)
+			return
+		}
+		log.Printf("Binlog archiving for task %d interrupted: %v, restarting in %s", taskID, err, binlogRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(binlogRetryInterval):
+		}
+	}
+}
+
+// stream 启动一次mysqlbinlog并定期上传已关闭的binlog文件
+func (s *BinlogService) stream(ctx context.Context, taskID uint) error {
+	// 重新加载任务（确保使用最新配置）
+	var task model.Task
+	if err := database.DB.Preload("Host").Preload("Storage").First(&task, taskID).Error; err != nil {
+		return fmt.Errorf("failed to load task: %w", err)
+	}
+	if task.Host == nil || task.Storage == nil {
+		return fmt.Errorf("host or storage of task %s not found", task.Name)
+	}
+
+	var config binlogTaskConfig
+	if task.ScheduleConfig != "" {
+		if err := json.Unmarshal([]byte(task.ScheduleConfig), &config); err != nil {
+			return fmt.Errorf("failed to parse schedule config: %w", err)
+		}
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = 10
+	}
+
+	storageInstance, err := newStorageInstance(task.Storage)
+	if err != nil {
+		return err
+	}
+
+	spoolDir := binlogSpoolDir(task.ID)
+
+	// 上次运行遗留的已关闭文件先归档
+	s.archiveClosedFiles(ctx, &task, storageInstance, spoolDir)
+
+	startFile, err := s.resolveStartFile(&task, &config, spoolDir)
+	if err != nil {
+		return err
+	}
+	log.Printf("Starting binlog archiving for task %s from %s", task.Name, startFile)
+
+	params := &backup.BinlogStreamParams{
+		Host:      task.Host.Host,
+		Port:      task.Host.Port,
+		Username:  task.Host.Username,
+		Password:  task.Host.Password,
+		SpoolDir:  spoolDir,
+		StartFile: startFile,
+		ServerID:  config.ServerID,
+		ExtraArgs: config.ExtraArgs,
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streamer := &backup.BinlogStreamer{}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- streamer.Stream(streamCtx, params)
+	}()
+
+	pollTicker := time.NewTicker(time.Duration(config.PollInterval) * time.Second)
+	defer pollTicker.Stop()
+
+	var rotateC <-chan time.Time
+	if config.RotateInterval > 0 {
+		rotateTicker := time.NewTicker(time.Duration(config.RotateInterval) * time.Minute)
+		defer rotateTicker.Stop()
+		rotateC = rotateTicker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			cancel()
+			<-errCh
+			s.archiveClosedFiles(context.Background(), &task, storageInstance, spoolDir)
+			return ctx.Err()
+
+		case err := <-errCh:
+			s.archiveClosedFiles(ctx, &task, storageInstance, spoolDir)
+			return err
+
+		case <-pollTicker.C:
+			s.archiveClosedFiles(ctx, &task, storageInstance, spoolDir)
+
+		case <-rotateC:
+			// 定期切换binlog，避免长时间未关闭的文件无法归档
+			if err := backup.FlushBinaryLogs(task.Host.Host, task.Host.Port, task.Host.Username, task.Host.Password); err != nil {
+				log.Printf("Failed to rotate binlog for task %s: %v", task.Name, err)
+			}
+		}
+	}
+}
+
+// resolveStartFile 确定本次mysqlbinlog的起始文件
+func (s *BinlogService) resolveStartFile(task *model.Task, config *binlogTaskConfig, spoolDir string) (string, error) {
+	// 暂存目录中遗留的最新文件没有写完，从它重新拉取
+	spooled, err := backup.SpooledBinlogFiles(spoolDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read spool directory: %w", err)
+	}
+	if len(spooled) > 0 {
+		return spooled[len(spooled)-1], nil
+	}
+
+	serverFiles, err := backup.ListBinaryLogs(task.Host.Host, task.Host.Port, task.Host.Username, task.Host.Password)
+	if err != nil {
+		return "", err
+	}
+	if len(serverFiles) == 0 {
+		return "", fmt.Errorf("binary logging is not enabled on host %s", task.Host.Name)
+	}
+
+	var last model.BinlogFile
+	if err := database.DB.Where("task_id = ?", task.ID).Order("file_name DESC").First(&last).Error; err != nil {
+		// 首次归档
+		if config.StartFile != "" {
+			return config.StartFile, nil
+		}
+		return serverFiles[len(serverFiles)-1], nil
+	}
+
+	// 从上次归档的下一个文件继续
+	for i, name := range serverFiles {
+		if name == last.FileName {
+			if i+1 < len(serverFiles) {
+				return serverFiles[i+1], nil
+			}
+			return name, nil
+		}
+	}
+
+	log.Printf("Warning: binlog %s of task %s has been purged on the server, archive has a gap", last.FileName, task.Name)
+	return serverFiles[0], nil
+}
+
+// archiveClosedFiles 上传已关闭的binlog文件并记录到目录
+func (s *BinlogService) archiveClosedFiles(ctx context.Context, task *model.Task, storageInstance storage.Storage, spoolDir string) {
+	files, err := backup.ClosedBinlogFiles(spoolDir)
+	if err != nil {
+		log.Printf("Failed to list spooled binlogs for task %s: %v", task.Name, err)
+		return
+	}
+
+	archived := 0
+	for _, name := range files {
+		if err := s.archiveFile(ctx, task, storageInstance, spoolDir, name); err != nil {
+			log.Printf("Failed to archive binlog %s for task %s: %v", name, task.Name, err)
+			return
+		}
+		archived++
+	}
+
+	if archived > 0 {
+		s.cleanupExpiredBinlogs(ctx, task, storageInstance)
+	}
+}
+
+// archiveFile 解析、上传单个binlog文件
+func (s *BinlogService) archiveFile(ctx context.Context, task *model.Task, storageInstance storage.Storage, spoolDir, name string) error {
+	localPath := filepath.Join(spoolDir, name)
+
+	fileInfo, err := os.Stat(localPath)
+	if err != nil {
+		return err
+	}
+
+	info, err := backup.ParseBinlogFile(ctx, localPath)
+	if err != nil {
+		return err
+	}
+
+	remotePath := path.Join(task.Host.Name, "binlog", name)
+	if err := storageInstance.Upload(ctx, localPath, remotePath); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	// 同一文件可能因mysqlbinlog重启被重复拉取，已存在时更新记录
+	var binlogFile model.BinlogFile
+	database.DB.Where("task_id = ? AND file_name = ?", task.ID, name).First(&binlogFile)
+	binlogFile.TaskID = task.ID
+	binlogFile.HostID = task.HostID
+	binlogFile.HostName = task.Host.Name
+	binlogFile.FileName = name
+	binlogFile.FilePath = remotePath
+	binlogFile.FileSize = fileInfo.Size()
+	binlogFile.StorageID = task.Storage.ID
+	binlogFile.StorageType = task.Storage.Type
+	binlogFile.StorageName = task.Storage.Name
+	binlogFile.FirstEventTime = info.FirstEventTime
+	binlogFile.LastEventTime = info.LastEventTime
+	binlogFile.PreviousGTIDs = info.PreviousGTIDs
+	binlogFile.GTIDs = info.GTIDs
+	if err := database.DB.Save(&binlogFile).Error; err != nil {
+		return fmt.Errorf("failed to save binlog record: %w", err)
+	}
+
+	os.Remove(localPath)
+	log.Printf("Binlog archived: %s (task: %s, GTIDs: %s)", remotePath, task.Name, info.GTIDs)
+	return nil
+}
+
+// cleanupExpiredBinlogs 清理超过保留天数的binlog。源库上最早的仍保留的成功备份做时间点恢复需要从其binlog坐标开始的所有binlog，
+// 这些binlog即使超过保留天数也不删除；文件从记录所在的存储删除，删除失败时保留记录
+func (s *BinlogService) cleanupExpiredBinlogs(ctx context.Context, task *model.Task, storageInstance storage.Storage) {
+	if task.RetentionDays <= 0 {
+		return
+	}
+
+	expireTime := time.Now().AddDate(0, 0, -task.RetentionDays)
+
+	query := database.DB.Where("task_id = ? AND last_event_time < ?", task.ID, expireTime)
+	var oldestBase model.BackupLog
+	if err := database.DB.Joins("JOIN tasks ON tasks.id = backup_logs.task_id").
+		Where("tasks.host_id = ? AND backup_logs.status = ? AND backup_logs.binlog_file <> '' AND backup_logs.file_path <> ''", task.HostID, "success").
+		Order("backup_logs.start_time ASC").First(&oldestBase).Error; err == nil {
+		query = query.Where("file_name < ?", oldestBase.BinlogFile)
+	}
+
+	var expired []model.BinlogFile
+	query.Find(&expired)
+
+	storages := map[uint]storage.Storage{task.StorageID: storageInstance}
+	deleted := 0
+	for _, binlogFile := range expired {
+		storageID := binlogFile.StorageID
+		if storageID == 0 {
+			storageID = task.StorageID
+		}
+		fileStorage, ok := storages[storageID]
+		if !ok {
+			var storageModel model.Storage
+			if err := database.DB.First(&storageModel, storageID).Error; err != nil {
+				log.Printf("Storage %d of binlog %s not found: %v", storageID, binlogFile.FileName, err)
+				continue
+			}
+			instance, err := newStorageInstance(&storageModel)
+			if err != nil {
+				log.Printf("Failed to open storage %s of binlog %s: %v", storageModel.Name, binlogFile.FileName, err)
+				continue
+			}
+			storages[storageID] = instance
+			fileStorage = instance
+		}
+
+		if err := fileStorage.Delete(ctx, binlogFile.FilePath); err != nil {
+			log.Printf("Failed to delete binlog file: %v", err)
+			continue
+		}
+		database.DB.Delete(&binlogFile)
+		deleted++
+	}
+
+	if deleted > 0 {
+		log.Printf("Cleaned up %d expired binlogs for task %s", deleted, task.Name)
+	}
+}
+
+// binlogSpoolDir binlog本地暂存目录
+func binlogSpoolDir(taskID uint) string {
+	return filepath.Join("./data/binlog", fmt.Sprintf("task_%d", taskID))
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+	"mbmanager/internal/database"
+	"mbmanager/internal/encryption"
+	"mbmanager/internal/model"
+	"os"
+	"strings"
+)
+
+// EncryptionService 加密密钥服务
+type EncryptionService struct{}
+
+// NewEncryptionService 创建加密密钥服务实例
+func NewEncryptionService() *EncryptionService {
+	return &EncryptionService{}
+}
+
+// CreateKey 登记密钥，keyData为空时生成随机密钥，返回base64编码的密钥（只在创建时返回一次）
+func (s *EncryptionService) CreateKey(key *model.EncryptionKey, keyData string) (string, error) {
+	var raw []byte
+	var err error
+	if keyData == "" {
+		raw, err = encryption.GenerateKey()
+	} else {
+		raw, err = encryption.ParseKey(keyData)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	key.KeyID = encryption.KeyID(raw)
+	key.KeyData = encryption.EncodeKey(raw)
+
+	var count int64
+	database.DB.Model(&model.EncryptionKey{}).Where("key_id = ?", key.KeyID).Count(&count)
+	if count > 0 {
+		return "", fmt.Errorf("key is already registered")
+	}
+
+	if err := database.DB.Create(key).Error; err != nil {
+		return "", err
+	}
+	return key.KeyData, nil
+}
+
+// LoadKey 按密钥标识加载密钥，forEncrypt为true时要求密钥可用于新备份
+func (s *EncryptionService) LoadKey(keyID string, forEncrypt bool) ([]byte, error) {
+	var key model.EncryptionKey
+	if err := database.DB.Where("key_id = ?", keyID).First(&key).Error; err != nil {
+		return nil, fmt.Errorf("encryption key %s not found", keyID)
+	}
+	if forEncrypt && key.Status != 1 {
+		return nil, fmt.Errorf("encryption key %s is disabled for new backups", keyID)
+	}
+	return encryption.ParseKey(key.KeyData)
+}
+
+// KeyInUse 检查密钥是否被任务或备份使用
+func (s *EncryptionService) KeyInUse(keyID string) bool {
+	var count int64
+	database.DB.Model(&model.Task{}).Where("encryption_key_id = ?", keyID).Count(&count)
+	if count > 0 {
+		return true
+	}
+	database.DB.Model(&model.BackupLog{}).Where("encryption_key_id = ?", keyID).Count(&count)
+	return count > 0
+}
+
+// DecryptFile 解密下载到本地的加密备份文件，返回解密后的文件路径
+func (s *EncryptionService) DecryptFile(path, keyID string) (string, error) {
+	key, err := s.LoadKey(keyID, false)
+	if err != nil {
+		return "", err
+	}
+
+	plainPath := strings.TrimSuffix(path, encryption.Extension)
+	if plainPath == path {
+		plainPath = path + ".dec"
+	}
+	if err := encryption.DecryptFile(path, plainPath, key); err != nil {
+		return "", fmt.Errorf("failed to decrypt %s: %w", path, err)
+	}
+	os.Remove(path)
+	return plainPath, nil
+}
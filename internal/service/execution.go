@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBackupCancelled 备份被用户取消
+var ErrBackupCancelled = errors.New("backup cancelled")
+
+// backupExecution 一次运行中的备份执行
+type backupExecution struct {
+	TaskID      uint
+	BackupLogID uint
+	cancel      context.CancelFunc
+	cancelled   bool
+}
+
+var (
+	executionsMu     sync.Mutex
+	executionsByLog  = make(map[uint]*backupExecution) // 备份日志ID -> 执行
+	executionsByTask = make(map[uint]*backupExecution) // 任务ID -> 执行
+)
+
+// registerExecution 登记运行中的备份，cancel用于取消本次执行
+func registerExecution(taskID, backupLogID uint, cancel context.CancelFunc) *backupExecution {
+	e := &backupExecution{TaskID: taskID, BackupLogID: backupLogID, cancel: cancel}
+
+	executionsMu.Lock()
+	defer executionsMu.Unlock()
+	executionsByLog[backupLogID] = e
+	executionsByTask[taskID] = e
+	return e
+}
+
+// unregister 备份结束后移除登记
+func (e *backupExecution) unregister() {
+	executionsMu.Lock()
+	defer executionsMu.Unlock()
+	if executionsByLog[e.BackupLogID] == e {
+		delete(executionsByLog, e.BackupLogID)
+	}
+	if executionsByTask[e.TaskID] == e {
+		delete(executionsByTask, e.TaskID)
+	}
+}
+
+// isCancelled 是否已被取消
+func (e *backupExecution) isCancelled() bool {
+	executionsMu.Lock()
+	defer executionsMu.Unlock()
+	return e.cancelled
+}
+
+// CancelBackup 取消运行中的备份，备份不在本服务中运行时返回false
+func CancelBackup(backupLogID uint) bool {
+	executionsMu.Lock()
+	defer executionsMu.Unlock()
+
+	e, ok := executionsByLog[backupLogID]
+	if !ok {
+		return false
+	}
+	e.cancelled = true
+	e.cancel()
+	return true
+}
+
+// RunningBackupLogID 返回任务当前运行中的备份日志ID，任务没有运行时返回false
+func RunningBackupLogID(taskID uint) (uint, bool) {
+	executionsMu.Lock()
+	defer executionsMu.Unlock()
+
+	e, ok := executionsByTask[taskID]
+	if !ok {
+		return 0, false
+	}
+	return e.BackupLogID, true
+}
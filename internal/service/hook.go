@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/hook"
+	"mbmanager/internal/model"
+)
+
+// ValidateHooks 检查任务的钩子配置
+func ValidateHooks(task *model.Task) error {
+	_, err := hook.Parse(task.Hooks)
+	return err
+}
+
+// TaskCommandHooks 返回任务中在服务器上执行命令的钩子（shell、ssh），未配置时为空，
+// 用于判断修改任务是否需要管理员权限
+func TaskCommandHooks(task *model.Task) string {
+	hooks, err := hook.Parse(task.Hooks)
+	if err != nil {
+		return ""
+	}
+	var commandHooks []hook.Hook
+	for _, h := range hooks {
+		if h.Type == "shell" || h.Type == "ssh" {
+			commandHooks = append(commandHooks, h)
+		}
+	}
+	if len(commandHooks) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(commandHooks)
+	return string(data)
+}
+
+// newHookRunner 创建任务的钩子执行器，sql钩子连接备份主机，ssh钩子默认使用任务的SSH配置；未配置钩子时返回nil
+func newHookRunner(task *model.Task, host *model.Host) (*hook.Runner, error) {
+	hooks, err := hook.Parse(task.Hooks)
+	if err != nil || len(hooks) == 0 {
+		return nil, err
+	}
+
+	target := &hook.Target{
+		Host:     host.Host,
+		Port:     host.Port,
+		Username: host.Username,
+		Password: host.Password,
+	}
+	if task.BackupOptions != "" {
+		options := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(task.BackupOptions), &options); err == nil {
+			target.SSHConfig = parseSSHConfig(options)
+		}
+	}
+	return hook.NewRunner(hooks, target), nil
+}
+
+// hookEnv 生成传给钩子的MBM_*环境变量，后置钩子额外包含备份状态和产物信息
+func hookEnv(task *model.Task, host *model.Host, backupLog *model.BackupLog, result *backup.BackupResult, backupErr error) map[string]string {
+	env := map[string]string{
+		"MBM_TASK_ID":       fmt.Sprintf("%d", task.ID),
+		"MBM_TASK_NAME":     task.Name,
+		"MBM_BACKUP_TYPE":   task.BackupType,
+		"MBM_BACKUP_LOG_ID": fmt.Sprintf("%d", backupLog.ID),
+		"MBM_HOST_NAME":     host.Name,
+		"MBM_MYSQL_HOST":    host.Host,
+		"MBM_MYSQL_PORT":    fmt.Sprintf("%d", host.Port),
+		"MBM_DATABASES":     task.Databases,
+		"MBM_STATUS":        "running",
+	}
+	if errors.Is(backupErr, ErrBackupCancelled) {
+		env["MBM_STATUS"] = "cancelled"
+		env["MBM_ERROR"] = backupErr.Error()
+	} else if backupErr != nil {
+		env["MBM_STATUS"] = "failed"
+		env["MBM_ERROR"] = backupErr.Error()
+	} else if result != nil {
+		env["MBM_STATUS"] = "success"
+	}
+	if result != nil {
+		env["MBM_ARTIFACT_PATH"] = result.FilePath
+		env["MBM_FILE_SIZE"] = fmt.Sprintf("%d", result.FileSize)
+		env["MBM_CHECKSUM"] = result.Checksum
+		if result.Coordinates != nil {
+			env["MBM_BINLOG_FILE"] = result.Coordinates.File
+			env["MBM_BINLOG_POSITION"] = fmt.Sprintf("%d", result.Coordinates.Position)
+			env["MBM_GTID_EXECUTED"] = result.Coordinates.GTIDSet
+		}
+	}
+	return env
+}
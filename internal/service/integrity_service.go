@@ -0,0 +1,155 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// IntegrityService 备份文件完整性校验服务
+type IntegrityService struct{}
+
+// NewIntegrityService 创建完整性校验服务实例
+func NewIntegrityService() *IntegrityService {
+	return &IntegrityService{}
+}
+
+// IntegrityResult 完整性校验结果
+type IntegrityResult struct {
+	BackupLogID uint   `json:"backup_log_id"`
+	Status      string `json:"status"`   // ok, corrupted, missing
+	Expected    string `json:"expected"` // 备份时记录的校验和
+	Actual      string `json:"actual"`   // 重新读取存储中文件计算的校验和
+	Sidecar     string `json:"sidecar"`  // 存储中校验和文件记录的校验和
+	Message     string `json:"message"`
+}
+
+// VerifyBackup 通过存储重新读取备份文件并与记录的校验和比较，结果写入备份日志
+func (s *IntegrityService) VerifyBackup(ctx context.Context, backupLogID uint) (*IntegrityResult, error) {
+	var backupLog model.BackupLog
+	if err := database.DB.First(&backupLog, backupLogID).Error; err != nil {
+		return nil, fmt.Errorf("backup log not found: %w", err)
+	}
+	if backupLog.Status != "success" || backupLog.FilePath == "" {
+		return nil, fmt.Errorf("backup file not available")
+	}
+	if backupLog.Checksum == "" {
+		return nil, fmt.Errorf("backup %d has no recorded checksum", backupLog.ID)
+	}
+
+	storageModel, err := backupLogStorage(&backupLog)
+	if err != nil {
+		return nil, err
+	}
+	storageInstance, err := newStorageInstance(storageModel)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &IntegrityResult{
+		BackupLogID: backupLog.ID,
+		Expected:    backupLog.Checksum,
+	}
+
+	exists, err := storageInstance.Exists(ctx, backupLog.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check backup file: %w", err)
+	}
+	if !exists {
+		result.Status = "missing"
+		result.Message = "backup file does not exist in storage"
+		s.RecordIntegrity(&backupLog, result.Status)
+		return result, nil
+	}
+
+	tmpDir := filepath.Join("./data/tmp", fmt.Sprintf("integrity_%d_%d", backupLog.ID, time.Now().UnixNano()))
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	localPath := filepath.Join(tmpDir, filepath.Base(backupLog.FilePath))
+	if err := storageInstance.Download(ctx, backupLog.FilePath, localPath); err != nil {
+		return nil, fmt.Errorf("failed to download backup file: %w", err)
+	}
+	if result.Actual, err = backup.FileChecksum(localPath); err != nil {
+		return nil, err
+	}
+
+	// 校验和文件被修改同样视为篡改
+	sidecarPath := localPath + backup.ChecksumExtension
+	if err := storageInstance.Download(ctx, backupLog.FilePath+backup.ChecksumExtension, sidecarPath); err == nil {
+		if content, err := os.ReadFile(sidecarPath); err == nil {
+			result.Sidecar, _ = backup.ParseChecksumFile(string(content))
+		}
+	}
+
+	switch {
+	case result.Actual != result.Expected:
+		result.Status = "corrupted"
+		result.Message = "backup file checksum does not match"
+	case result.Sidecar != "" && result.Sidecar != result.Expected:
+		result.Status = "corrupted"
+		result.Message = "checksum file in storage does not match"
+	case result.Sidecar == "":
+		result.Status = "ok"
+		result.Message = "backup file checksum matches, checksum file is missing or invalid"
+	default:
+		result.Status = "ok"
+		result.Message = "backup file checksum matches"
+	}
+
+	s.RecordIntegrity(&backupLog, result.Status)
+	return result, nil
+}
+
+// RecordIntegrity 记录完整性校验结果
+func (s *IntegrityService) RecordIntegrity(backupLog *model.BackupLog, status string) {
+	now := time.Now()
+	backupLog.IntegrityStatus = status
+	backupLog.IntegrityAt = &now
+	database.DB.Model(backupLog).Updates(map[string]interface{}{
+		"integrity_status": status,
+		"integrity_at":     &now,
+	})
+}
+
+// Scrub 巡检备份文件完整性，每次最多检查batch个最久未检查的备份，发现损坏或丢失时记录日志
+func (s *IntegrityService) Scrub(ctx context.Context, batch int) {
+	var backupLogs []model.BackupLog
+	query := database.DB.Where("status = ? AND checksum <> ''", "success").Order("integrity_at ASC")
+	if batch > 0 {
+		query = query.Limit(batch)
+	}
+	if err := query.Find(&backupLogs).Error; err != nil {
+		log.Printf("Failed to load backups for scrub: %v", err)
+		return
+	}
+
+	var ok, failed int
+	for _, backupLog := range backupLogs {
+		if ctx.Err() != nil {
+			break
+		}
+		result, err := s.VerifyBackup(ctx, backupLog.ID)
+		if err != nil {
+			log.Printf("Scrub of backup %d failed: %v", backupLog.ID, err)
+			failed++
+			continue
+		}
+		if result.Status != "ok" {
+			log.Printf("Scrub found %s backup %d (%s): %s", result.Status, backupLog.ID, backupLog.FilePath, result.Message)
+			failed++
+			continue
+		}
+		ok++
+	}
+
+	log.Printf("Backup scrub completed: %d ok, %d failed", ok, failed)
+}
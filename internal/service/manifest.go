@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"path/filepath"
+	"strings"
+)
+
+// writeManifest 采集源库元数据写入备份日志，并将元数据清单上传到备份文件旁
+func (s *BackupService) writeManifest(ctx context.Context, task *model.Task, host *model.Host, databases []string, filter *backup.TableFilter, backupLog *model.BackupLog, result *backup.BackupResult) {
+	backupLog.ToolVersion = result.ToolVersion
+
+	manifest := &backup.Manifest{
+		ManifestVersion: 1,
+		TaskID:          task.ID,
+		TaskName:        task.Name,
+		BackupLogID:     backupLog.ID,
+		HostName:        host.Name,
+		BackupType:      backupLog.BackupType,
+		BackupMode:      backupLog.BackupMode,
+		ParentLogID:     backupLog.ParentLogID,
+		StartTime:       backupLog.StartTime,
+		FileName:        filepath.Base(backupLog.FilePath),
+		FileSize:        backupLog.FileSize,
+		RawSize:         backupLog.RawSize,
+		Checksum:        backupLog.Checksum,
+		Compression:     task.CompressionType,
+		EncryptionKeyID: backupLog.EncryptionKeyID,
+		ToolVersion:     result.ToolVersion,
+		Databases:       []backup.DatabaseStats{},
+	}
+	if backupLog.EndTime != nil {
+		manifest.EndTime = *backupLog.EndTime
+	}
+	if backupLog.BinlogFile != "" || backupLog.GTIDExecuted != "" {
+		manifest.Binlog = &backup.ManifestBinlog{
+			File:         backupLog.BinlogFile,
+			Position:     backupLog.BinlogPosition,
+			GTIDExecuted: backupLog.GTIDExecuted,
+		}
+	}
+	if backupLog.SourceBinlogFile != "" {
+		manifest.SourceHost = backupLog.SourceHost
+		manifest.SourceBinlog = &backup.ManifestBinlog{
+			File:     backupLog.SourceBinlogFile,
+			Position: backupLog.SourceBinlogPos,
+		}
+	}
+	if backupLog.ToLSN > 0 {
+		manifest.LSN = &backup.ManifestLSN{From: backupLog.FromLSN, To: backupLog.ToLSN}
+	}
+
+	// 表统计来自information_schema，采集失败不影响备份
+	info, err := backup.CollectServerInfo(host.Host, host.Port, host.Username, host.Password, databases)
+	if err != nil {
+		log.Printf("Failed to collect server info for backup %d: %v", backupLog.ID, err)
+	} else {
+		info.Exclude(filter)
+		manifest.ServerVersion = info.Version
+		manifest.Databases = info.Databases
+		backupLog.ServerVersion = info.Version
+		backupLog.TableCount = info.TableCount()
+		backupLog.RowEstimate = info.RowEstimate()
+	}
+
+	if err := s.uploadManifest(ctx, task, backupLog.FilePath, manifest); err != nil {
+		log.Printf("Failed to upload manifest of backup %d: %v", backupLog.ID, err)
+	}
+}
+
+// uploadManifest 上传元数据清单
+func (s *BackupService) uploadManifest(ctx context.Context, task *model.Task, remotePath string, manifest *backup.Manifest) error {
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, task.StorageID).Error; err != nil {
+		return fmt.Errorf("failed to load storage: %w", err)
+	}
+	storageInstance, err := newStorageInstance(&storageModel)
+	if err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storageInstance.UploadStream(ctx, strings.NewReader(string(content)), remotePath+backup.ManifestExtension)
+}
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"mbmanager/internal/storage"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MigrationService 备份迁移服务
+type MigrationService struct{}
+
+// NewMigrationService 创建备份迁移服务实例
+func NewMigrationService() *MigrationService {
+	return &MigrationService{}
+}
+
+// MigrationRequest 迁移请求，筛选条件为空时迁移源存储中的全部备份
+type MigrationRequest struct {
+	SourceStorageID uint   `json:"source_storage_id" binding:"required"`
+	DestStorageID   uint   `json:"dest_storage_id" binding:"required"`
+	HostName        string `json:"host_name"`     // 备份来源主机
+	TaskID          uint   `json:"task_id"`       // 任务
+	FromTime        string `json:"from_time"`     // 备份开始时间下限
+	ToTime          string `json:"to_time"`       // 备份开始时间上限
+	DeleteSource    bool   `json:"delete_source"` // 校验通过后删除源存储中的产物
+}
+
+// migrationItem 一个待迁移的产物，backupCopy为nil时是备份日志的主存储产物
+type migrationItem struct {
+	backupLog  model.BackupLog
+	backupCopy *model.BackupCopy
+}
+
+// migratingStorages 正在迁移中的存储，同一存储同时只允许一个迁移任务
+var (
+	migratingMu       sync.Mutex
+	migratingStorages = make(map[uint]uint) // 存储ID -> 迁移任务ID
+)
+
+// StartMigration 校验请求、创建迁移任务并在后台执行
+func (s *MigrationService) StartMigration(req *MigrationRequest, createdBy string) (*model.MigrationJob, error) {
+	if req.SourceStorageID == req.DestStorageID {
+		return nil, fmt.Errorf("source and destination storage must be different")
+	}
+	var source, dest model.Storage
+	if err := database.DB.First(&source, req.SourceStorageID).Error; err != nil {
+		return nil, fmt.Errorf("source storage not found")
+	}
+	if err := database.DB.First(&dest, req.DestStorageID).Error; err != nil {
+		return nil, fmt.Errorf("destination storage not found")
+	}
+
+	job := &model.MigrationJob{
+		SourceStorageID: source.ID,
+		SourceStorage:   source.Name,
+		DestStorageID:   dest.ID,
+		DestStorage:     dest.Name,
+		HostName:        req.HostName,
+		TaskID:          req.TaskID,
+		DeleteSource:    req.DeleteSource,
+		Status:          "running",
+		CreatedBy:       createdBy,
+		StartTime:       time.Now(),
+	}
+	if req.FromTime != "" {
+		t, err := parseTargetTime(req.FromTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from_time: %w", err)
+		}
+		job.FromTime = t
+	}
+	if req.ToTime != "" {
+		t, err := parseTargetTime(req.ToTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid to_time: %w", err)
+		}
+		job.ToTime = t
+	}
+
+	items := s.collectItems(job)
+	job.Total = len(items)
+	for _, item := range items {
+		job.TotalBytes += item.fileSize()
+	}
+
+	migratingMu.Lock()
+	if jobID, ok := migratingStorages[source.ID]; ok {
+		migratingMu.Unlock()
+		return nil, fmt.Errorf("storage %s is being migrated by job %d", source.Name, jobID)
+	}
+	if jobID, ok := migratingStorages[dest.ID]; ok {
+		migratingMu.Unlock()
+		return nil, fmt.Errorf("storage %s is being migrated by job %d", dest.Name, jobID)
+	}
+	// 对账期间改写存储引用会造成误报
+	reconcilingMu.Lock()
+	for _, storageModel := range []*model.Storage{&source, &dest} {
+		if jobID, ok := reconcilingStorages[storageModel.ID]; ok {
+			reconcilingMu.Unlock()
+			migratingMu.Unlock()
+			return nil, fmt.Errorf("storage %s is being reconciled by job %d", storageModel.Name, jobID)
+		}
+	}
+	reconcilingMu.Unlock()
+	if err := database.DB.Create(job).Error; err != nil {
+		migratingMu.Unlock()
+		return nil, fmt.Errorf("failed to create migration job: %w", err)
+	}
+	migratingStorages[source.ID] = job.ID
+	migratingStorages[dest.ID] = job.ID
+	migratingMu.Unlock()
+
+	go s.executeMigration(context.Background(), job, &source, &dest, items)
+
+	return job, nil
+}
+
+// collectItems 按筛选条件收集源存储中的备份产物：主存储在源存储的成功备份，以及在源存储中校验成功的副本
+func (s *MigrationService) collectItems(job *model.MigrationJob) []migrationItem {
+	query := database.DB.Model(&model.BackupLog{}).Where("status = ? AND file_path <> ''", "success")
+	if job.HostName != "" {
+		query = query.Where("host_name = ?", job.HostName)
+	}
+	if job.TaskID != 0 {
+		query = query.Where("task_id = ?", job.TaskID)
+	}
+	if job.FromTime != nil {
+		query = query.Where("start_time >= ?", *job.FromTime)
+	}
+	if job.ToTime != nil {
+		query = query.Where("start_time <= ?", *job.ToTime)
+	}
+
+	var backupLogs []model.BackupLog
+	query.Order("start_time").Find(&backupLogs)
+
+	var items []migrationItem
+	for _, backupLog := range backupLogs {
+		storageModel, err := backupLogStorage(&backupLog)
+		if err == nil && storageModel.ID == job.SourceStorageID {
+			items = append(items, migrationItem{backupLog: backupLog})
+		}
+
+		var copies []model.BackupCopy
+		database.DB.Where("backup_log_id = ? AND storage_id = ? AND status = ?", backupLog.ID, job.SourceStorageID, "success").Find(&copies)
+		for i := range copies {
+			items = append(items, migrationItem{backupLog: backupLog, backupCopy: &copies[i]})
+		}
+	}
+	return items
+}
+
+// filePath 产物在存储中的路径
+func (item *migrationItem) filePath() string {
+	if item.backupCopy != nil {
+		return item.backupCopy.FilePath
+	}
+	return item.backupLog.FilePath
+}
+
+// fileSize 产物大小
+func (item *migrationItem) fileSize() int64 {
+	if item.backupCopy != nil && item.backupCopy.FileSize > 0 {
+		return item.backupCopy.FileSize
+	}
+	return item.backupLog.FileSize
+}
+
+// executeMigration 依次迁移各产物，单个产物失败不影响其他产物
+func (s *MigrationService) executeMigration(ctx context.Context, job *model.MigrationJob, source, dest *model.Storage, items []migrationItem) {
+	defer func() {
+		migratingMu.Lock()
+		delete(migratingStorages, source.ID)
+		delete(migratingStorages, dest.ID)
+		migratingMu.Unlock()
+	}()
+
+	stepLog := s.stepLogger(job)
+	stepLog(fmt.Sprintf("Migrating %d artifacts (%d bytes) from %s to %s", job.Total, job.TotalBytes, source.Name, dest.Name))
+
+	sourceInstance, err := newStorageInstance(source)
+	var destInstance storage.Storage
+	if err == nil {
+		destInstance, err = newStorageInstance(dest)
+	}
+	if err != nil {
+		s.finish(job, err)
+		return
+	}
+
+	tmpDir := filepath.Join("./data/tmp", fmt.Sprintf("migration_%d_%d", job.ID, time.Now().Unix()))
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		s.finish(job, fmt.Errorf("failed to create temp directory: %w", err))
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for i := range items {
+		item := &items[i]
+		job.CurrentFile = item.filePath()
+		database.DB.Model(job).Update("current_file", job.CurrentFile)
+
+		skipped, err := s.migrateItem(ctx, job, item, source, dest, sourceInstance, destInstance, tmpDir)
+		switch {
+		case err != nil:
+			job.Failed++
+			stepLog(fmt.Sprintf("Failed %s (backup %d): %v", item.filePath(), item.backupLog.ID, err))
+		case skipped != "":
+			job.Skipped++
+			stepLog(fmt.Sprintf("Skipped %s (backup %d): %s", item.filePath(), item.backupLog.ID, skipped))
+		default:
+			job.Completed++
+			stepLog(fmt.Sprintf("Migrated %s (backup %d)", item.filePath(), item.backupLog.ID))
+		}
+
+		job.BytesDone += item.fileSize()
+		if job.TotalBytes > 0 {
+			job.Progress = int(job.BytesDone * 100 / job.TotalBytes)
+		} else {
+			job.Progress = (i + 1) * 100 / len(items)
+		}
+		database.DB.Model(job).Updates(map[string]interface{}{
+			"completed":  job.Completed,
+			"failed":     job.Failed,
+			"skipped":    job.Skipped,
+			"bytes_done": job.BytesDone,
+			"progress":   job.Progress,
+		})
+	}
+
+	s.finish(job, nil)
+}
+
+// migrateItem 迁移一个产物：下载到本地并校验，上传到目标存储后读回校验，再更新目录中的存储引用。
+// 返回非空的skipped表示跳过的原因
+func (s *MigrationService) migrateItem(ctx context.Context, job *model.MigrationJob, item *migrationItem, source, dest *model.Storage, sourceInstance, destInstance storage.Storage, tmpDir string) (skipped string, err error) {
+	remotePath := item.filePath()
+
+	// 目标存储中已有该备份的主产物或副本时不重复迁移
+	var existing int64
+	database.DB.Model(&model.BackupCopy{}).Where("backup_log_id = ? AND storage_id = ? AND status <> ?", item.backupLog.ID, dest.ID, "failed").Count(&existing)
+	if existing > 0 || (item.backupCopy != nil && item.backupLog.StorageID == dest.ID) {
+		return "destination already holds this backup", nil
+	}
+
+	// 下载源产物，按目录中记录的校验和确认源文件完好
+	workDir := filepath.Join(tmpDir, fmt.Sprintf("item_%d", item.backupLog.ID))
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	localPath := filepath.Join(workDir, filepath.Base(remotePath))
+	if err := sourceInstance.Download(ctx, remotePath, localPath); err != nil {
+		return "", fmt.Errorf("failed to download from %s: %w", source.Name, err)
+	}
+	checksum, err := backup.FileChecksum(localPath)
+	if err != nil {
+		return "", err
+	}
+	if expected := item.backupLog.Checksum; expected != "" && checksum != expected {
+		return "", fmt.Errorf("source checksum mismatch: expected %s, got %s", expected, checksum)
+	}
+
+	// 上传产物及校验和文件，元数据清单存在时一并迁移
+	if err := destInstance.Upload(ctx, localPath, remotePath); err != nil {
+		return "", fmt.Errorf("failed to upload to %s: %w", dest.Name, err)
+	}
+	if err := uploadChecksumFile(ctx, destInstance, remotePath, checksum); err != nil {
+		log.Printf("Failed to upload checksum of %s to storage %s: %v", remotePath, dest.Name, err)
+	}
+	manifestPath := localPath + backup.ManifestExtension
+	if sourceInstance.Download(ctx, remotePath+backup.ManifestExtension, manifestPath) == nil {
+		if err := destInstance.Upload(ctx, manifestPath, remotePath+backup.ManifestExtension); err != nil {
+			log.Printf("Failed to upload manifest of %s to storage %s: %v", remotePath, dest.Name, err)
+		}
+	}
+
+	// 读回目标存储中的产物校验
+	actual, size, err := remoteChecksum(ctx, destInstance, remotePath, filepath.Join(workDir, "verify"))
+	if err == nil && actual != checksum {
+		err = fmt.Errorf("destination checksum mismatch: expected %s, got %s", checksum, actual)
+	}
+	if err != nil {
+		deleteFromStorage(dest, remotePath)
+		return "", fmt.Errorf("failed to verify %s: %w", dest.Name, err)
+	}
+
+	// 更新目录中的存储引用，备份在迁移期间被删除时清理目标存储中的产物
+	now := time.Now()
+	if !s.repointItem(item, dest, size, checksum, now) {
+		deleteFromStorage(dest, remotePath)
+		return "backup was deleted during migration", nil
+	}
+
+	// 删除源产物，或将其作为副本保留以便继续按保留期清理
+	if job.DeleteSource {
+		if err := deleteFromStorage(source, remotePath); err != nil {
+			log.Printf("Failed to delete %s from storage %s after migration: %v", remotePath, source.Name, err)
+		}
+		return "", nil
+	}
+	sourceCopy := model.BackupCopy{
+		BackupLogID: item.backupLog.ID,
+		TaskID:      item.backupLog.TaskID,
+		StorageID:   source.ID,
+		StorageType: source.Type,
+		StorageName: source.Name,
+		FilePath:    remotePath,
+		FileSize:    size,
+		Checksum:    checksum,
+		Status:      "success",
+		VerifiedAt:  &now,
+	}
+	if item.backupCopy != nil {
+		sourceCopy.RetentionDays = item.backupCopy.RetentionDays
+	}
+	if err := database.DB.Create(&sourceCopy).Error; err != nil {
+		log.Printf("Failed to record source copy of backup %d: %v", item.backupLog.ID, err)
+	}
+	return "", nil
+}
+
+// repointItem 将产物的存储引用改为目标存储，记录已被删除时返回false
+func (s *MigrationService) repointItem(item *migrationItem, dest *model.Storage, size int64, checksum string, verifiedAt time.Time) bool {
+	if item.backupCopy != nil {
+		result := database.DB.Model(item.backupCopy).Updates(map[string]interface{}{
+			"storage_id":   dest.ID,
+			"storage_type": dest.Type,
+			"storage_name": dest.Name,
+			"file_size":    size,
+			"checksum":     checksum,
+			"verified_at":  verifiedAt,
+		})
+		return result.Error == nil && result.RowsAffected > 0
+	}
+
+	result := database.DB.Model(&item.backupLog).Updates(map[string]interface{}{
+		"storage_id":   dest.ID,
+		"storage_type": dest.Type,
+		"storage_name": dest.Name,
+	})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+	// 已提升为主存储的副本随备份日志一起迁移
+	database.DB.Model(&model.BackupCopy{}).
+		Where("backup_log_id = ? AND status = ?", item.backupLog.ID, "promoted").
+		Updates(map[string]interface{}{
+			"storage_id":   dest.ID,
+			"storage_type": dest.Type,
+			"storage_name": dest.Name,
+		})
+	return true
+}
+
+// finish 结束迁移任务，err不为nil时表示任务整体失败
+func (s *MigrationService) finish(job *model.MigrationJob, err error) {
+	endTime := time.Now()
+	job.EndTime = &endTime
+	job.CurrentFile = ""
+	switch {
+	case err != nil:
+		job.Status = "failed"
+		job.ErrorMessage = err.Error()
+	case job.Failed > 0:
+		job.Status = "partial"
+		job.ErrorMessage = fmt.Sprintf("%d of %d artifacts failed to migrate", job.Failed, job.Total)
+	default:
+		job.Status = "success"
+		job.Progress = 100
+	}
+	database.DB.Save(job)
+	log.Printf("Migration job %d finished: %s (%d migrated, %d skipped, %d failed)",
+		job.ID, job.Status, job.Completed, job.Skipped, job.Failed)
+}
+
+// stepLogger 返回将步骤日志追加到迁移任务的回调
+func (s *MigrationService) stepLogger(job *model.MigrationJob) func(message string) {
+	return func(message string) {
+		job.Steps += fmt.Sprintf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), message)
+		database.DB.Model(job).Update("steps", job.Steps)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// pitrPlan 时间点恢复计划
+type pitrPlan struct {
+	BaseLog    *model.BackupLog   // 作为起点的全量备份
+	Binlogs    []model.BinlogFile // 需要重放的binlog，按文件名排序
+	TargetTime *time.Time         // 目标时间
+	TargetGTID string             // 目标GTID
+}
+
+// parseTargetTime 解析目标时间，支持RFC3339和本地时间格式
+func parseTargetTime(value string) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target_time %q, expected RFC3339 or 2006-01-02 15:04:05", value)
+	}
+	return &t, nil
+}
+
+// planPointInTime 为任务选择基础备份和需要重放的binlog
+func planPointInTime(task *model.Task, targetTime *time.Time, targetGTID string) (*pitrPlan, error) {
+	plan := &pitrPlan{TargetTime: targetTime, TargetGTID: targetGTID}
+
+	baseLog, err := findBaseBackup(task, targetTime, targetGTID)
+	if err != nil {
+		return nil, err
+	}
+	plan.BaseLog = baseLog
+
+	// 同一主机可能有多个归档任务，按文件名去重
+	var files []model.BinlogFile
+	database.DB.Where("host_id = ? AND file_name >= ?", task.HostID, baseLog.BinlogFile).
+		Order("file_name ASC, id ASC").Find(&files)
+
+	var candidates []model.BinlogFile
+	for _, file := range files {
+		if len(candidates) > 0 && candidates[len(candidates)-1].FileName == file.FileName {
+			continue
+		}
+		candidates = append(candidates, file)
+	}
+
+	if len(candidates) == 0 || candidates[0].FileName != baseLog.BinlogFile {
+		return nil, fmt.Errorf("binlog %s (start of backup %d) has not been archived", baseLog.BinlogFile, baseLog.ID)
+	}
+
+	reached := false
+	for i, file := range candidates {
+		if i > 0 && !isNextBinlog(candidates[i-1].FileName, file.FileName) {
+			return nil, fmt.Errorf("archived binlogs have a gap between %s and %s", candidates[i-1].FileName, file.FileName)
+		}
+
+		if targetTime != nil {
+			if i > 0 && file.FirstEventTime != nil && file.FirstEventTime.After(*targetTime) {
+				reached = true
+				break
+			}
+			plan.Binlogs = append(plan.Binlogs, file)
+			if file.LastEventTime != nil && !file.LastEventTime.Before(*targetTime) {
+				reached = true
+				break
+			}
+			continue
+		}
+
+		plan.Binlogs = append(plan.Binlogs, file)
+		if backup.GTIDSetContains(file.GTIDs, targetGTID) {
+			reached = true
+			break
+		}
+	}
+
+	if !reached {
+		last := plan.Binlogs[len(plan.Binlogs)-1]
+		if targetTime != nil {
+			return nil, fmt.Errorf("target time is beyond archived binlogs (last archived: %s)", last.FileName)
+		}
+		return nil, fmt.Errorf("GTID %s not found in archived binlogs (last archived: %s)", targetGTID, last.FileName)
+	}
+
+	return plan, nil
+}
+
+// findBaseBackup 查找目标点之前最近一次记录了binlog坐标的成功备份
+func findBaseBackup(task *model.Task, targetTime *time.Time, targetGTID string) (*model.BackupLog, error) {
+	query := database.DB.Where("task_id = ? AND status = ? AND binlog_file <> ''", task.ID, "success")
+
+	if targetTime != nil {
+		var baseLog model.BackupLog
+		if err := query.Where("end_time <= ?", *targetTime).Order("start_time DESC").First(&baseLog).Error; err != nil {
+			return nil, fmt.Errorf("no backup with binlog coordinates found before %s", targetTime.Format("2006-01-02 15:04:05"))
+		}
+		return &baseLog, nil
+	}
+
+	var logs []model.BackupLog
+	query.Where("gtid_executed <> ''").Order("start_time DESC").Find(&logs)
+	for i := range logs {
+		if !backup.GTIDSetContains(logs[i].GTIDExecuted, targetGTID) {
+			return &logs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no backup with GTID coordinates found before %s", targetGTID)
+}
+
+// isNextBinlog 判断next是否为prev的下一个binlog文件（mysql-bin.000001 -> mysql-bin.000002）
+func isNextBinlog(prev, next string) bool {
+	prevIdx := strings.LastIndex(prev, ".")
+	nextIdx := strings.LastIndex(next, ".")
+	if prevIdx < 0 || nextIdx < 0 || prev[:prevIdx] != next[:nextIdx] {
+		return false
+	}
+
+	prevSeq, err1 := strconv.Atoi(prev[prevIdx+1:])
+	nextSeq, err2 := strconv.Atoi(next[nextIdx+1:])
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	return nextSeq == prevSeq+1
+}
+
+// replayBinlogs 下载计划中的binlog并重放到目标主机
+func (s *RestoreService) replayBinlogs(ctx context.Context, restoreLog *model.RestoreLog, plan *pitrPlan, host *model.Host, req *RestoreRequest, workDir string) error {
+	step := s.stepLogger(restoreLog)
+
+	binlogDir := filepath.Join(workDir, "binlog")
+	if err := os.MkdirAll(binlogDir, 0755); err != nil {
+		return fmt.Errorf("failed to create binlog directory: %w", err)
+	}
+
+	// 下载binlog，不同文件可能在不同存储中
+	storages := make(map[uint]*model.Storage)
+	var localFiles, names []string
+	for _, file := range plan.Binlogs {
+		storageConfig, ok := storages[file.StorageID]
+		if !ok {
+			storageConfig = &model.Storage{}
+			if err := database.DB.First(storageConfig, file.StorageID).Error; err != nil {
+				return fmt.Errorf("storage of binlog %s not found: %w", file.FileName, err)
+			}
+			storages[file.StorageID] = storageConfig
+		}
+
+		storageInstance, err := newStorageInstance(storageConfig)
+		if err != nil {
+			return err
+		}
+
+		localPath := filepath.Join(binlogDir, file.FileName)
+		if err := storageInstance.Download(ctx, file.FilePath, localPath); err != nil {
+			return fmt.Errorf("failed to download binlog %s: %w", file.FileName, err)
+		}
+		localFiles = append(localFiles, localPath)
+		names = append(names, file.FileName)
+	}
+	step(fmt.Sprintf("Downloaded %d binlog files: %s", len(names), strings.Join(names, ", ")))
+
+	namesJSON, _ := json.Marshal(names)
+	restoreLog.BinlogFiles = string(namesJSON)
+
+	params := &backup.BinlogReplayParams{
+		Host:          host.Host,
+		Port:          host.Port,
+		Username:      host.Username,
+		Password:      host.Password,
+		Files:         localFiles,
+		StartPosition: plan.BaseLog.BinlogPosition,
+	}
+
+	if plan.TargetTime != nil {
+		params.StopDatetime = plan.TargetTime.Local().Format("2006-01-02 15:04:05")
+	} else {
+		stopPosition, err := backup.FindGTIDStopPosition(ctx, localFiles[len(localFiles)-1], plan.TargetGTID)
+		if err != nil {
+			return err
+		}
+		params.StopPosition = stopPosition
+	}
+
+	// 恢复到其他库名时，只重放该库并重写库名
+	if req.TargetDatabase != "" {
+		var databases []string
+		json.Unmarshal([]byte(plan.BaseLog.Databases), &databases)
+		if len(databases) == 1 && databases[0] != req.TargetDatabase {
+			params.RewriteDB = fmt.Sprintf("%s->%s", databases[0], req.TargetDatabase)
+		}
+		params.Database = req.TargetDatabase
+	}
+
+	replayStartTime := time.Now()
+	command, err := backup.ReplayBinlogs(ctx, params)
+	restoreLog.ReplayTime = int(time.Since(replayStartTime).Seconds())
+	step("Replay binlogs: " + command)
+	if err != nil {
+		return fmt.Errorf("binlog replay failed: %w", err)
+	}
+
+	if restoreLog.Command != "" {
+		restoreLog.Command += "\n"
+	}
+	restoreLog.Command += command
+	return nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"sync"
+	"time"
+)
+
+// progressHistorySize 估算预期数据量和耗时时参考的最近成功备份数
+const progressHistorySize = 5
+
+// BackupProgressState 备份进度快照，包含根据历史备份估算的百分比和剩余时间
+type BackupProgressState struct {
+	BackupLogID uint   `json:"backup_log_id"`
+	TaskID      uint   `json:"task_id"`
+	Status      string `json:"status"` // running, success, failed
+	backup.BackupProgress
+	ExpectedBytes  int64     `json:"expected_bytes"`  // 历史备份的平均数据量（压缩前），0表示没有历史
+	Percent        float64   `json:"percent"`         // 完成百分比，-1表示无法估算
+	ElapsedSeconds int       `json:"elapsed_seconds"` // 已运行时间
+	ETASeconds     int       `json:"eta_seconds"`     // 预计剩余时间，-1表示无法估算
+	StartedAt      time.Time `json:"started_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// backupProgressTracker 跟踪一次运行中的备份进度并推送给订阅者
+type backupProgressTracker struct {
+	state           BackupProgressState
+	expectedSeconds int // 历史备份的平均耗时
+	subscribers     map[chan BackupProgressState]struct{}
+}
+
+var (
+	progressMu       sync.Mutex
+	progressTrackers = make(map[uint]*backupProgressTracker) // 备份日志ID -> 进度
+)
+
+// startBackupProgress 开始跟踪备份进度，根据同一任务最近的成功备份估算数据量和耗时
+func startBackupProgress(backupLog *model.BackupLog) *backupProgressTracker {
+	var history []model.BackupLog
+	database.DB.Where("task_id = ? AND status = ?", backupLog.TaskID, "success").
+		Order("start_time DESC").Limit(progressHistorySize).Find(&history)
+
+	var totalBytes int64
+	var totalSeconds, bytesCount, secondsCount int
+	for _, h := range history {
+		if h.RawSize > 0 {
+			totalBytes += h.RawSize
+			bytesCount++
+		}
+		if h.Duration > 0 {
+			totalSeconds += h.Duration
+			secondsCount++
+		}
+	}
+
+	t := &backupProgressTracker{
+		state: BackupProgressState{
+			BackupLogID:    backupLog.ID,
+			TaskID:         backupLog.TaskID,
+			Status:         "running",
+			BackupProgress: backup.BackupProgress{Phase: "preparing"},
+			Percent:        -1,
+			ETASeconds:     -1,
+			StartedAt:      backupLog.StartTime,
+			UpdatedAt:      time.Now(),
+		},
+		subscribers: make(map[chan BackupProgressState]struct{}),
+	}
+	if bytesCount > 0 {
+		t.state.ExpectedBytes = totalBytes / int64(bytesCount)
+	}
+	if secondsCount > 0 {
+		t.expectedSeconds = totalSeconds / secondsCount
+	}
+
+	progressMu.Lock()
+	progressTrackers[backupLog.ID] = t
+	progressMu.Unlock()
+	return t
+}
+
+// update 记录执行器上报的进度
+func (t *backupProgressTracker) update(progress backup.BackupProgress) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	t.state.BackupProgress = progress
+	t.publishLocked()
+}
+
+// setPhase 切换阶段（加密、上传等由服务执行的步骤），保留已上报的其他进度
+func (t *backupProgressTracker) setPhase(phase string) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	t.state.Phase = phase
+	t.publishLocked()
+}
+
+// finish 结束跟踪，向订阅者推送最终状态后关闭订阅
+func (t *backupProgressTracker) finish(status string) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	t.state.Status = status
+	t.publishLocked()
+	if status == "success" {
+		t.state.Percent = 100
+		t.state.ETASeconds = 0
+	}
+	for ch := range t.subscribers {
+		sendLatest(ch, t.state)
+		close(ch)
+	}
+	t.subscribers = nil
+	delete(progressTrackers, t.state.BackupLogID)
+}
+
+// publishLocked 重新估算百分比和剩余时间并推送给订阅者，调用方需持有progressMu
+func (t *backupProgressTracker) publishLocked() {
+	now := time.Now()
+	t.state.UpdatedAt = now
+	elapsed := now.Sub(t.state.StartedAt).Seconds()
+	t.state.ElapsedSeconds = int(elapsed)
+
+	// 优先按数据量估算，没有历史数据时按表数量估算；运行中最多显示99%
+	fraction := -1.0
+	switch {
+	case t.state.ExpectedBytes > 0:
+		fraction = float64(t.state.BytesDone) / float64(t.state.ExpectedBytes)
+	case t.state.TablesTotal > 0:
+		fraction = float64(t.state.TablesDone) / float64(t.state.TablesTotal)
+	}
+	if fraction >= 0 {
+		if fraction > 0.99 {
+			fraction = 0.99
+		}
+		t.state.Percent = float64(int(fraction*1000)) / 10
+	}
+
+	t.state.ETASeconds = -1
+	switch {
+	case fraction >= 0.01 && t.state.Phase == "dumping":
+		t.state.ETASeconds = int(elapsed/fraction - elapsed)
+	case t.expectedSeconds > 0:
+		t.state.ETASeconds = t.expectedSeconds - t.state.ElapsedSeconds
+		if t.state.ETASeconds < 0 {
+			t.state.ETASeconds = 0
+		}
+	}
+
+	for ch := range t.subscribers {
+		sendLatest(ch, t.state)
+	}
+}
+
+// sendLatest 非阻塞地推送进度，订阅者来不及读取时丢弃旧的进度
+func sendLatest(ch chan BackupProgressState, state BackupProgressState) {
+	select {
+	case <-ch:
+	default:
+	}
+	select {
+	case ch <- state:
+	default:
+	}
+}
+
+// GetBackupProgress 获取运行中备份的进度，备份不在运行时返回false
+func GetBackupProgress(backupLogID uint) (BackupProgressState, bool) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	t, ok := progressTrackers[backupLogID]
+	if !ok {
+		return BackupProgressState{}, false
+	}
+	return t.state, true
+}
+
+// SubscribeBackupProgress 订阅运行中备份的进度，备份结束后通道被关闭；
+// 备份不在运行时返回false。调用方不再读取时需调用返回的取消函数
+func SubscribeBackupProgress(backupLogID uint) (<-chan BackupProgressState, func(), bool) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+
+	t, ok := progressTrackers[backupLogID]
+	if !ok {
+		return nil, nil, false
+	}
+	ch := make(chan BackupProgressState, 1)
+	ch <- t.state
+	t.subscribers[ch] = struct{}{}
+
+	cancel := func() {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+		if _, ok := t.subscribers[ch]; ok {
+			delete(t.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel, true
+}
+
+// FinishedBackupProgress 根据已结束的备份日志构造最终进度。日志仍为running但没有进度时
+// （例如服务重启前中断的备份），阶段为unknown
+func FinishedBackupProgress(backupLog *model.BackupLog) BackupProgressState {
+	state := BackupProgressState{
+		BackupLogID:    backupLog.ID,
+		TaskID:         backupLog.TaskID,
+		Status:         backupLog.Status,
+		BackupProgress: backup.BackupProgress{Phase: "finished", BytesDone: backupLog.RawSize},
+		Percent:        -1,
+		ElapsedSeconds: backupLog.Duration,
+		ETASeconds:     0,
+		StartedAt:      backupLog.StartTime,
+		UpdatedAt:      backupLog.StartTime,
+	}
+	if backupLog.EndTime != nil {
+		state.UpdatedAt = *backupLog.EndTime
+	}
+	switch backupLog.Status {
+	case "success":
+		state.Percent = 100
+	case "running":
+		state.Phase = "unknown"
+		state.ETASeconds = -1
+	}
+	return state
+}
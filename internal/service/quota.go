@@ -0,0 +1,383 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"mbmanager/internal/notification"
+	"sort"
+	"sync"
+	"time"
+)
+
+// StorageUsage 存储的容量使用情况
+type StorageUsage struct {
+	UsedBytes   int64   `json:"used_bytes"`   // 存储中的文件总量
+	TotalBytes  uint64  `json:"total_bytes"`  // 磁盘总容量，不支持时为0
+	FreeBytes   uint64  `json:"free_bytes"`   // 磁盘剩余空间
+	FreePercent float64 `json:"free_percent"` // 剩余空间百分比，-1表示不支持
+}
+
+// QuotaResult 一次容量检查的结果
+type QuotaResult struct {
+	StorageID      uint          `json:"storage_id"`
+	StorageName    string        `json:"storage_name"`
+	QuotaBytes     int64         `json:"quota_bytes"`
+	MinFreePercent int           `json:"min_free_percent"`
+	Before         StorageUsage  `json:"before"`         // 清理前
+	After          *StorageUsage `json:"after"`          // 清理后，未清理时为nil
+	ExcessBytes    int64         `json:"excess_bytes"`   // 检查时超出配额或剩余空间下限的字节数
+	PrunedBackups  []uint        `json:"pruned_backups"` // 删除的备份日志ID（有副本的备份改由副本保留）
+	PrunedCopies   []uint        `json:"pruned_copies"`  // 删除的副本ID
+	FreedBytes     int64         `json:"freed_bytes"`
+	Status         string        `json:"status"` // ok, pruned（超出后已清理）, exceeded（清理后仍超出）
+}
+
+// diskSpaceStorage 能获取磁盘空间的存储（本地、NAS和SSH）
+type diskSpaceStorage interface {
+	GetDiskSpace(ctx context.Context) (total, used, free uint64, err error)
+}
+
+// quotaLocks 存储ID -> 容量检查锁，避免多个备份同时清理同一存储
+var quotaLocks sync.Map
+
+// quotaCandidate 容量清理的候选产物，backupCopy为nil时是备份日志的主存储产物
+type quotaCandidate struct {
+	time       time.Time
+	size       int64
+	backupLog  *model.BackupLog
+	backupCopy *model.BackupCopy
+	done       bool
+}
+
+// MeasureStorageUsage 统计存储中的文件总量，本地、NAS和SSH存储同时获取磁盘剩余空间
+func MeasureStorageUsage(ctx context.Context, storageModel *model.Storage) (*StorageUsage, error) {
+	storageInstance, err := newStorageInstance(storageModel)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := storageInstance.List(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	usage := &StorageUsage{FreePercent: -1}
+	for _, f := range files {
+		usage.UsedBytes += f.Size
+	}
+
+	if disk, ok := storageInstance.(diskSpaceStorage); ok {
+		total, _, free, err := disk.GetDiskSpace(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get disk space: %w", err)
+		}
+		usage.TotalBytes = total
+		usage.FreeBytes = free
+		if total > 0 {
+			usage.FreePercent = float64(free) / float64(total) * 100
+		}
+	}
+	return usage, nil
+}
+
+// ValidateStorageQuota 检查存储的容量限制配置
+func ValidateStorageQuota(storageModel *model.Storage) error {
+	if storageModel.QuotaBytes < 0 {
+		return fmt.Errorf("quota_bytes must not be negative")
+	}
+	if storageModel.MinFreePercent < 0 || storageModel.MinFreePercent >= 100 {
+		return fmt.Errorf("min_free_percent must be between 0 and 99")
+	}
+	if storageModel.NotificationIDs != "" {
+		var ids []int
+		if err := json.Unmarshal([]byte(storageModel.NotificationIDs), &ids); err != nil {
+			return fmt.Errorf("invalid notification_ids: %w", err)
+		}
+	}
+	return nil
+}
+
+// StorageQuotaExcess 返回存储超出容量配额或剩余空间下限的字节数，未超出时为0
+func StorageQuotaExcess(storageModel *model.Storage, usage *StorageUsage) int64 {
+	return quotaExcess(storageModel, usage)
+}
+
+// quotaExcess 返回超出容量配额或剩余空间下限的字节数，未超出时为0
+func quotaExcess(storageModel *model.Storage, usage *StorageUsage) int64 {
+	var excess int64
+	if storageModel.QuotaBytes > 0 && usage.UsedBytes > storageModel.QuotaBytes {
+		excess = usage.UsedBytes - storageModel.QuotaBytes
+	}
+	if storageModel.MinFreePercent > 0 && usage.FreePercent >= 0 && usage.FreePercent < float64(storageModel.MinFreePercent) {
+		need := int64(float64(usage.TotalBytes)*float64(storageModel.MinFreePercent)/100) - int64(usage.FreeBytes)
+		if need > excess {
+			excess = need
+		}
+	}
+	return excess
+}
+
+// EnforceStorageQuota 检查存储的容量，超出配额或剩余空间下限时跨任务从最旧的备份开始清理，
+// 直到回到限制之内；各任务最近的min_keep个成功备份及其依赖的备份不清理。超出时发送告警
+func EnforceStorageQuota(ctx context.Context, storageID uint) (*QuotaResult, error) {
+	lock, _ := quotaLocks.LoadOrStore(storageID, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, storageID).Error; err != nil {
+		return nil, fmt.Errorf("storage not found")
+	}
+
+	usage, err := MeasureStorageUsage(ctx, &storageModel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure usage of storage %s: %w", storageModel.Name, err)
+	}
+
+	result := &QuotaResult{
+		StorageID:      storageModel.ID,
+		StorageName:    storageModel.Name,
+		QuotaBytes:     storageModel.QuotaBytes,
+		MinFreePercent: storageModel.MinFreePercent,
+		Before:         *usage,
+		ExcessBytes:    quotaExcess(&storageModel, usage),
+		PrunedBackups:  []uint{},
+		PrunedCopies:   []uint{},
+		Status:         "ok",
+	}
+
+	if result.ExcessBytes > 0 {
+		pruneForQuota(&storageModel, result)
+
+		after, err := MeasureStorageUsage(ctx, &storageModel)
+		if err != nil {
+			log.Printf("Failed to measure usage of storage %s after pruning: %v", storageModel.Name, err)
+			after = &StorageUsage{UsedBytes: usage.UsedBytes - result.FreedBytes, FreePercent: -1}
+		}
+		result.After = after
+		usage = after
+		result.Status = "pruned"
+		if quotaExcess(&storageModel, after) > 0 {
+			result.Status = "exceeded"
+		}
+	}
+
+	// 状态变为超出时告警，持续超出时不重复告警
+	if result.Status != "ok" && result.Status != storageModel.QuotaStatus {
+		sendQuotaAlert(&storageModel, result)
+	}
+
+	now := time.Now()
+	database.DB.Model(&storageModel).Updates(map[string]interface{}{
+		"quota_status":     result.Status,
+		"used_bytes":       usage.UsedBytes,
+		"free_percent":     usage.FreePercent,
+		"quota_checked_at": now,
+	})
+	return result, nil
+}
+
+// pruneForQuota 从最旧的产物开始清理，直到释放的空间达到超出量
+func pruneForQuota(storageModel *model.Storage, result *QuotaResult) {
+	candidates := quotaCandidates(storageModel)
+
+	// 增量/差异备份依赖的备份要等依赖它的备份清理后才能清理，因此多轮进行直到没有可清理的产物
+	for result.FreedBytes < result.ExcessBytes {
+		progress := false
+		for i := range candidates {
+			c := &candidates[i]
+			if c.done || result.FreedBytes >= result.ExcessBytes {
+				continue
+			}
+
+			if c.backupCopy != nil {
+				if err := deleteBackupCopy(c.backupCopy); err != nil {
+					log.Printf("Failed to delete copy %d for quota of storage %s: %v", c.backupCopy.ID, storageModel.Name, err)
+					c.done = true
+					continue
+				}
+				result.PrunedCopies = append(result.PrunedCopies, c.backupCopy.ID)
+			} else {
+				if HasDependentBackups(c.backupLog.ID) {
+					continue
+				}
+				if _, err := pruneBackup(c.backupLog); err != nil {
+					log.Printf("Failed to prune backup %d for quota of storage %s: %v", c.backupLog.ID, storageModel.Name, err)
+					c.done = true
+					continue
+				}
+				result.PrunedBackups = append(result.PrunedBackups, c.backupLog.ID)
+			}
+			c.done = true
+			result.FreedBytes += c.size
+			progress = true
+		}
+		if !progress {
+			break
+		}
+	}
+
+	if len(result.PrunedBackups)+len(result.PrunedCopies) > 0 {
+		log.Printf("Pruned %d backups and %d copies (%d bytes) for quota of storage %s",
+			len(result.PrunedBackups), len(result.PrunedCopies), result.FreedBytes, storageModel.Name)
+	}
+}
+
+// quotaCandidates 返回存储中可清理的产物，按时间从旧到新排序：主存储在该存储的成功备份
+// （不含各任务最近的min_keep个），以及该存储中的副本
+func quotaCandidates(storageModel *model.Storage) []quotaCandidate {
+	var logs []model.BackupLog
+	database.DB.Where("status = ? AND file_path <> '' AND (storage_id = ? OR (storage_id = 0 AND task_id IN (?)))",
+		"success", storageModel.ID, database.DB.Model(&model.Task{}).Select("id").Where("storage_id = ?", storageModel.ID)).
+		Find(&logs)
+
+	var copies []model.BackupCopy
+	database.DB.Where("storage_id = ? AND status = ?", storageModel.ID, "success").Find(&copies)
+
+	// 每个任务最近的min_keep个成功备份不清理
+	kept := make(map[uint]bool)
+	checked := make(map[uint]bool)
+	for _, l := range logs {
+		if checked[l.TaskID] {
+			continue
+		}
+		checked[l.TaskID] = true
+
+		var task model.Task
+		if err := database.DB.First(&task, l.TaskID).Error; err != nil || task.MinKeep <= 0 {
+			continue
+		}
+		var ids []uint
+		database.DB.Model(&model.BackupLog{}).Where("task_id = ? AND status = ?", task.ID, "success").
+			Order("start_time DESC").Limit(task.MinKeep).Pluck("id", &ids)
+		for _, id := range ids {
+			kept[id] = true
+		}
+	}
+
+	var candidates []quotaCandidate
+	for i := range logs {
+		if kept[logs[i].ID] {
+			continue
+		}
+		candidates = append(candidates, quotaCandidate{time: logs[i].StartTime, size: logs[i].FileSize, backupLog: &logs[i]})
+	}
+	for i := range copies {
+		candidates = append(candidates, quotaCandidate{time: copies[i].CreatedAt, size: copies[i].FileSize, backupCopy: &copies[i]})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].time.Before(candidates[j].time)
+	})
+	return candidates
+}
+
+// enforceTaskQuotas 检查任务主存储和副本存储的容量
+func enforceTaskQuotas(ctx context.Context, task *model.Task) {
+	storageIDs := []uint{task.StorageID}
+	if copies, err := parseCopyStorages(task); err == nil {
+		for _, c := range copies {
+			storageIDs = append(storageIDs, c.StorageID)
+		}
+	}
+
+	for _, storageID := range storageIDs {
+		var storageModel model.Storage
+		if err := database.DB.First(&storageModel, storageID).Error; err != nil {
+			continue
+		}
+		if storageModel.QuotaBytes <= 0 && storageModel.MinFreePercent <= 0 {
+			continue
+		}
+		if _, err := EnforceStorageQuota(ctx, storageID); err != nil {
+			log.Printf("Failed to enforce quota of storage %s: %v", storageModel.Name, err)
+		}
+	}
+}
+
+// EnforceAllStorageQuotas 检查所有配置了容量限制的存储
+func EnforceAllStorageQuotas(ctx context.Context) {
+	var storages []model.Storage
+	database.DB.Where("quota_bytes > 0 OR min_free_percent > 0").Find(&storages)
+	for _, storageModel := range storages {
+		if _, err := EnforceStorageQuota(ctx, storageModel.ID); err != nil {
+			log.Printf("Failed to enforce quota of storage %s: %v", storageModel.Name, err)
+		}
+	}
+}
+
+// sendQuotaAlert 发送容量告警，存储未配置通知渠道时发送到以该存储为主存储的任务的通知渠道
+func sendQuotaAlert(storageModel *model.Storage, result *QuotaResult) {
+	var notificationIDs []int
+	if storageModel.NotificationIDs != "" {
+		if err := json.Unmarshal([]byte(storageModel.NotificationIDs), &notificationIDs); err != nil {
+			log.Printf("Failed to parse notification IDs of storage %s: %v", storageModel.Name, err)
+		}
+	} else {
+		var tasks []model.Task
+		database.DB.Where("storage_id = ? AND notification_ids <> ''", storageModel.ID).Find(&tasks)
+		seen := make(map[int]bool)
+		for _, task := range tasks {
+			var ids []int
+			json.Unmarshal([]byte(task.NotificationIDs), &ids)
+			for _, id := range ids {
+				if !seen[id] {
+					seen[id] = true
+					notificationIDs = append(notificationIDs, id)
+				}
+			}
+		}
+	}
+
+	level := notification.LevelWarning
+	title := fmt.Sprintf("[告警] 存储 %s 超出容量限制，已清理旧备份", storageModel.Name)
+	if result.Status == "exceeded" {
+		level = notification.LevelError
+		title = fmt.Sprintf("[告警] 存储 %s 超出容量限制，清理后仍然超出", storageModel.Name)
+	}
+
+	content := fmt.Sprintf(`
+存储名称：%s
+容量配额：%d MB
+剩余空间下限：%d%%
+清理前文件总量：%d MB
+超出：%d MB
+清理备份：%d 个
+清理副本：%d 个
+释放空间：%d MB
+`,
+		storageModel.Name,
+		storageModel.QuotaBytes/1024/1024,
+		storageModel.MinFreePercent,
+		result.Before.UsedBytes/1024/1024,
+		result.ExcessBytes/1024/1024,
+		len(result.PrunedBackups),
+		len(result.PrunedCopies),
+		result.FreedBytes/1024/1024,
+	)
+	if result.Before.FreePercent >= 0 {
+		content += fmt.Sprintf("清理前剩余空间：%.1f%%\n", result.Before.FreePercent)
+	}
+	if result.After != nil && result.After.FreePercent >= 0 {
+		content += fmt.Sprintf("清理后剩余空间：%.1f%%\n", result.After.FreePercent)
+	}
+	if result.Status == "exceeded" {
+		content += "\n各任务最少保留的备份不会被清理，请扩容存储或调整保留策略，否则后续备份可能失败"
+	}
+
+	log.Printf("Storage %s over quota: %s", storageModel.Name, result.Status)
+	if len(notificationIDs) == 0 {
+		return
+	}
+	sendToChannels(notificationIDs, &notification.Message{
+		Title:   title,
+		Content: content,
+		Level:   level,
+		Extra: map[string]interface{}{
+			"storage_name": storageModel.Name,
+			"status":       result.Status,
+		},
+	})
+}
@@ -0,0 +1,696 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"mbmanager/internal/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reconcileGracePeriod 修改时间在此期间内的未登记文件可能是正在上传的备份，不视为孤立文件
+const reconcileGracePeriod = time.Hour
+
+// 对账发现的不一致类型
+const (
+	findingOrphan       = "orphan"        // 存储中有但目录中没有的文件
+	findingMissing      = "missing"       // 目录中有但存储中没有的产物
+	findingSizeMismatch = "size_mismatch" // 大小与目录记录不一致的产物
+)
+
+// 不一致的处理方式
+const (
+	reconcileActionAdopt  = "adopt"  // 将孤立文件登记为备份
+	reconcileActionMark   = "mark"   // 将丢失或大小不一致的产物标记为不可用
+	reconcileActionDelete = "delete" // 删除孤立文件，或删除有问题的产物及其目录记录
+	reconcileActionIgnore = "ignore"
+)
+
+// reconcileResolutions 处理方式对应的处理结果
+var reconcileResolutions = map[string]string{
+	reconcileActionAdopt:  "adopted",
+	reconcileActionMark:   "marked",
+	reconcileActionDelete: "deleted",
+	reconcileActionIgnore: "ignored",
+}
+
+// ReconcileService 存储对账服务
+type ReconcileService struct{}
+
+// NewReconcileService 创建存储对账服务实例
+func NewReconcileService() *ReconcileService {
+	return &ReconcileService{}
+}
+
+// ReconcileResolveRequest 处理对账发现的请求
+type ReconcileResolveRequest struct {
+	FindingIDs []uint `json:"finding_ids" binding:"required"`
+	Action     string `json:"action" binding:"required"` // adopt, mark, delete, ignore
+	TaskID     uint   `json:"task_id"`                   // 领养没有元数据清单的孤立文件时归属的任务
+}
+
+// ReconcileResolveResult 单个对账发现的处理结果
+type ReconcileResolveResult struct {
+	FindingID   uint   `json:"finding_id"`
+	Success     bool   `json:"success"`
+	BackupLogID uint   `json:"backup_log_id,omitempty"` // 领养后创建的备份日志
+	Error       string `json:"error,omitempty"`
+}
+
+// catalogEntry 目录中引用存储文件的一条记录
+type catalogEntry struct {
+	entryType    string // backup, copy, binlog
+	backupLogID  uint
+	backupCopyID uint
+	binlogFileID uint
+	filePath     string
+	fileSize     int64
+	checked      bool // 是否检查丢失和大小，失败的备份和副本不保证存储中有文件
+}
+
+// reconcilingStorages 正在对账的存储，同一存储同时只允许一个对账任务
+var (
+	reconcilingMu       sync.Mutex
+	reconcilingStorages = make(map[uint]uint) // 存储ID -> 对账任务ID
+)
+
+// reconcileKey 统一存储路径的分隔符，用于比较列出的文件与目录记录
+func reconcileKey(p string) string {
+	return strings.TrimPrefix(filepath.ToSlash(p), "/")
+}
+
+// sidecarBase 文件是校验和文件或元数据清单时返回其所属的备份文件路径
+func sidecarBase(key string) (string, bool) {
+	for _, ext := range []string{backup.ChecksumExtension, backup.ManifestExtension} {
+		if strings.HasSuffix(key, ext) {
+			return strings.TrimSuffix(key, ext), true
+		}
+	}
+	return "", false
+}
+
+// StartReconcile 创建存储的对账任务并在后台执行
+func (s *ReconcileService) StartReconcile(storageID uint, createdBy string) (*model.ReconcileJob, error) {
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, storageID).Error; err != nil {
+		return nil, fmt.Errorf("storage not found")
+	}
+
+	job, err := s.createJob(&storageModel, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	go s.executeReconcile(context.Background(), job, &storageModel)
+	return job, nil
+}
+
+// ReconcileAll 依次对账所有启用的存储，由定时任务调用
+func (s *ReconcileService) ReconcileAll(ctx context.Context) {
+	var storages []model.Storage
+	if err := database.DB.Where("status = ?", 1).Find(&storages).Error; err != nil {
+		log.Printf("Failed to load storages for reconcile: %v", err)
+		return
+	}
+
+	for i := range storages {
+		if ctx.Err() != nil {
+			return
+		}
+		job, err := s.createJob(&storages[i], "system")
+		if err != nil {
+			log.Printf("Skipped reconcile of storage %s: %v", storages[i].Name, err)
+			continue
+		}
+		s.executeReconcile(ctx, job, &storages[i])
+	}
+}
+
+// createJob 登记对账任务。迁移会改写目录中的存储引用，迁移中的存储不对账
+func (s *ReconcileService) createJob(storageModel *model.Storage, createdBy string) (*model.ReconcileJob, error) {
+	// 与StartMigration相同的加锁顺序
+	migratingMu.Lock()
+	defer migratingMu.Unlock()
+	if migrationID, ok := migratingStorages[storageModel.ID]; ok {
+		return nil, fmt.Errorf("storage %s is being migrated by job %d", storageModel.Name, migrationID)
+	}
+
+	reconcilingMu.Lock()
+	defer reconcilingMu.Unlock()
+	if jobID, ok := reconcilingStorages[storageModel.ID]; ok {
+		return nil, fmt.Errorf("storage %s is being reconciled by job %d", storageModel.Name, jobID)
+	}
+
+	job := &model.ReconcileJob{
+		StorageID:   storageModel.ID,
+		StorageName: storageModel.Name,
+		Status:      "running",
+		CreatedBy:   createdBy,
+		StartTime:   time.Now(),
+	}
+	if err := database.DB.Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create reconcile job: %w", err)
+	}
+	reconcilingStorages[storageModel.ID] = job.ID
+	return job, nil
+}
+
+// executeReconcile 列出存储中的文件并与目录比较，记录孤立文件、丢失的产物和大小不一致的产物
+func (s *ReconcileService) executeReconcile(ctx context.Context, job *model.ReconcileJob, storageModel *model.Storage) {
+	defer func() {
+		reconcilingMu.Lock()
+		delete(reconcilingStorages, storageModel.ID)
+		reconcilingMu.Unlock()
+	}()
+
+	stepLog := s.stepLogger(job)
+
+	storageInstance, err := newStorageInstance(storageModel)
+	if err != nil {
+		s.finish(job, err)
+		return
+	}
+
+	// 先加载目录再列出文件：列出期间完成的备份只会表现为宽限期内的未登记文件，不会被误报为丢失
+	entries := loadCatalog(storageModel.ID)
+	job.TotalEntries = len(entries)
+	files, err := storageInstance.List(ctx, "")
+	if err != nil {
+		s.finish(job, fmt.Errorf("failed to list storage: %w", err))
+		return
+	}
+	job.TotalFiles = len(files)
+	stepLog(fmt.Sprintf("Listed %d files in %s, %d catalog entries reference it", job.TotalFiles, storageModel.Name, job.TotalEntries))
+
+	listed := make(map[string]storage.FileInfo, len(files))
+	for _, f := range files {
+		listed[reconcileKey(f.Path)] = f
+	}
+
+	var findings []model.ReconcileFinding
+	known := make(map[string]bool, len(entries)*3)
+	for _, entry := range entries {
+		key := reconcileKey(entry.filePath)
+		known[key] = true
+		known[key+backup.ChecksumExtension] = true
+		known[key+backup.ManifestExtension] = true
+		if !entry.checked {
+			continue
+		}
+
+		f, ok := listed[key]
+		if !ok {
+			// 对象存储的列表可能滞后，逐个确认后再记为丢失
+			exists, err := storageInstance.Exists(ctx, entry.filePath)
+			if err != nil {
+				stepLog(fmt.Sprintf("Failed to check %s: %v", entry.filePath, err))
+				continue
+			}
+			if exists {
+				job.Matched++
+				continue
+			}
+			job.Missing++
+			findings = append(findings, entry.finding(job, findingMissing, nil))
+			continue
+		}
+		if entry.fileSize > 0 && f.Size != entry.fileSize {
+			job.Mismatched++
+			findings = append(findings, entry.finding(job, findingSizeMismatch, &f))
+			continue
+		}
+		job.Matched++
+	}
+
+	now := time.Now()
+	for _, f := range files {
+		key := reconcileKey(f.Path)
+		if known[key] {
+			continue
+		}
+		// 孤立备份文件的校验和文件和元数据清单随备份文件一起处理
+		if base, ok := sidecarBase(key); ok {
+			if _, listedBase := listed[base]; listedBase {
+				continue
+			}
+		}
+		if now.Sub(f.ModifiedTime) < reconcileGracePeriod {
+			job.Skipped++
+			continue
+		}
+
+		modifiedTime := f.ModifiedTime
+		_, hasManifest := listed[key+backup.ManifestExtension]
+		job.Orphans++
+		findings = append(findings, model.ReconcileFinding{
+			JobID:        job.ID,
+			StorageID:    job.StorageID,
+			Kind:         findingOrphan,
+			FilePath:     f.Path,
+			FileSize:     f.Size,
+			ModifiedTime: &modifiedTime,
+			HasManifest:  hasManifest,
+			Resolution:   "pending",
+		})
+	}
+
+	if len(findings) > 0 {
+		if err := database.DB.CreateInBatches(findings, 100).Error; err != nil {
+			s.finish(job, fmt.Errorf("failed to save findings: %w", err))
+			return
+		}
+	}
+	stepLog(fmt.Sprintf("%d matched, %d orphans, %d missing, %d size mismatches, %d recent files skipped",
+		job.Matched, job.Orphans, job.Missing, job.Mismatched, job.Skipped))
+
+	s.finish(job, nil)
+}
+
+// loadCatalog 加载目录中引用存储文件的记录：主存储在该存储的备份、该存储中的副本和归档的binlog
+func loadCatalog(storageID uint) []catalogEntry {
+	var entries []catalogEntry
+
+	// 没有记录存储的旧备份使用任务的存储
+	var taskIDs []uint
+	database.DB.Model(&model.Task{}).Where("storage_id = ?", storageID).Pluck("id", &taskIDs)
+	var backupLogs []model.BackupLog
+	database.DB.Select("id", "file_path", "file_size", "status").
+		Where("file_path <> '' AND (storage_id = ? OR (storage_id = 0 AND task_id IN ?))", storageID, taskIDs).
+		Find(&backupLogs)
+	for _, l := range backupLogs {
+		entries = append(entries, catalogEntry{
+			entryType:   "backup",
+			backupLogID: l.ID,
+			filePath:    l.FilePath,
+			fileSize:    l.FileSize,
+			checked:     l.Status == "success",
+		})
+	}
+
+	// 提升为主存储的副本与备份日志指向同一文件，由备份日志检查
+	var copies []model.BackupCopy
+	database.DB.Where("storage_id = ? AND file_path <> ''", storageID).Find(&copies)
+	for _, c := range copies {
+		entries = append(entries, catalogEntry{
+			entryType:    "copy",
+			backupLogID:  c.BackupLogID,
+			backupCopyID: c.ID,
+			filePath:     c.FilePath,
+			fileSize:     c.FileSize,
+			checked:      c.Status == "success",
+		})
+	}
+
+	var binlogs []model.BinlogFile
+	database.DB.Where("storage_id = ? AND file_path <> ''", storageID).Find(&binlogs)
+	for _, b := range binlogs {
+		entries = append(entries, catalogEntry{
+			entryType:    "binlog",
+			binlogFileID: b.ID,
+			filePath:     b.FilePath,
+			fileSize:     b.FileSize,
+			checked:      true,
+		})
+	}
+	return entries
+}
+
+// finding 构造目录记录的不一致，f为nil表示文件丢失
+func (entry *catalogEntry) finding(job *model.ReconcileJob, kind string, f *storage.FileInfo) model.ReconcileFinding {
+	finding := model.ReconcileFinding{
+		JobID:        job.ID,
+		StorageID:    job.StorageID,
+		Kind:         kind,
+		EntryType:    entry.entryType,
+		BackupLogID:  entry.backupLogID,
+		BackupCopyID: entry.backupCopyID,
+		BinlogFileID: entry.binlogFileID,
+		FilePath:     entry.filePath,
+		CatalogSize:  entry.fileSize,
+		Resolution:   "pending",
+	}
+	if f != nil {
+		modifiedTime := f.ModifiedTime
+		finding.FileSize = f.Size
+		finding.ModifiedTime = &modifiedTime
+	}
+	return finding
+}
+
+// ResolveFindings 按指定方式处理对账任务的不一致，单个失败不影响其他
+func (s *ReconcileService) ResolveFindings(ctx context.Context, jobID uint, req *ReconcileResolveRequest, username string) ([]ReconcileResolveResult, error) {
+	resolution, ok := reconcileResolutions[req.Action]
+	if !ok {
+		return nil, fmt.Errorf("unsupported action: %s", req.Action)
+	}
+
+	var job model.ReconcileJob
+	if err := database.DB.First(&job, jobID).Error; err != nil {
+		return nil, fmt.Errorf("reconcile job not found")
+	}
+	if job.Status == "running" {
+		return nil, fmt.Errorf("reconcile job %d is still running", job.ID)
+	}
+	var findings []model.ReconcileFinding
+	database.DB.Where("job_id = ? AND id IN ?", job.ID, req.FindingIDs).Find(&findings)
+	if len(findings) == 0 {
+		return nil, fmt.Errorf("no findings selected")
+	}
+
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, job.StorageID).Error; err != nil {
+		return nil, fmt.Errorf("storage %d not found", job.StorageID)
+	}
+	storageInstance, err := newStorageInstance(&storageModel)
+	if err != nil {
+		return nil, err
+	}
+
+	// 对账之后目录可能已变化，处理孤立文件前按当前目录重新确认
+	known := make(map[string]bool)
+	for _, entry := range loadCatalog(storageModel.ID) {
+		known[reconcileKey(entry.filePath)] = true
+	}
+
+	results := make([]ReconcileResolveResult, 0, len(findings))
+	for i := range findings {
+		finding := &findings[i]
+		result := ReconcileResolveResult{FindingID: finding.ID}
+
+		var err error
+		switch {
+		case finding.Resolution != "pending":
+			err = fmt.Errorf("finding is already %s", finding.Resolution)
+		case req.Action == reconcileActionIgnore:
+		case finding.Kind == findingOrphan && known[reconcileKey(finding.FilePath)]:
+			err = fmt.Errorf("file is now referenced by the catalog")
+		case finding.Kind == findingOrphan && req.Action == reconcileActionAdopt:
+			result.BackupLogID, err = s.adoptOrphan(ctx, &storageModel, storageInstance, finding, req.TaskID)
+		case finding.Kind == findingOrphan && req.Action == reconcileActionDelete:
+			err = deleteFromStorage(&storageModel, finding.FilePath)
+		case finding.Kind == findingOrphan:
+			err = fmt.Errorf("orphan files can only be adopted, deleted or ignored")
+		case req.Action == reconcileActionMark:
+			err = s.markEntry(finding)
+		case req.Action == reconcileActionDelete:
+			err = s.deleteEntry(ctx, storageInstance, finding)
+		default:
+			err = fmt.Errorf("catalog entries can only be marked, deleted or ignored")
+		}
+
+		if err != nil {
+			result.Error = err.Error()
+			if finding.Resolution == "pending" {
+				database.DB.Model(finding).Update("error_message", result.Error)
+			}
+		} else {
+			result.Success = true
+			now := time.Now()
+			updates := map[string]interface{}{
+				"resolution":    resolution,
+				"resolved_by":   username,
+				"resolved_at":   &now,
+				"error_message": "",
+			}
+			if result.BackupLogID != 0 {
+				updates["backup_log_id"] = result.BackupLogID
+			}
+			database.DB.Model(finding).Updates(updates)
+			log.Printf("Reconcile finding %d (%s %s) %s by %s", finding.ID, finding.Kind, finding.FilePath, resolution, username)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// adoptOrphan 将孤立文件登记为成功的备份。文件旁有元数据清单时按清单恢复备份信息，
+// 否则按taskID指定的任务登记为全量备份；校验和取自存储中的校验和文件
+func (s *ReconcileService) adoptOrphan(ctx context.Context, storageModel *model.Storage, storageInstance storage.Storage, finding *model.ReconcileFinding, taskID uint) (uint, error) {
+	key := reconcileKey(finding.FilePath)
+	if _, ok := sidecarBase(key); ok {
+		return 0, fmt.Errorf("checksum and manifest files cannot be adopted on their own")
+	}
+	if strings.Contains("/"+key, "/binlog/") {
+		return 0, fmt.Errorf("binlog archives cannot be adopted as backups")
+	}
+
+	info, err := storageInstance.GetFileInfo(ctx, finding.FilePath)
+	if err != nil {
+		return 0, fmt.Errorf("file no longer exists in storage: %w", err)
+	}
+
+	tmpDir := filepath.Join("./data/tmp", fmt.Sprintf("reconcile_%d_%d", finding.ID, time.Now().UnixNano()))
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var manifest *backup.Manifest
+	manifestPath := filepath.Join(tmpDir, "manifest.json")
+	if storageInstance.Download(ctx, finding.FilePath+backup.ManifestExtension, manifestPath) == nil {
+		if content, err := os.ReadFile(manifestPath); err == nil {
+			manifest = &backup.Manifest{}
+			if err := json.Unmarshal(content, manifest); err != nil {
+				return 0, fmt.Errorf("invalid manifest: %w", err)
+			}
+		}
+	}
+	var checksum string
+	checksumPath := filepath.Join(tmpDir, "checksum")
+	if storageInstance.Download(ctx, finding.FilePath+backup.ChecksumExtension, checksumPath) == nil {
+		if content, err := os.ReadFile(checksumPath); err == nil {
+			checksum, _ = backup.ParseChecksumFile(string(content))
+		}
+	}
+
+	if taskID == 0 && manifest != nil {
+		taskID = manifest.TaskID
+	}
+	if taskID == 0 {
+		return 0, fmt.Errorf("task_id is required for files without a manifest")
+	}
+	var task model.Task
+	if err := database.DB.Preload("Host").First(&task, taskID).Error; err != nil {
+		return 0, fmt.Errorf("task %d not found, specify task_id", taskID)
+	}
+
+	endTime := info.ModifiedTime
+	backupLog := model.BackupLog{
+		TaskID:      task.ID,
+		TaskName:    task.Name,
+		Databases:   task.Databases,
+		BackupType:  task.BackupType,
+		RunID:       newRunID(),
+		Attempt:     1,
+		Status:      "success",
+		StartTime:   info.ModifiedTime,
+		EndTime:     &endTime,
+		FilePath:    finding.FilePath,
+		FileSize:    info.Size,
+		Checksum:    checksum,
+		StorageID:   storageModel.ID,
+		StorageType: storageModel.Type,
+		StorageName: storageModel.Name,
+		BackupMode:  "full",
+	}
+	if task.Host != nil {
+		backupLog.HostName = task.Host.Name
+	}
+	if manifest != nil {
+		if err := applyManifest(&backupLog, manifest); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := database.DB.Create(&backupLog).Error; err != nil {
+		return 0, fmt.Errorf("failed to create backup log: %w", err)
+	}
+	return backupLog.ID, nil
+}
+
+// applyManifest 按元数据清单恢复备份日志的信息。增量/差异备份依赖的备份必须已在目录中
+func applyManifest(backupLog *model.BackupLog, manifest *backup.Manifest) error {
+	if manifest.BackupMode != "" && manifest.BackupMode != "full" {
+		var parent model.BackupLog
+		if manifest.ParentLogID == 0 || database.DB.Where("id = ? AND status = ?", manifest.ParentLogID, "success").First(&parent).Error != nil {
+			return fmt.Errorf("parent backup %d of this %s backup is not in the catalog, adopt it first", manifest.ParentLogID, manifest.BackupMode)
+		}
+		backupLog.BackupMode = manifest.BackupMode
+		backupLog.ParentLogID = parent.ID
+	}
+
+	if manifest.HostName != "" {
+		backupLog.HostName = manifest.HostName
+	}
+	if manifest.BackupType != "" {
+		backupLog.BackupType = manifest.BackupType
+	}
+	if !manifest.StartTime.IsZero() {
+		backupLog.StartTime = manifest.StartTime
+	}
+	if !manifest.EndTime.IsZero() {
+		endTime := manifest.EndTime
+		backupLog.EndTime = &endTime
+		backupLog.Duration = int(endTime.Sub(backupLog.StartTime).Seconds())
+	}
+	if backupLog.Checksum == "" {
+		backupLog.Checksum = manifest.Checksum
+	}
+	backupLog.RawSize = manifest.RawSize
+	if manifest.RawSize > 0 && backupLog.FileSize > 0 {
+		backupLog.CompressionRatio = math.Round(float64(manifest.RawSize)/float64(backupLog.FileSize)*100) / 100
+	}
+	backupLog.EncryptionKeyID = manifest.EncryptionKeyID
+	backupLog.ServerVersion = manifest.ServerVersion
+	backupLog.ToolVersion = manifest.ToolVersion
+	if manifest.Binlog != nil {
+		backupLog.BinlogFile = manifest.Binlog.File
+		backupLog.BinlogPosition = manifest.Binlog.Position
+		backupLog.GTIDExecuted = manifest.Binlog.GTIDExecuted
+	}
+	if manifest.SourceHost != "" {
+		backupLog.BackupSource = "replica"
+		backupLog.SourceHost = manifest.SourceHost
+	}
+	if manifest.SourceBinlog != nil {
+		backupLog.SourceBinlogFile = manifest.SourceBinlog.File
+		backupLog.SourceBinlogPos = manifest.SourceBinlog.Position
+	}
+	if manifest.LSN != nil {
+		backupLog.FromLSN = manifest.LSN.From
+		backupLog.ToLSN = manifest.LSN.To
+	}
+	if len(manifest.Databases) > 0 {
+		info := backup.ServerInfo{Version: manifest.ServerVersion, Databases: manifest.Databases}
+		names := make([]string, 0, len(manifest.Databases))
+		for _, db := range manifest.Databases {
+			names = append(names, db.Name)
+		}
+		if content, err := json.Marshal(names); err == nil {
+			backupLog.Databases = string(content)
+		}
+		backupLog.TableCount = info.TableCount()
+		backupLog.RowEstimate = info.RowEstimate()
+	}
+	return nil
+}
+
+// markEntry 将丢失或大小不一致的产物标记为不可用：备份记录完整性状态，副本标记为失败
+func (s *ReconcileService) markEntry(finding *model.ReconcileFinding) error {
+	message := "file is missing from storage"
+	status := "missing"
+	if finding.Kind == findingSizeMismatch {
+		message = fmt.Sprintf("file size %d does not match recorded size %d", finding.FileSize, finding.CatalogSize)
+		status = "corrupted"
+	}
+
+	switch finding.EntryType {
+	case "backup":
+		var backupLog model.BackupLog
+		if err := database.DB.First(&backupLog, finding.BackupLogID).Error; err != nil {
+			return fmt.Errorf("backup log no longer exists")
+		}
+		NewIntegrityService().RecordIntegrity(&backupLog, status)
+		return nil
+	case "copy":
+		result := database.DB.Model(&model.BackupCopy{}).Where("id = ?", finding.BackupCopyID).Updates(map[string]interface{}{
+			"status":        "failed",
+			"error_message": message,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("backup copy no longer exists")
+		}
+		return nil
+	default:
+		return fmt.Errorf("binlog archives cannot be marked, delete the catalog entry instead")
+	}
+}
+
+// deleteEntry 删除有问题的产物及其目录记录。丢失的产物只删除记录和残留的校验和文件、元数据清单
+func (s *ReconcileService) deleteEntry(ctx context.Context, storageInstance storage.Storage, finding *model.ReconcileFinding) error {
+	removeFile := func() error {
+		if finding.Kind == findingSizeMismatch {
+			if err := storageInstance.Delete(ctx, finding.FilePath); err != nil {
+				return fmt.Errorf("failed to delete file: %w", err)
+			}
+		}
+		storageInstance.Delete(ctx, finding.FilePath+backup.ChecksumExtension)
+		storageInstance.Delete(ctx, finding.FilePath+backup.ManifestExtension)
+		return nil
+	}
+
+	switch finding.EntryType {
+	case "backup":
+		var backupLog model.BackupLog
+		if err := database.DB.First(&backupLog, finding.BackupLogID).Error; err != nil {
+			return fmt.Errorf("backup log no longer exists")
+		}
+		if HasDependentBackups(backupLog.ID) {
+			return fmt.Errorf("backup is required by incremental backups, delete them first")
+		}
+		var copies int64
+		database.DB.Model(&model.BackupCopy{}).Where("backup_log_id = ? AND status = ?", backupLog.ID, "success").Count(&copies)
+		if copies > 0 {
+			return fmt.Errorf("backup still has copies in other storages, mark it instead")
+		}
+		if err := removeFile(); err != nil {
+			return err
+		}
+		if err := DeleteBackupCopies(backupLog.ID); err != nil {
+			return err
+		}
+		return database.DB.Delete(&backupLog).Error
+	case "copy":
+		var backupCopy model.BackupCopy
+		if err := database.DB.First(&backupCopy, finding.BackupCopyID).Error; err != nil {
+			return fmt.Errorf("backup copy no longer exists")
+		}
+		if err := removeFile(); err != nil {
+			return err
+		}
+		return database.DB.Delete(&backupCopy).Error
+	default:
+		var binlogFile model.BinlogFile
+		if err := database.DB.First(&binlogFile, finding.BinlogFileID).Error; err != nil {
+			return fmt.Errorf("binlog file no longer exists")
+		}
+		if err := removeFile(); err != nil {
+			return err
+		}
+		return database.DB.Delete(&binlogFile).Error
+	}
+}
+
+// finish 结束对账任务，err不为nil时表示任务失败
+func (s *ReconcileService) finish(job *model.ReconcileJob, err error) {
+	endTime := time.Now()
+	job.EndTime = &endTime
+	if err != nil {
+		job.Status = "failed"
+		job.ErrorMessage = err.Error()
+	} else {
+		job.Status = "success"
+	}
+	database.DB.Save(job)
+	log.Printf("Reconcile job %d of storage %s finished: %s (%d orphans, %d missing, %d size mismatches)",
+		job.ID, job.StorageName, job.Status, job.Orphans, job.Missing, job.Mismatched)
+}
+
+// stepLogger 返回将步骤日志追加到对账任务的回调
+func (s *ReconcileService) stepLogger(job *model.ReconcileJob) func(message string) {
+	return func(message string) {
+		job.Steps += fmt.Sprintf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), message)
+		database.DB.Model(job).Update("steps", job.Steps)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/model"
+)
+
+// replicaBackup 从库备份期间的复制状态，备份结束后负责恢复复制
+type replicaBackup struct {
+	db      *sql.DB
+	status  *backup.ReplicaStatus // 备份开始时（停止SQL线程后）的复制状态
+	stopped bool                  // 是否由本次备份停止了SQL线程
+}
+
+// GetReplicaStatus 查询主机的复制状态，不是从库时返回nil
+func (s *HostService) GetReplicaStatus(host *model.Host) (*backup.ReplicaStatus, error) {
+	db, err := openHostDB(host)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return backup.QueryReplicaStatus(db)
+}
+
+// openHostDB 打开到主机的MySQL连接
+func openHostDB(host *model.Host) (*sql.DB, error) {
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/",
+		host.Username,
+		host.Password,
+		host.Host,
+		host.Port,
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	return db, nil
+}
+
+// prepareReplicaBackup 在从库模式的主机上检查复制状态和延迟，按配置停止SQL线程；
+// 主机不是从库模式时返回nil。复制延迟超过阈值或复制未运行时拒绝备份
+func prepareReplicaBackup(host *model.Host) (*replicaBackup, error) {
+	if host.BackupSource != "replica" {
+		return nil, nil
+	}
+
+	db, err := openHostDB(host)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := backup.QueryReplicaStatus(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if status == nil {
+		db.Close()
+		return nil, fmt.Errorf("host %s is configured as a replica but has no replication configured", host.Name)
+	}
+
+	if host.MaxReplicationLag > 0 {
+		if status.SecondsBehind == nil {
+			db.Close()
+			return nil, fmt.Errorf("replication is not running on %s (IO thread running: %v, SQL thread running: %v), refusing to back up",
+				host.Name, status.IORunning, status.SQLRunning)
+		}
+		if *status.SecondsBehind > int64(host.MaxReplicationLag) {
+			db.Close()
+			return nil, fmt.Errorf("replication lag on %s is %ds, exceeds the limit of %ds, refusing to back up",
+				host.Name, *status.SecondsBehind, host.MaxReplicationLag)
+		}
+	}
+
+	r := &replicaBackup{db: db, status: status}
+	if host.StopReplicaSQL == 1 && status.SQLRunning {
+		if err := backup.StopReplicaSQLThread(db); err != nil {
+			db.Close()
+			return nil, err
+		}
+		r.stopped = true
+		log.Printf("Stopped replica SQL thread on %s for backup", host.Name)
+
+		// 停止后重新读取，得到与备份一致的主库坐标
+		status, err := backup.QueryReplicaStatus(db)
+		if err != nil || status == nil {
+			r.finish()
+			return nil, fmt.Errorf("failed to read replica status after stopping SQL thread: %v", err)
+		}
+		r.status = status
+	}
+	return r, nil
+}
+
+// finish 恢复被停止的SQL线程并返回与备份一致的主库坐标。停止了SQL线程时坐标取停止后的状态；
+// 否则只有备份前后坐标没有变化时才可信，变化时返回nil
+func (r *replicaBackup) finish() (*backup.BinlogCoordinates, error) {
+	defer r.db.Close()
+
+	coords := r.status.SourceCoordinates()
+	if r.stopped {
+		if err := backup.StartReplicaSQLThread(r.db); err != nil {
+			return coords, err
+		}
+		return coords, nil
+	}
+
+	after, err := backup.QueryReplicaStatus(r.db)
+	if err != nil || after == nil || after.SourceLogFile != r.status.SourceLogFile || after.SourceLogPos != r.status.SourceLogPos {
+		return nil, nil
+	}
+	return coords, nil
+}
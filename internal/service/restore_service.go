@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RestoreService 恢复服务
+type RestoreService struct{}
+
+// NewRestoreService 创建恢复服务实例
+func NewRestoreService() *RestoreService {
+	return &RestoreService{}
+}
+
+// RestoreRequest 恢复请求
+type RestoreRequest struct {
+	HostID         uint                   `json:"host_id" binding:"required"` // 恢复目标主机
+	TargetDatabase string                 `json:"target_database"`            // 目标数据库，为空表示使用备份中的库名
+	Options        map[string]interface{} `json:"options"`                    // 额外选项
+	TargetTime     string                 `json:"target_time"`                // 时间点恢复的目标时间，与target_gtid二选一
+	TargetGTID     string                 `json:"target_gtid"`                // 时间点恢复的目标GTID（恢复到该事务为止）
+	HostOptions    map[string]interface{} `json:"-"`                          // 来自已保存配置（如恢复验证策略）的目标服务器选项，为空时使用任务备份选项中的配置
+	AllowDecrypt   bool                   `json:"-"`                          // 是否允许解密加密的备份，仅管理员或系统发起的恢复为true
+}
+
+// ErrDecryptNotAllowed 无权解密加密备份时返回的错误
+var ErrDecryptNotAllowed = errors.New("only administrators can restore encrypted backups")
+
+// restoreHostOptions 在目标服务器上执行命令或决定操作路径的恢复选项，只能来自已保存的配置，不能由恢复请求指定
+var restoreHostOptions = []string{"ssh_config", "datadir", "owner", "stop_command", "start_command"}
+
+// taskHostOptions 返回任务备份选项中restore配置的目标服务器选项（xtrabackup恢复时的ssh_config、datadir、属主和启停命令）。
+// 顶层的ssh_config是备份源服务器，不能作为恢复目标
+func taskHostOptions(task *model.Task) map[string]interface{} {
+	taskOptions := make(map[string]interface{})
+	if task.BackupOptions == "" || json.Unmarshal([]byte(task.BackupOptions), &taskOptions) != nil {
+		return nil
+	}
+	hostOptions := make(map[string]interface{})
+	if restoreOptions, ok := taskOptions["restore"].(map[string]interface{}); ok {
+		for key, value := range restoreOptions {
+			hostOptions[key] = value
+		}
+	}
+	return hostOptions
+}
+
+// restoreTargetOptions 返回xtrabackup恢复的目标服务器选项，优先使用请求中已保存配置的选项，否则使用任务的restore配置；
+// 必须为恢复目标单独配置ssh_config，且其主机与恢复目标主机一致，避免在其他服务器上停库和覆盖datadir
+func restoreTargetOptions(task *model.Task, req *RestoreRequest, host *model.Host) (map[string]interface{}, error) {
+	hostOptions := req.HostOptions
+	if hostOptions == nil {
+		hostOptions = taskHostOptions(task)
+	}
+	sshConfig := parseSSHConfig(hostOptions)
+	if sshConfig == nil || sshConfig.Host == "" {
+		return nil, fmt.Errorf("xtrabackup restore requires ssh_config of the target server, configure restore.ssh_config in the task's backup options")
+	}
+	if !strings.EqualFold(strings.TrimSpace(sshConfig.Host), strings.TrimSpace(host.Host)) {
+		return nil, fmt.Errorf("restore ssh_config host %s does not match target host %s (%s)", sshConfig.Host, host.Name, host.Host)
+	}
+	return hostOptions, nil
+}
+
+// splitHostOptions 将已保存的恢复选项拆分为普通选项和目标服务器选项，没有目标服务器选项时后者为nil
+func splitHostOptions(options map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	var hostOptions map[string]interface{}
+	rest := make(map[string]interface{}, len(options))
+	for key, value := range options {
+		rest[key] = value
+	}
+	for _, key := range restoreHostOptions {
+		if value, ok := rest[key]; ok {
+			if hostOptions == nil {
+				hostOptions = make(map[string]interface{})
+			}
+			hostOptions[key] = value
+			delete(rest, key)
+		}
+	}
+	return rest, hostOptions
+}
+
+// TaskRestoreCommands 返回任务备份选项和恢复验证策略中配置的恢复启停命令，未配置时为空，
+// 用于判断修改任务是否需要管理员权限
+func TaskRestoreCommands(task *model.Task) string {
+	optionSets := []map[string]interface{}{taskHostOptions(task)}
+	var policy verifyPolicy
+	if task.VerifyPolicy != "" && json.Unmarshal([]byte(task.VerifyPolicy), &policy) == nil {
+		optionSets = append(optionSets, policy.RestoreOptions)
+	}
+
+	var commands []string
+	configured := false
+	for _, options := range optionSets {
+		for _, key := range []string{"stop_command", "start_command"} {
+			command := getStringValue(options, key)
+			configured = configured || command != ""
+			commands = append(commands, command)
+		}
+	}
+	if !configured {
+		return ""
+	}
+	return strings.Join(commands, "\n")
+}
+
+// restoreJob 一次恢复所需的记录和配置
+type restoreJob struct {
+	restoreLog *model.RestoreLog
+	backupLog  *model.BackupLog
+	task       *model.Task
+	host       *model.Host
+	req        *RestoreRequest
+	plan       *pitrPlan
+}
+
+// StartRestore 创建恢复记录并在后台执行恢复
+func (s *RestoreService) StartRestore(backupLogID uint, req *RestoreRequest) (*model.RestoreLog, error) {
+	job, err := s.newRestoreJob(backupLogID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	go s.executeRestore(context.Background(), job.restoreLog, job.backupLog, job.task, job.host, job.req, job.plan)
+
+	return job.restoreLog, nil
+}
+
+// RunRestore 创建恢复记录并同步执行恢复，返回执行完成后的恢复记录
+func (s *RestoreService) RunRestore(ctx context.Context, backupLogID uint, req *RestoreRequest) (*model.RestoreLog, error) {
+	job, err := s.newRestoreJob(backupLogID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.executeRestore(ctx, job.restoreLog, job.backupLog, job.task, job.host, job.req, job.plan)
+
+	return job.restoreLog, nil
+}
+
+// newRestoreJob 校验恢复请求并创建恢复记录
+func (s *RestoreService) newRestoreJob(backupLogID uint, req *RestoreRequest) (*restoreJob, error) {
+	// 加载备份日志
+	var backupLog model.BackupLog
+	if err := database.DB.First(&backupLog, backupLogID).Error; err != nil {
+		return nil, fmt.Errorf("backup log not found: %w", err)
+	}
+	if backupLog.Status != "success" || backupLog.FilePath == "" {
+		return nil, fmt.Errorf("backup file not available")
+	}
+
+	for _, key := range restoreHostOptions {
+		if _, ok := req.Options[key]; ok {
+			return nil, fmt.Errorf("option %s cannot be set in the request, configure it in the task's backup options", key)
+		}
+	}
+	if req.TargetDatabase != "" && !backup.ValidDatabaseName(req.TargetDatabase) {
+		return nil, fmt.Errorf("invalid target_database %q", req.TargetDatabase)
+	}
+
+	// 加载任务和存储信息
+	var task model.Task
+	if err := database.DB.Preload("Storage").First(&task, backupLog.TaskID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load task: %w", err)
+	}
+	if task.Storage == nil {
+		return nil, fmt.Errorf("storage of task %s not found", task.Name)
+	}
+
+	// 时间点恢复：在同一任务的备份中重新选择目标点之前最近的全量备份
+	var plan *pitrPlan
+	if req.TargetTime != "" || req.TargetGTID != "" {
+		if req.TargetTime != "" && req.TargetGTID != "" {
+			return nil, fmt.Errorf("target_time and target_gtid cannot be used together")
+		}
+
+		var targetTime *time.Time
+		if req.TargetTime != "" {
+			t, err := parseTargetTime(req.TargetTime)
+			if err != nil {
+				return nil, err
+			}
+			targetTime = t
+		} else if !backup.ValidGTID(req.TargetGTID) {
+			return nil, fmt.Errorf("invalid target_gtid %q", req.TargetGTID)
+		}
+
+		p, err := planPointInTime(&task, targetTime, req.TargetGTID)
+		if err != nil {
+			return nil, err
+		}
+		plan = p
+		backupLog = *plan.BaseLog
+	}
+
+	// 检查是否支持该备份类型的恢复
+	if _, err := backup.NewRestoreExecutor(backupLog.BackupType); err != nil {
+		return nil, err
+	}
+
+	// 恢复会解密备份链中的加密产物，与下载一样只允许管理员
+	if !req.AllowDecrypt && BackupChainEncrypted(&backupLog) {
+		return nil, ErrDecryptNotAllowed
+	}
+
+	// 加载目标主机
+	var host model.Host
+	if err := database.DB.First(&host, req.HostID).Error; err != nil {
+		return nil, fmt.Errorf("target host not found: %w", err)
+	}
+	if backupLog.BackupType == "xtrabackup" {
+		if _, err := restoreTargetOptions(&task, req, &host); err != nil {
+			return nil, err
+		}
+	}
+
+	restoreLog := &model.RestoreLog{
+		BackupLogID:    backupLog.ID,
+		TaskID:         task.ID,
+		TaskName:       task.Name,
+		SourceHostName: backupLog.HostName,
+		HostID:         host.ID,
+		HostName:       host.Name,
+		TargetDatabase: req.TargetDatabase,
+		BackupType:     backupLog.BackupType,
+		FilePath:       backupLog.FilePath,
+		RestoreType:    "full",
+		Status:         "running",
+		StartTime:      time.Now(),
+	}
+	if plan != nil {
+		restoreLog.RestoreType = "pitr"
+		restoreLog.TargetTime = plan.TargetTime
+		restoreLog.TargetGTID = plan.TargetGTID
+	}
+	if err := database.DB.Create(restoreLog).Error; err != nil {
+		return nil, fmt.Errorf("failed to create restore log: %w", err)
+	}
+
+	return &restoreJob{
+		restoreLog: restoreLog,
+		backupLog:  &backupLog,
+		task:       &task,
+		host:       &host,
+		req:        req,
+		plan:       plan,
+	}, nil
+}
+
+// executeRestore 执行恢复并更新恢复记录
+func (s *RestoreService) executeRestore(ctx context.Context, restoreLog *model.RestoreLog, backupLog *model.BackupLog, task *model.Task, host *model.Host, req *RestoreRequest, plan *pitrPlan) {
+	log.Printf("Starting restore: backup %d -> host %s (restore ID: %d)", backupLog.ID, host.Name, restoreLog.ID)
+
+	err := s.performRestore(ctx, restoreLog, backupLog, task, host, req, plan)
+
+	endTime := time.Now()
+	restoreLog.EndTime = &endTime
+	restoreLog.Duration = int(endTime.Sub(restoreLog.StartTime).Seconds())
+
+	if err != nil {
+		restoreLog.Status = "failed"
+		restoreLog.ErrorMessage = err.Error()
+		database.DB.Save(restoreLog)
+		log.Printf("Restore failed (restore ID: %d): %v", restoreLog.ID, err)
+		return
+	}
+
+	restoreLog.Status = "success"
+	restoreLog.Progress = 100
+	database.DB.Save(restoreLog)
+	log.Printf("Restore completed (restore ID: %d)", restoreLog.ID)
+}
+
+// performRestore 下载备份文件并执行恢复，时间点恢复时再重放binlog
+func (s *RestoreService) performRestore(ctx context.Context, restoreLog *model.RestoreLog, backupLog *model.BackupLog, task *model.Task, host *model.Host, req *RestoreRequest, plan *pitrPlan) error {
+	// 创建临时目录
+	tmpDir := filepath.Join("./data/tmp", fmt.Sprintf("restore_%d_%d", restoreLog.ID, time.Now().Unix()))
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir) // 清理临时目录
+
+	// 增量/差异备份需要整条备份链，从全量备份开始依次下载；主存储不可用时从副本存储下载
+	chain, err := backupChain(backupLog)
+	if err != nil {
+		return err
+	}
+
+	var localPaths []string
+	downloadStartTime := time.Now()
+	for i, chainLog := range chain {
+		chainDir := filepath.Join(tmpDir, fmt.Sprintf("chain_%d", i))
+		if err := os.MkdirAll(chainDir, 0755); err != nil {
+			return fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		localPath := filepath.Join(chainDir, filepath.Base(chainLog.FilePath))
+		if err := DownloadBackupArtifact(ctx, &chainLog, localPath); err != nil {
+			return err
+		}
+		// 加密备份先解密，恢复执行器按解密后的扩展名识别格式
+		if chainLog.EncryptionKeyID != "" {
+			plainPath, err := NewEncryptionService().DecryptFile(localPath, chainLog.EncryptionKeyID)
+			if err != nil {
+				return fmt.Errorf("failed to decrypt backup %d: %w", chainLog.ID, err)
+			}
+			localPath = plainPath
+		}
+		localPaths = append(localPaths, localPath)
+	}
+	restoreLog.DownloadTime = int(time.Since(downloadStartTime).Seconds())
+	if len(chain) > 1 {
+		s.stepLogger(restoreLog)(fmt.Sprintf("Downloaded backup chain of %d backups (base: %d)", len(chain), chain[0].ID))
+	}
+
+	// 创建恢复执行器
+	executor, err := backup.NewRestoreExecutor(backupLog.BackupType)
+	if err != nil {
+		return err
+	}
+
+	params := &backup.RestoreParams{
+		Host:             host.Host,
+		Port:             host.Port,
+		Username:         host.Username,
+		Password:         host.Password,
+		TargetDatabase:   req.TargetDatabase,
+		FilePath:         localPaths[0],
+		IncrementalFiles: localPaths[1:],
+		WorkDir:          tmpDir,
+		Options:          make(map[string]interface{}, len(req.Options)),
+		Progress:         s.progressReporter(restoreLog, plan != nil),
+		StepLog:          s.stepLogger(restoreLog),
+	}
+	for key, value := range req.Options {
+		params.Options[key] = value
+	}
+
+	// xtrabackup恢复的SSH配置、datadir和启停命令只使用已保存的恢复目标配置
+	if backupLog.BackupType == "xtrabackup" {
+		hostOptions, err := restoreTargetOptions(task, req, host)
+		if err != nil {
+			return err
+		}
+		params.SSHConfig = parseSSHConfig(hostOptions)
+		for key, value := range hostOptions {
+			if key != "ssh_config" {
+				params.Options[key] = value
+			}
+		}
+	}
+	if backupLog.Databases != "" {
+		if err := json.Unmarshal([]byte(backupLog.Databases), &params.Databases); err != nil {
+			log.Printf("Failed to parse databases: %v", err)
+		}
+	}
+
+	// 执行恢复并记录时间
+	restoreStartTime := time.Now()
+	result, err := executor.Restore(ctx, params)
+	restoreLog.RestoreTime = int(time.Since(restoreStartTime).Seconds())
+
+	if err != nil {
+		return fmt.Errorf("restore execution failed: %w", err)
+	}
+
+	restoreLog.Command = result.Command
+
+	if plan != nil {
+		return s.replayBinlogs(ctx, restoreLog, plan, host, req, tmpDir)
+	}
+	return nil
+}
+
+// progressReporter 返回将进度写入恢复记录的回调，进度变化时才写库；
+// 时间点恢复时全量恢复占前90%，剩余为binlog重放
+func (s *RestoreService) progressReporter(restoreLog *model.RestoreLog, pitr bool) backup.ProgressFunc {
+	return func(percent int, message string) {
+		if pitr {
+			percent = percent * 9 / 10
+		}
+		if percent == restoreLog.Progress && message == restoreLog.CurrentStep {
+			return
+		}
+		changed := percent != restoreLog.Progress
+		restoreLog.Progress = percent
+		restoreLog.CurrentStep = message
+		if changed {
+			database.DB.Model(restoreLog).Updates(map[string]interface{}{
+				"progress":     percent,
+				"current_step": message,
+			})
+		}
+	}
+}
+
+// stepLogger 返回将步骤日志追加到恢复记录的回调
+func (s *RestoreService) stepLogger(restoreLog *model.RestoreLog) func(message string) {
+	return func(message string) {
+		restoreLog.Steps += fmt.Sprintf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), message)
+		database.DB.Model(restoreLog).Update("steps", restoreLog.Steps)
+	}
+}
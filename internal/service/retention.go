@@ -0,0 +1,184 @@
+package service
+
+import (
+	"fmt"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"time"
+)
+
+// 保留原因
+const (
+	keepReasonRetentionDays = "retention_days" // 在保留天数内
+	keepReasonDaily         = "daily"
+	keepReasonWeekly        = "weekly"
+	keepReasonMonthly       = "monthly"
+	keepReasonYearly        = "yearly"
+	keepReasonMinKeep       = "min_keep" // 最近的N个成功备份
+	keepReasonChain         = "chain"    // 被保留的增量/差异备份依赖
+	keepReasonPromoted      = "promoted" // 主存储产物已清理，由副本按自己的保留期保留
+	keepReasonForever       = "forever"  // 未配置任何保留规则，永久保留
+)
+
+// RetentionPolicy 备份保留策略。配置了GFS规则时，备份满足任一规则即保留，
+// RetentionDays此时表示额外保留最近N天内的全部备份，0表示只按GFS规则保留；
+// 未配置GFS规则时RetentionDays为0表示永久保留
+type RetentionPolicy struct {
+	RetentionDays int `json:"retention_days"`
+	KeepDaily     int `json:"keep_daily"`   // 最近N个有备份的日期，每天保留最新的一个
+	KeepWeekly    int `json:"keep_weekly"`  // 最近N个有备份的周（ISO周）
+	KeepMonthly   int `json:"keep_monthly"` // 最近N个有备份的月
+	KeepYearly    int `json:"keep_yearly"`  // 最近N个有备份的年
+	MinKeep       int `json:"min_keep"`     // 无论规则如何都保留的最近成功备份数
+}
+
+// TaskRetentionPolicy 返回任务配置的保留策略
+func TaskRetentionPolicy(task *model.Task) RetentionPolicy {
+	return RetentionPolicy{
+		RetentionDays: task.RetentionDays,
+		KeepDaily:     task.KeepDaily,
+		KeepWeekly:    task.KeepWeekly,
+		KeepMonthly:   task.KeepMonthly,
+		KeepYearly:    task.KeepYearly,
+		MinKeep:       task.MinKeep,
+	}
+}
+
+// gfs 是否配置了GFS规则
+func (p RetentionPolicy) gfs() bool {
+	return p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.KeepYearly > 0
+}
+
+// prunes 策略是否会清理备份
+func (p RetentionPolicy) prunes() bool {
+	return p.RetentionDays > 0 || p.gfs()
+}
+
+// ValidateRetentionPolicy 检查任务的保留策略
+func ValidateRetentionPolicy(task *model.Task) error {
+	p := TaskRetentionPolicy(task)
+	if p.RetentionDays < 0 || p.KeepDaily < 0 || p.KeepWeekly < 0 || p.KeepMonthly < 0 || p.KeepYearly < 0 || p.MinKeep < 0 {
+		return fmt.Errorf("retention settings must not be negative")
+	}
+	return nil
+}
+
+// RetentionDecision 保留策略对一个成功备份的判定
+type RetentionDecision struct {
+	BackupLogID uint      `json:"backup_log_id"`
+	StartTime   time.Time `json:"start_time"`
+	BackupMode  string    `json:"backup_mode"`
+	FilePath    string    `json:"file_path"`
+	FileSize    int64     `json:"file_size"`
+	StorageName string    `json:"storage_name"`
+	Keep        bool      `json:"keep"`
+	Reasons     []string  `json:"reasons"` // 保留原因，删除时为空
+}
+
+// RetentionPlan 保留策略的执行计划
+type RetentionPlan struct {
+	TaskID      uint                `json:"task_id"`
+	Policy      RetentionPolicy     `json:"policy"`
+	Keep        []RetentionDecision `json:"keep"`
+	Delete      []RetentionDecision `json:"delete"`
+	DeleteBytes int64               `json:"delete_bytes"`
+	protected   map[uint]bool       // 被保留的增量/差异备份依赖的备份ID
+}
+
+// gfsRule 一条GFS规则，bucket返回备份所属的时间段
+type gfsRule struct {
+	reason string
+	count  int
+	bucket func(t time.Time) string
+}
+
+// PlanRetention 按保留策略计算任务的哪些成功备份保留、哪些删除，不做任何修改
+func PlanRetention(task *model.Task, policy RetentionPolicy, now time.Time) *RetentionPlan {
+	plan := &RetentionPlan{TaskID: task.ID, Policy: policy, protected: map[uint]bool{}}
+
+	var logs []model.BackupLog
+	database.DB.Where("task_id = ? AND status = ?", task.ID, "success").Order("start_time DESC").Find(&logs)
+
+	reasons := make(map[uint][]string, len(logs))
+	if !policy.prunes() {
+		for _, l := range logs {
+			reasons[l.ID] = []string{keepReasonForever}
+		}
+	} else {
+		// 保留天数内的全部备份和最近的N个备份
+		expireTime := now.AddDate(0, 0, -policy.RetentionDays)
+		for i, l := range logs {
+			if i < policy.MinKeep {
+				reasons[l.ID] = append(reasons[l.ID], keepReasonMinKeep)
+			}
+			if policy.RetentionDays > 0 && !l.StartTime.Before(expireTime) {
+				reasons[l.ID] = append(reasons[l.ID], keepReasonRetentionDays)
+			}
+		}
+
+		// GFS规则：每个时间段保留最新的一个备份，共保留最近N个时间段
+		rules := []gfsRule{
+			{keepReasonDaily, policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+			{keepReasonWeekly, policy.KeepWeekly, func(t time.Time) string {
+				year, week := t.ISOWeek()
+				return fmt.Sprintf("%d-W%02d", year, week)
+			}},
+			{keepReasonMonthly, policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+			{keepReasonYearly, policy.KeepYearly, func(t time.Time) string { return t.Format("2006") }},
+		}
+		for _, rule := range rules {
+			seen := make(map[string]bool)
+			for _, l := range logs {
+				if len(seen) >= rule.count {
+					break
+				}
+				bucket := rule.bucket(l.StartTime.Local())
+				if seen[bucket] {
+					continue
+				}
+				seen[bucket] = true
+				reasons[l.ID] = append(reasons[l.ID], rule.reason)
+			}
+		}
+
+		// 已由副本保留的备份不由主存储的保留策略清理
+		for id := range promotedBackupLogs(task.ID) {
+			reasons[id] = append(reasons[id], keepReasonPromoted)
+		}
+	}
+
+	// 被保留的增量/差异备份依赖的上游备份必须保留
+	var kept []model.BackupLog
+	for _, l := range logs {
+		if len(reasons[l.ID]) > 0 {
+			kept = append(kept, l)
+		}
+	}
+	plan.protected = chainDependencies(kept)
+	for id := range plan.protected {
+		if len(reasons[id]) == 0 {
+			reasons[id] = []string{keepReasonChain}
+		}
+	}
+
+	for _, l := range logs {
+		decision := RetentionDecision{
+			BackupLogID: l.ID,
+			StartTime:   l.StartTime,
+			BackupMode:  l.BackupMode,
+			FilePath:    l.FilePath,
+			FileSize:    l.FileSize,
+			StorageName: l.StorageName,
+			Keep:        len(reasons[l.ID]) > 0,
+			Reasons:     reasons[l.ID],
+		}
+		if decision.Keep {
+			plan.Keep = append(plan.Keep, decision)
+		} else {
+			decision.Reasons = []string{}
+			plan.Delete = append(plan.Delete, decision)
+			plan.DeleteBytes += l.FileSize
+		}
+	}
+	return plan
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mbmanager/internal/model"
+	"strings"
+	"time"
+)
+
+// ErrBackupTimeout 备份超过任务的最长运行时间
+var ErrBackupTimeout = errors.New("backup exceeded maximum runtime")
+
+// 可重试的错误类别
+const (
+	retryClassNetwork = "network" // 连接中断、超时等网络错误
+	retryClassLock    = "lock"    // 锁等待超时、死锁
+	retryClassStorage = "storage" // 上传到存储失败
+	retryClassTimeout = "timeout" // 超过最长运行时间
+	retryClassOther   = "other"
+	retryClassAll     = "all"
+)
+
+// maxRetryDelay 重试等待时间的上限
+const maxRetryDelay = time.Hour
+
+var (
+	networkErrorPatterns = []string{
+		"connection refused", "connection reset", "broken pipe", "i/o timeout",
+		"no route to host", "network is unreachable", "unexpected eof", "invalid connection",
+		"server has gone away", "lost connection", "handshake failed", "failed to connect ssh",
+		"tls handshake timeout", "temporary failure in name resolution",
+	}
+	lockErrorPatterns = []string{
+		"lock wait timeout", "deadlock", "metadata lock", "unable to obtain lock",
+	}
+)
+
+// classifyBackupError 判断备份错误的类别
+func classifyBackupError(err error) string {
+	if errors.Is(err, ErrBackupTimeout) {
+		return retryClassTimeout
+	}
+
+	message := strings.ToLower(err.Error())
+	for _, pattern := range lockErrorPatterns {
+		if strings.Contains(message, pattern) {
+			return retryClassLock
+		}
+	}
+	for _, pattern := range networkErrorPatterns {
+		if strings.Contains(message, pattern) {
+			return retryClassNetwork
+		}
+	}
+	if strings.Contains(message, "failed to upload") {
+		return retryClassStorage
+	}
+	return retryClassOther
+}
+
+// shouldRetry 按任务配置判断该类别的错误是否值得重试
+func shouldRetry(task *model.Task, class string) bool {
+	for _, item := range strings.Split(task.RetryOn, ",") {
+		item = strings.TrimSpace(item)
+		if item == retryClassAll || item == class {
+			return true
+		}
+	}
+	return false
+}
+
+// retryDelay 第attempt次尝试失败后的等待时间，按指数退避
+func retryDelay(task *model.Task, attempt int) time.Duration {
+	delay := time.Duration(task.RetryBackoff) * time.Second
+	if delay <= 0 {
+		delay = time.Minute
+	}
+	for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// ValidateRetryPolicy 检查任务的超时和重试配置
+func ValidateRetryPolicy(task *model.Task) error {
+	if task.MaxRuntime < 0 {
+		return fmt.Errorf("max_runtime must not be negative")
+	}
+	if task.RetryMaxAttempts < 0 || task.RetryMaxAttempts > 10 {
+		return fmt.Errorf("retry_max_attempts must be between 0 and 10 (0 and 1 both mean no retry)")
+	}
+	if task.RetryBackoff < 0 {
+		return fmt.Errorf("retry_backoff must not be negative")
+	}
+	for _, item := range strings.Split(task.RetryOn, ",") {
+		switch strings.TrimSpace(item) {
+		case "", retryClassNetwork, retryClassLock, retryClassStorage, retryClassTimeout, retryClassAll:
+		default:
+			return fmt.Errorf("unsupported retry error class: %s", strings.TrimSpace(item))
+		}
+	}
+	return nil
+}
+
+// newRunID 生成一次执行的标识
+func newRunID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
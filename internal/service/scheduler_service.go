@@ -15,11 +15,15 @@ import (
 
 // SchedulerService 调度服务
 type SchedulerService struct {
-	scheduler   gocron.Scheduler
-	backupSvc   *BackupService
-	taskJobs    map[uint]gocron.Job // 任务ID -> Job映射
-	taskLocks   sync.Map            // 任务锁，防止并发执行
-	mu          sync.RWMutex
+	scheduler     gocron.Scheduler
+	backupSvc     *BackupService
+	binlogSvc     *BinlogService
+	verifySvc     *VerifyService
+	taskJobs      map[uint]gocron.Job         // 任务ID -> Job映射
+	verifyJobs    map[uint]gocron.Job         // 任务ID -> 定期恢复验证Job映射
+	binlogStreams map[uint]context.CancelFunc // 任务ID -> binlog归档取消函数
+	taskLocks     sync.Map                    // 任务锁，防止并发执行
+	mu            sync.RWMutex
 }
 
 // NewSchedulerService 创建调度服务实例
@@ -30,9 +34,13 @@ func NewSchedulerService(backupSvc *BackupService) (*SchedulerService, error) {
 	}
 
 	return &SchedulerService{
-		scheduler: scheduler,
-		backupSvc: backupSvc,
-		taskJobs:  make(map[uint]gocron.Job),
+		scheduler:     scheduler,
+		backupSvc:     backupSvc,
+		binlogSvc:     NewBinlogService(),
+		verifySvc:     NewVerifyService(),
+		taskJobs:      make(map[uint]gocron.Job),
+		verifyJobs:    make(map[uint]gocron.Job),
+		binlogStreams: make(map[uint]context.CancelFunc),
 	}, nil
 }
 
@@ -63,6 +71,15 @@ func (s *SchedulerService) Start(ctx context.Context) error {
 // Stop 停止调度器
 func (s *SchedulerService) Stop() error {
 	log.Println("Stopping scheduler service...")
+
+	// 停止所有binlog归档
+	s.mu.Lock()
+	for taskID, cancel := range s.binlogStreams {
+		cancel()
+		delete(s.binlogStreams, taskID)
+	}
+	s.mu.Unlock()
+
 	return s.scheduler.Shutdown()
 }
 
@@ -78,6 +95,20 @@ func (s *SchedulerService) AddTask(task *model.Task) error {
 		}
 		delete(s.taskJobs, task.ID)
 	}
+	if cancel, exists := s.binlogStreams[task.ID]; exists {
+		cancel()
+		delete(s.binlogStreams, task.ID)
+	}
+	s.removeVerifyJob(task.ID)
+
+	// binlog归档任务是常驻任务，不通过gocron调度
+	if task.ScheduleType == "binlog" {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.binlogStreams[task.ID] = cancel
+		go s.binlogSvc.Run(ctx, task.ID)
+		log.Printf("Binlog archiving started: %s (ID: %d)", task.Name, task.ID)
+		return nil
+	}
 
 	// 解析调度配置
 	var scheduleConfig map[string]interface{}
@@ -160,6 +191,11 @@ func (s *SchedulerService) AddTask(task *model.Task) error {
 	// 保存任务映射
 	s.taskJobs[task.ID] = job
 
+	// 定期恢复验证
+	if err := s.addVerifyJob(task); err != nil {
+		log.Printf("Failed to schedule verification for task %s: %v", task.Name, err)
+	}
+
 	// 更新下次执行时间
 	if nextRun, err := job.NextRun(); err == nil {
 		task.NextRunAt = &nextRun
@@ -175,6 +211,15 @@ func (s *SchedulerService) RemoveTask(taskID uint) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	s.removeVerifyJob(taskID)
+
+	if cancel, exists := s.binlogStreams[taskID]; exists {
+		cancel()
+		delete(s.binlogStreams, taskID)
+		log.Printf("Binlog archiving stopped: ID %d", taskID)
+		return nil
+	}
+
 	job, exists := s.taskJobs[taskID]
 	if !exists {
 		return fmt.Errorf("task not found in scheduler")
@@ -208,8 +253,15 @@ func (s *SchedulerService) RunTaskNow(taskID uint) error {
 		return fmt.Errorf("failed to load task: %w", err)
 	}
 
+	if task.ScheduleType == "binlog" {
+		return fmt.Errorf("binlog archiving task runs continuously and cannot be run manually")
+	}
+
 	// 检查任务锁
 	if _, loaded := s.taskLocks.LoadOrStore(taskID, true); loaded {
+		if logID, ok := RunningBackupLogID(taskID); ok {
+			return fmt.Errorf("task is already running (backup log %d)", logID)
+		}
 		return fmt.Errorf("task is already running")
 	}
 	defer s.taskLocks.Delete(taskID)
@@ -260,6 +312,108 @@ func (s *SchedulerService) createJobTask(task *model.Task) gocron.Task {
 	})
 }
 
+// addVerifyJob 按任务的验证策略添加定期恢复验证作业（调用方持有锁）
+func (s *SchedulerService) addVerifyJob(task *model.Task) error {
+	policy, err := parseVerifyPolicy(task)
+	if err != nil {
+		return err
+	}
+	if policy == nil || policy.Cron == "" {
+		return nil
+	}
+
+	taskID := task.ID
+	job, err := s.scheduler.NewJob(
+		gocron.CronJob(policy.Cron, false),
+		gocron.NewTask(func() {
+			if err := s.verifySvc.VerifyLatest(context.Background(), taskID); err != nil {
+				log.Printf("Scheduled verification for task %d failed: %v", taskID, err)
+			}
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create verify job: %w", err)
+	}
+
+	s.verifyJobs[task.ID] = job
+	return nil
+}
+
+// removeVerifyJob 移除任务的定期恢复验证作业（调用方持有锁）
+func (s *SchedulerService) removeVerifyJob(taskID uint) {
+	if job, exists := s.verifyJobs[taskID]; exists {
+		if err := s.scheduler.RemoveJob(job.ID()); err != nil {
+			log.Printf("Failed to remove verify job: %v", err)
+		}
+		delete(s.verifyJobs, taskID)
+	}
+}
+
+// AddScrubJob 添加备份完整性巡检作业，cronExpr为空时不巡检
+func (s *SchedulerService) AddScrubJob(cronExpr string, batch int) error {
+	if cronExpr == "" {
+		return nil
+	}
+
+	integritySvc := NewIntegrityService()
+	_, err := s.scheduler.NewJob(
+		gocron.CronJob(cronExpr, false),
+		gocron.NewTask(func() {
+			integritySvc.Scrub(context.Background(), batch)
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create scrub job: %w", err)
+	}
+
+	log.Printf("Backup scrub scheduled: %s (batch: %d)", cronExpr, batch)
+	return nil
+}
+
+// AddQuotaJob 添加存储容量检查作业，cronExpr为空时只在备份后检查
+func (s *SchedulerService) AddQuotaJob(cronExpr string) error {
+	if cronExpr == "" {
+		return nil
+	}
+
+	_, err := s.scheduler.NewJob(
+		gocron.CronJob(cronExpr, false),
+		gocron.NewTask(func() {
+			EnforceAllStorageQuotas(context.Background())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create quota job: %w", err)
+	}
+
+	log.Printf("Storage quota check scheduled: %s", cronExpr)
+	return nil
+}
+
+// AddReconcileJob 添加定期对账所有存储的任务
+func (s *SchedulerService) AddReconcileJob(cronExpr string) error {
+	if cronExpr == "" {
+		return nil
+	}
+
+	_, err := s.scheduler.NewJob(
+		gocron.CronJob(cronExpr, false),
+		gocron.NewTask(func() {
+			NewReconcileService().ReconcileAll(context.Background())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create reconcile job: %w", err)
+	}
+
+	log.Printf("Storage reconcile scheduled: %s", cronExpr)
+	return nil
+}
+
 // GetNextRunTime 获取任务下次执行时间
 func (s *SchedulerService) GetNextRunTime(taskID uint) (*time.Time, error) {
 	s.mu.RLock()
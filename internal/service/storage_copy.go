@@ -0,0 +1,414 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"mbmanager/internal/storage"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CopyStorage 任务的副本存储配置
+type CopyStorage struct {
+	StorageID     uint `json:"storage_id"`
+	RetentionDays int  `json:"retention_days"` // 0表示随主备份一起清理
+}
+
+// parseCopyStorages 解析任务的副本存储配置
+func parseCopyStorages(task *model.Task) ([]CopyStorage, error) {
+	if strings.TrimSpace(task.CopyStorages) == "" {
+		return nil, nil
+	}
+	var copies []CopyStorage
+	if err := json.Unmarshal([]byte(task.CopyStorages), &copies); err != nil {
+		return nil, fmt.Errorf("invalid copy_storages: %w", err)
+	}
+	return copies, nil
+}
+
+// ValidateCopyStorages 检查副本存储配置，副本存储必须存在且不能与主存储或其他副本重复
+func ValidateCopyStorages(task *model.Task) error {
+	copies, err := parseCopyStorages(task)
+	if err != nil {
+		return err
+	}
+
+	seen := map[uint]bool{task.StorageID: true}
+	for _, c := range copies {
+		if c.RetentionDays < 0 {
+			return fmt.Errorf("retention_days of copy storage %d must not be negative", c.StorageID)
+		}
+		if seen[c.StorageID] {
+			return fmt.Errorf("copy storage %d duplicates the primary storage or another copy", c.StorageID)
+		}
+		seen[c.StorageID] = true
+
+		var storageModel model.Storage
+		if err := database.DB.First(&storageModel, c.StorageID).Error; err != nil {
+			return fmt.Errorf("copy storage %d not found", c.StorageID)
+		}
+	}
+	return nil
+}
+
+// uploadCopies 将本地备份文件并行上传到任务的各副本存储。副本上传后读回计算校验和，
+// 与主存储的校验和一致时才记为成功；返回的副本记录尚未关联备份日志
+func (s *BackupService) uploadCopies(ctx context.Context, task *model.Task, localPath, remotePath, checksum, tmpDir string) []model.BackupCopy {
+	copies, err := parseCopyStorages(task)
+	if err != nil {
+		log.Printf("Failed to parse copy storages of task %s: %v", task.Name, err)
+		return nil
+	}
+
+	results := make([]model.BackupCopy, len(copies))
+	var wg sync.WaitGroup
+	for i, c := range copies {
+		wg.Add(1)
+		go func(i int, c CopyStorage) {
+			defer wg.Done()
+			workDir := filepath.Join(tmpDir, fmt.Sprintf("copy_%d", c.StorageID))
+			results[i] = s.uploadCopy(ctx, task, c, localPath, remotePath, checksum, workDir)
+			if results[i].Status != "success" {
+				log.Printf("Copy of %s to storage %d failed: %s", remotePath, c.StorageID, results[i].ErrorMessage)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+// uploadStreamCopies 流式备份没有本地产物，从主存储下载后上传到副本存储
+func (s *BackupService) uploadStreamCopies(ctx context.Context, task *model.Task, primary storage.Storage, remotePath, checksum, tmpDir string) []model.BackupCopy {
+	copies, err := parseCopyStorages(task)
+	if err != nil {
+		log.Printf("Failed to parse copy storages of task %s: %v", task.Name, err)
+		return nil
+	}
+	if len(copies) == 0 {
+		return nil
+	}
+
+	workDir := filepath.Join(tmpDir, "stream_copy")
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return failedCopies(task, copies, fmt.Sprintf("failed to create temp directory: %v", err))
+	}
+	defer os.RemoveAll(workDir)
+
+	localPath := filepath.Join(workDir, filepath.Base(remotePath))
+	if err := primary.Download(ctx, remotePath, localPath); err != nil {
+		return failedCopies(task, copies, fmt.Sprintf("failed to read back primary artifact: %v", err))
+	}
+	return s.uploadCopies(ctx, task, localPath, remotePath, checksum, tmpDir)
+}
+
+// failedCopies 构造全部失败的副本记录
+func failedCopies(task *model.Task, copies []CopyStorage, message string) []model.BackupCopy {
+	results := make([]model.BackupCopy, len(copies))
+	for i, c := range copies {
+		results[i] = model.BackupCopy{
+			TaskID:        task.ID,
+			StorageID:     c.StorageID,
+			RetentionDays: c.RetentionDays,
+			Status:        "failed",
+			ErrorMessage:  message,
+		}
+		var storageModel model.Storage
+		if database.DB.First(&storageModel, c.StorageID).Error == nil {
+			results[i].StorageType = storageModel.Type
+			results[i].StorageName = storageModel.Name
+		}
+	}
+	log.Printf("Copies of task %s failed: %s", task.Name, message)
+	return results
+}
+
+// discardCopies 删除未记录的副本文件（主存储上传失败时），忽略错误
+func discardCopies(copies []model.BackupCopy) {
+	for _, backupCopy := range copies {
+		if backupCopy.FilePath == "" {
+			continue
+		}
+		var storageModel model.Storage
+		if database.DB.First(&storageModel, backupCopy.StorageID).Error == nil {
+			deleteFromStorage(&storageModel, backupCopy.FilePath)
+		}
+	}
+}
+
+// uploadCopy 上传到一个副本存储并校验
+func (s *BackupService) uploadCopy(ctx context.Context, task *model.Task, c CopyStorage, localPath, remotePath, checksum, workDir string) model.BackupCopy {
+	backupCopy := model.BackupCopy{
+		TaskID:        task.ID,
+		StorageID:     c.StorageID,
+		FilePath:      remotePath,
+		RetentionDays: c.RetentionDays,
+		Status:        "failed",
+	}
+
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, c.StorageID).Error; err != nil {
+		backupCopy.ErrorMessage = fmt.Sprintf("failed to load storage: %v", err)
+		return backupCopy
+	}
+	backupCopy.StorageType = storageModel.Type
+	backupCopy.StorageName = storageModel.Name
+
+	storageInstance, err := newStorageInstance(&storageModel)
+	if err != nil {
+		backupCopy.ErrorMessage = err.Error()
+		return backupCopy
+	}
+
+	if err := storageInstance.Upload(ctx, localPath, remotePath); err != nil {
+		backupCopy.ErrorMessage = fmt.Sprintf("failed to upload: %v", err)
+		return backupCopy
+	}
+	if err := uploadChecksumFile(ctx, storageInstance, remotePath, checksum); err != nil {
+		log.Printf("Failed to upload checksum of %s to storage %s: %v", remotePath, storageModel.Name, err)
+	}
+
+	// 读回副本校验，确认副本与主存储中的产物一致
+	actual, size, err := remoteChecksum(ctx, storageInstance, remotePath, workDir)
+	if err != nil {
+		backupCopy.ErrorMessage = fmt.Sprintf("failed to verify: %v", err)
+		return backupCopy
+	}
+	now := time.Now()
+	backupCopy.Checksum = actual
+	backupCopy.FileSize = size
+	backupCopy.VerifiedAt = &now
+	if actual != checksum {
+		backupCopy.ErrorMessage = fmt.Sprintf("checksum mismatch: expected %s, got %s", checksum, actual)
+		return backupCopy
+	}
+
+	backupCopy.Status = "success"
+	return backupCopy
+}
+
+// remoteChecksum 下载存储中的文件，返回其校验和与大小
+func remoteChecksum(ctx context.Context, storageInstance storage.Storage, remotePath, workDir string) (string, int64, error) {
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return "", 0, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	localPath := filepath.Join(workDir, filepath.Base(remotePath))
+	if err := storageInstance.Download(ctx, remotePath, localPath); err != nil {
+		return "", 0, fmt.Errorf("failed to download: %w", err)
+	}
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return "", 0, err
+	}
+	checksum, err := backup.FileChecksum(localPath)
+	if err != nil {
+		return "", 0, err
+	}
+	return checksum, info.Size(), nil
+}
+
+// saveBackupCopies 记录备份日志的副本
+func saveBackupCopies(backupLogID uint, copies []model.BackupCopy) {
+	for i := range copies {
+		copies[i].BackupLogID = backupLogID
+		if err := database.DB.Create(&copies[i]).Error; err != nil {
+			log.Printf("Failed to save backup copy: %v", err)
+		}
+	}
+}
+
+// backupLogStorage 返回备份产物所在的存储，没有记录存储的旧备份使用任务的存储
+func backupLogStorage(backupLog *model.BackupLog) (*model.Storage, error) {
+	storageID := backupLog.StorageID
+	if storageID == 0 {
+		var task model.Task
+		if err := database.DB.First(&task, backupLog.TaskID).Error; err != nil {
+			return nil, fmt.Errorf("failed to load task: %w", err)
+		}
+		storageID = task.StorageID
+	}
+
+	var storageModel model.Storage
+	if err := database.DB.First(&storageModel, storageID).Error; err != nil {
+		return nil, fmt.Errorf("storage %d of backup %d not found", storageID, backupLog.ID)
+	}
+	return &storageModel, nil
+}
+
+// DownloadBackupArtifact 下载备份产物到本地，主存储下载失败时依次尝试校验成功的副本
+func DownloadBackupArtifact(ctx context.Context, backupLog *model.BackupLog, localPath string) error {
+	var errs []string
+
+	storageModel, err := backupLogStorage(backupLog)
+	if err == nil {
+		if err = downloadFromStorage(ctx, storageModel, backupLog.FilePath, localPath); err == nil {
+			return nil
+		}
+		err = fmt.Errorf("%s: %w", storageModel.Name, err)
+	}
+	errs = append(errs, err.Error())
+
+	var copies []model.BackupCopy
+	database.DB.Where("backup_log_id = ? AND status = ?", backupLog.ID, "success").Find(&copies)
+	for _, backupCopy := range copies {
+		var copyStorage model.Storage
+		if err := database.DB.First(&copyStorage, backupCopy.StorageID).Error; err != nil {
+			errs = append(errs, fmt.Sprintf("copy storage %d not found", backupCopy.StorageID))
+			continue
+		}
+		if err := downloadFromStorage(ctx, &copyStorage, backupCopy.FilePath, localPath); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", copyStorage.Name, err))
+			continue
+		}
+		log.Printf("Downloaded backup %d from copy storage %s", backupLog.ID, copyStorage.Name)
+		return nil
+	}
+
+	return fmt.Errorf("failed to download backup %d: %s", backupLog.ID, strings.Join(errs, "; "))
+}
+
+// downloadFromStorage 从指定存储下载文件
+func downloadFromStorage(ctx context.Context, storageModel *model.Storage, remotePath, localPath string) error {
+	storageInstance, err := newStorageInstance(storageModel)
+	if err != nil {
+		return err
+	}
+	return storageInstance.Download(ctx, remotePath, localPath)
+}
+
+// deleteFromStorage 删除存储中的备份产物及其校验和文件和元数据清单（旧备份没有这些文件，忽略错误）
+func deleteFromStorage(storageModel *model.Storage, remotePath string) error {
+	storageInstance, err := newStorageInstance(storageModel)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if err := storageInstance.Delete(ctx, remotePath); err != nil {
+		return err
+	}
+	storageInstance.Delete(ctx, remotePath+backup.ChecksumExtension)
+	storageInstance.Delete(ctx, remotePath+backup.ManifestExtension)
+	return nil
+}
+
+// deleteBackupCopy 删除副本文件和记录。提升为主存储的副本文件由备份日志管理，只删除记录；
+// 校验失败的副本尽量删除文件
+func deleteBackupCopy(backupCopy *model.BackupCopy) error {
+	var storageModel model.Storage
+	if backupCopy.Status != "promoted" && database.DB.First(&storageModel, backupCopy.StorageID).Error == nil {
+		err := deleteFromStorage(&storageModel, backupCopy.FilePath)
+		if err != nil && backupCopy.Status == "success" {
+			return err
+		}
+	}
+	return database.DB.Delete(backupCopy).Error
+}
+
+// DeleteBackupCopies 删除备份日志的所有副本
+func DeleteBackupCopies(backupLogID uint) error {
+	var copies []model.BackupCopy
+	database.DB.Where("backup_log_id = ?", backupLogID).Find(&copies)
+	for i := range copies {
+		if err := deleteBackupCopy(&copies[i]); err != nil {
+			return fmt.Errorf("failed to delete copy in storage %s: %w", copies[i].StorageName, err)
+		}
+	}
+	return nil
+}
+
+// copyExpired 副本是否超过自己的保留期，未设置保留天数的副本随主备份一起清理，不单独过期
+func copyExpired(backupCopy *model.BackupCopy) bool {
+	days := backupCopy.RetentionDays
+	return days > 0 && backupCopy.CreatedAt.Before(time.Now().AddDate(0, 0, -days))
+}
+
+// promotedBackupLogs 返回任务中主存储产物已过期、由副本提升为主存储的备份日志ID
+func promotedBackupLogs(taskID uint) map[uint]bool {
+	var ids []uint
+	database.DB.Model(&model.BackupCopy{}).Where("task_id = ? AND status = ?", taskID, "promoted").Pluck("backup_log_id", &ids)
+
+	promoted := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		promoted[id] = true
+	}
+	return promoted
+}
+
+// cleanupExpiredCopies 删除任务中超过各自保留期的副本。提升为主存储的副本过期时连同备份日志一起删除，
+// protected中的备份日志仍被增量/差异备份依赖，不删除
+func (s *BackupService) cleanupExpiredCopies(task *model.Task, protected map[uint]bool) {
+	var copies []model.BackupCopy
+	database.DB.Where("task_id = ?", task.ID).Find(&copies)
+
+	deleted := 0
+	for i := range copies {
+		backupCopy := &copies[i]
+		if !copyExpired(backupCopy) {
+			continue
+		}
+
+		if backupCopy.Status == "promoted" {
+			if protected[backupCopy.BackupLogID] {
+				continue
+			}
+			var backupLog model.BackupLog
+			if err := database.DB.First(&backupLog, backupCopy.BackupLogID).Error; err == nil {
+				if err := s.DeleteBackupArtifacts(&backupLog); err != nil {
+					log.Printf("Failed to delete expired backup %d: %v", backupLog.ID, err)
+				}
+				database.DB.Delete(&backupLog)
+			}
+			database.DB.Delete(backupCopy)
+			deleted++
+			continue
+		}
+
+		if err := deleteBackupCopy(backupCopy); err != nil {
+			log.Printf("Failed to delete expired copy %d: %v", backupCopy.ID, err)
+			continue
+		}
+		deleted++
+	}
+	if deleted > 0 {
+		log.Printf("Cleaned up %d expired backup copies for task %s", deleted, task.Name)
+	}
+}
+
+// promoteBackupCopy 主存储中的产物过期删除后，将单独设置了保留天数且未过期的副本中保留期最长的一个
+// 提升为备份日志的主存储，备份日志此后按该副本的保留期清理；没有这样的副本时返回false
+func promoteBackupCopy(backupLog *model.BackupLog) bool {
+	var copies []model.BackupCopy
+	database.DB.Where("backup_log_id = ? AND status = ?", backupLog.ID, "success").Find(&copies)
+
+	var best *model.BackupCopy
+	for i := range copies {
+		if copies[i].RetentionDays <= 0 || copyExpired(&copies[i]) {
+			continue
+		}
+		if best == nil || copies[i].RetentionDays > best.RetentionDays {
+			best = &copies[i]
+		}
+	}
+	if best == nil {
+		return false
+	}
+
+	backupLog.StorageID = best.StorageID
+	backupLog.StorageType = best.StorageType
+	backupLog.StorageName = best.StorageName
+	backupLog.FilePath = best.FilePath
+	database.DB.Save(backupLog)
+	database.DB.Model(best).Update("status", "promoted")
+	log.Printf("Primary artifact of backup %d expired, promoted copy in storage %s", backupLog.ID, best.StorageName)
+	return true
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+)
+
+// parseTablePatterns 解析任务中JSON数组格式的表过滤模式
+func parseTablePatterns(value string) ([]string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	var patterns []string
+	if err := json.Unmarshal([]byte(value), &patterns); err != nil {
+		return nil, fmt.Errorf("failed to parse table patterns: %w", err)
+	}
+	return patterns, nil
+}
+
+// resolveTableFilter 根据源库当前的库表解析任务的表过滤模式，未配置过滤时返回nil
+func resolveTableFilter(task *model.Task, host *model.Host, databases []string) (*backup.TableFilter, error) {
+	include, err := parseTablePatterns(task.IncludeTables)
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := parseTablePatterns(task.ExcludeTables)
+	if err != nil {
+		return nil, err
+	}
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil, nil
+	}
+
+	tables, err := NewHostService().GetTables(host, databases)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tables for filtering: %w", err)
+	}
+	return backup.ResolveTableFilter(tables, include, exclude)
+}
+
+// ValidateTableFilters 校验任务的表过滤模式语法，并确认每个模式在源库中都能匹配到表
+func ValidateTableFilters(task *model.Task) error {
+	include, err := parseTablePatterns(task.IncludeTables)
+	if err != nil {
+		return err
+	}
+	exclude, err := parseTablePatterns(task.ExcludeTables)
+	if err != nil {
+		return err
+	}
+	if len(include) == 0 && len(exclude) == 0 {
+		return nil
+	}
+	for _, pattern := range append(include, exclude...) {
+		if _, err := backup.CompileTablePattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	var databases []string
+	if task.Databases != "" {
+		if err := json.Unmarshal([]byte(task.Databases), &databases); err != nil {
+			return fmt.Errorf("failed to parse databases: %w", err)
+		}
+	}
+
+	var host model.Host
+	if err := database.DB.First(&host, task.HostID).Error; err != nil {
+		return fmt.Errorf("host not found: %w", err)
+	}
+	filter, err := resolveTableFilter(task, &host, databases)
+	if err != nil {
+		return err
+	}
+	if filter != nil && len(filter.UnmatchedPatterns) > 0 {
+		return fmt.Errorf("table pattern %q matches no table", filter.UnmatchedPatterns[0])
+	}
+	return nil
+}
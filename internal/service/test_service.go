@@ -90,6 +90,73 @@ func (s *HostService) GetDatabases(host *model.Host) ([]string, error) {
 	return databases, nil
 }
 
+// GetTables 获取数据库中的表（不含视图），databases为空表示全部非系统库，返回库名 -> 表名列表
+func (s *HostService) GetTables(host *model.Host, databases []string) (map[string][]string, error) {
+	if len(databases) == 0 {
+		var err error
+		if databases, err = s.GetDatabases(host); err != nil {
+			return nil, err
+		}
+	}
+
+	// 构建DSN
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/",
+		host.Username,
+		host.Password,
+		host.Host,
+		host.Port,
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer db.Close()
+
+	tables := make(map[string][]string)
+	for _, dbName := range databases {
+		rows, err := db.Query("SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'", dbName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tables of %s: %w", dbName, err)
+		}
+		tables[dbName] = []string{}
+		for rows.Next() {
+			var table string
+			if err := rows.Scan(&table); err != nil {
+				continue
+			}
+			tables[dbName] = append(tables[dbName], table)
+		}
+		rows.Close()
+	}
+
+	return tables, nil
+}
+
+// IsBinlogEnabled 检查服务器是否开启binlog
+func (s *HostService) IsBinlogEnabled(host *model.Host) (bool, error) {
+	// 构建DSN
+	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/",
+		host.Username,
+		host.Password,
+		host.Host,
+		host.Port,
+	)
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return false, fmt.Errorf("failed to open connection: %w", err)
+	}
+	defer db.Close()
+
+	var logBin int
+	if err := db.QueryRow("SELECT @@log_bin").Scan(&logBin); err != nil {
+		return false, fmt.Errorf("failed to query log_bin: %w", err)
+	}
+
+	return logBin == 1, nil
+}
+
 // StorageService 存储服务
 type StorageService struct{}
 
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mbmanager/internal/backup"
+	"mbmanager/internal/database"
+	"mbmanager/internal/model"
+	"strings"
+	"time"
+)
+
+// VerifyService 备份恢复验证服务
+type VerifyService struct {
+	restoreSvc *RestoreService
+}
+
+// NewVerifyService 创建恢复验证服务实例
+func NewVerifyService() *VerifyService {
+	return &VerifyService{restoreSvc: NewRestoreService()}
+}
+
+// verifyPolicy 恢复验证策略（Task.VerifyPolicy）
+type verifyPolicy struct {
+	Enabled        bool                   `json:"enabled"`
+	SandboxHostID  uint                   `json:"sandbox_host_id"` // 用于恢复验证的沙箱主机
+	AfterBackup    bool                   `json:"after_backup"`    // 备份成功后立即验证
+	Cron           string                 `json:"cron"`            // 定期验证最新备份的cron表达式，为空表示不定期验证
+	Checksum       bool                   `json:"checksum"`        // 比较CHECKSUM TABLE，只适用于备份期间无写入的表
+	DropAfter      bool                   `json:"drop_after"`      // 验证后删除沙箱中恢复的库（逻辑备份）
+	RestoreOptions map[string]interface{} `json:"restore_options"` // 传给恢复执行器的选项
+}
+
+// parseVerifyPolicy 解析任务的恢复验证策略，未启用时返回nil
+func parseVerifyPolicy(task *model.Task) (*verifyPolicy, error) {
+	if task.VerifyPolicy == "" {
+		return nil, nil
+	}
+
+	var policy verifyPolicy
+	if err := json.Unmarshal([]byte(task.VerifyPolicy), &policy); err != nil {
+		return nil, fmt.Errorf("failed to parse verify policy: %w", err)
+	}
+	if !policy.Enabled {
+		return nil, nil
+	}
+	if policy.SandboxHostID == 0 {
+		return nil, fmt.Errorf("sandbox_host_id is required in verify policy")
+	}
+	if policy.SandboxHostID == task.HostID {
+		return nil, fmt.Errorf("sandbox host must not be the backup source host")
+	}
+	return &policy, nil
+}
+
+// AfterBackup 备份成功后采集验证基准，策略要求时在后台启动恢复验证
+func (s *VerifyService) AfterBackup(task *model.Task, host *model.Host, backupLog *model.BackupLog) {
+	s.CaptureBaseline(task, host, backupLog)
+
+	policy, err := parseVerifyPolicy(task)
+	if err != nil || policy == nil || !policy.AfterBackup {
+		return
+	}
+	go func() {
+		if err := s.VerifyBackup(context.Background(), backupLog.ID); err != nil {
+			log.Printf("Verification of backup %d failed: %v", backupLog.ID, err)
+		}
+	}()
+}
+
+// CaptureBaseline 备份成功后采集源库的表数量和校验和，作为验证基准
+func (s *VerifyService) CaptureBaseline(task *model.Task, host *model.Host, backupLog *model.BackupLog) {
+	policy, err := parseVerifyPolicy(task)
+	if err != nil {
+		log.Printf("Invalid verify policy of task %s: %v", task.Name, err)
+		return
+	}
+	if policy == nil {
+		return
+	}
+
+	var databases []string
+	if backupLog.Databases != "" {
+		json.Unmarshal([]byte(backupLog.Databases), &databases)
+	}
+
+	snapshot, err := backup.CaptureSnapshot(host.Host, host.Port, host.Username, host.Password, databases, policy.Checksum)
+	if err != nil {
+		log.Printf("Failed to capture verify baseline for backup %d: %v", backupLog.ID, err)
+		return
+	}
+
+	// 表级过滤排除的表不在备份中
+	filter, err := resolveTableFilter(task, host, databases)
+	if err != nil {
+		log.Printf("Failed to resolve table filters for backup %d: %v", backupLog.ID, err)
+		return
+	}
+	snapshot.Exclude(filter)
+
+	baseline, _ := json.Marshal(snapshot)
+	backupLog.VerifyBaseline = string(baseline)
+	database.DB.Model(backupLog).Update("verify_baseline", backupLog.VerifyBaseline)
+}
+
+// VerifyBackup 将备份恢复到沙箱主机并与备份时的基准比较，结果写入备份日志
+func (s *VerifyService) VerifyBackup(ctx context.Context, backupLogID uint) error {
+	var backupLog model.BackupLog
+	if err := database.DB.First(&backupLog, backupLogID).Error; err != nil {
+		return fmt.Errorf("backup log not found: %w", err)
+	}
+	if backupLog.Status != "success" {
+		return fmt.Errorf("only successful backups can be verified")
+	}
+	if backupLog.VerifyStatus == "verifying" {
+		return fmt.Errorf("backup is already being verified")
+	}
+
+	var task model.Task
+	if err := database.DB.First(&task, backupLog.TaskID).Error; err != nil {
+		return fmt.Errorf("failed to load task: %w", err)
+	}
+	policy, err := parseVerifyPolicy(&task)
+	if err != nil {
+		return err
+	}
+	if policy == nil {
+		return fmt.Errorf("verify policy is not enabled for task %s", task.Name)
+	}
+
+	var sandbox model.Host
+	if err := database.DB.First(&sandbox, policy.SandboxHostID).Error; err != nil {
+		return fmt.Errorf("sandbox host not found: %w", err)
+	}
+
+	database.DB.Model(&backupLog).Update("verify_status", "verifying")
+	log.Printf("Verifying backup %d in sandbox %s", backupLog.ID, sandbox.Name)
+
+	message, verifyErr := s.restoreAndCompare(ctx, &backupLog, policy, &sandbox)
+
+	now := time.Now()
+	status := "verified"
+	if verifyErr != nil {
+		status = "failed"
+		message = verifyErr.Error()
+	}
+	database.DB.Model(&backupLog).Updates(map[string]interface{}{
+		"verify_status":  status,
+		"verify_message": message,
+		"verified_at":    &now,
+	})
+
+	log.Printf("Backup %d verification %s: %s", backupLog.ID, status, message)
+	return verifyErr
+}
+
+// VerifyLatest 验证任务最新的成功备份
+func (s *VerifyService) VerifyLatest(ctx context.Context, taskID uint) error {
+	var backupLog model.BackupLog
+	if err := database.DB.Where("task_id = ? AND status = ?", taskID, "success").
+		Order("start_time DESC").First(&backupLog).Error; err != nil {
+		return fmt.Errorf("no successful backup found for task %d", taskID)
+	}
+	return s.VerifyBackup(ctx, backupLog.ID)
+}
+
+// restoreAndCompare 恢复到沙箱并比较快照，返回验证说明
+func (s *VerifyService) restoreAndCompare(ctx context.Context, backupLog *model.BackupLog, policy *verifyPolicy, sandbox *model.Host) (string, error) {
+	options, hostOptions := splitHostOptions(policy.RestoreOptions)
+	req := &RestoreRequest{
+		HostID:      sandbox.ID,
+		Options:     options,
+		HostOptions: hostOptions,
+		// 恢复验证由管理员配置的策略或定时任务发起，需要解密加密备份
+		AllowDecrypt: true,
+	}
+	restoreLog, err := s.restoreSvc.RunRestore(ctx, backupLog.ID, req)
+	if err != nil {
+		return "", fmt.Errorf("failed to start restore: %w", err)
+	}
+	if restoreLog.Status != "success" {
+		return "", fmt.Errorf("restore %d failed: %s", restoreLog.ID, restoreLog.ErrorMessage)
+	}
+
+	// 没有基准时只能确认可以恢复
+	if backupLog.VerifyBaseline == "" {
+		return fmt.Sprintf("restore %d succeeded, no baseline to compare", restoreLog.ID), nil
+	}
+
+	var expected backup.SchemaSnapshot
+	if err := json.Unmarshal([]byte(backupLog.VerifyBaseline), &expected); err != nil {
+		return "", fmt.Errorf("failed to parse baseline: %w", err)
+	}
+
+	var databases []string
+	for dbName := range expected.Databases {
+		databases = append(databases, dbName)
+	}
+
+	actual, err := backup.CaptureSnapshot(sandbox.Host, sandbox.Port, sandbox.Username, sandbox.Password, databases, policy.Checksum)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect sandbox: %w", err)
+	}
+
+	// 逻辑备份恢复的库在验证后清理，物理备份覆盖整个实例无需清理
+	if policy.DropAfter && backupLog.BackupType != "xtrabackup" {
+		if err := backup.DropDatabases(sandbox.Host, sandbox.Port, sandbox.Username, sandbox.Password, databases); err != nil {
+			log.Printf("Failed to clean up sandbox %s: %v", sandbox.Name, err)
+		}
+	}
+
+	if diffs := backup.CompareSnapshot(&expected, actual); len(diffs) > 0 {
+		return "", fmt.Errorf("restore %d does not match baseline: %s", restoreLog.ID, strings.Join(diffs, "; "))
+	}
+
+	return fmt.Sprintf("restore %d matched baseline (%d databases)", restoreLog.ID, len(databases)), nil
+}
@@ -65,6 +65,40 @@ func (s *LocalStorage) Upload(ctx context.Context, localPath string, remotePath
 	return nil
 }
 
+func (s *LocalStorage) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	dstPath := filepath.Join(s.basePath, remotePath)
+
+	// 创建目标目录
+	dstDir := filepath.Dir(dstPath)
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	// 先写入临时文件，完成后再重命名，避免留下不完整的文件
+	partPath := dstPath + ".part"
+	dstFile, err := os.Create(partPath)
+	if err != nil {
+		return fmt.Errorf("failed to create destination file: %w", err)
+	}
+
+	if _, err := io.Copy(dstFile, reader); err != nil {
+		dstFile.Close()
+		os.Remove(partPath)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := dstFile.Close(); err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	if err := os.Rename(partPath, dstPath); err != nil {
+		os.Remove(partPath)
+		return fmt.Errorf("failed to rename file: %w", err)
+	}
+
+	return nil
+}
+
 func (s *LocalStorage) Download(ctx context.Context, remotePath string, localPath string) error {
 	srcPath := filepath.Join(s.basePath, remotePath)
 
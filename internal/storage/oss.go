@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
@@ -62,6 +64,52 @@ func (s *OSSStorage) Upload(ctx context.Context, localPath string, remotePath st
 	return nil
 }
 
+// ossPartSize OSS流式上传的分片大小
+const ossPartSize = 32 * 1024 * 1024
+
+func (s *OSSStorage) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	imur, err := s.bucket.InitiateMultipartUpload(remotePath)
+	if err != nil {
+		return fmt.Errorf("failed to initiate multipart upload: %w", err)
+	}
+
+	var parts []oss.UploadPart
+	buf := make([]byte, ossPartSize)
+	for partNumber := 1; ; partNumber++ {
+		if err := ctx.Err(); err != nil {
+			s.bucket.AbortMultipartUpload(imur)
+			return err
+		}
+
+		n, readErr := io.ReadFull(reader, buf)
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			s.bucket.AbortMultipartUpload(imur)
+			return fmt.Errorf("failed to read stream: %w", readErr)
+		}
+
+		// 空数据流也需要上传一个分片
+		if n > 0 || partNumber == 1 {
+			part, err := s.bucket.UploadPart(imur, bytes.NewReader(buf[:n]), int64(n), partNumber)
+			if err != nil {
+				s.bucket.AbortMultipartUpload(imur)
+				return fmt.Errorf("failed to upload part %d: %w", partNumber, err)
+			}
+			parts = append(parts, part)
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if _, err := s.bucket.CompleteMultipartUpload(imur, parts); err != nil {
+		s.bucket.AbortMultipartUpload(imur)
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
 func (s *OSSStorage) Download(ctx context.Context, remotePath string, localPath string) error {
 	err := s.bucket.GetObjectToFile(remotePath, localPath)
 	if err != nil {
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -92,6 +93,21 @@ func (s *S3Storage) Upload(ctx context.Context, localPath string, remotePath str
 	return nil
 }
 
+func (s *S3Storage) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	// s3manager对不可seek的数据流自动使用分片上传，失败时中止分片
+	_, err := s.uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(remotePath),
+		Body:   reader,
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to upload stream: %w", err)
+	}
+
+	return nil
+}
+
 func (s *S3Storage) Download(ctx context.Context, remotePath string, localPath string) error {
 	file, err := os.Create(localPath)
 	if err != nil {
@@ -172,6 +172,65 @@ func (s *SSHStorage) Upload(ctx context.Context, localPath string, remotePath st
 	return nil
 }
 
+func (s *SSHStorage) UploadStream(ctx context.Context, reader io.Reader, remotePath string) error {
+	client, err := s.connectSSH()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	// 构建完整的远程路径
+	fullRemotePath := filepath.Join(s.basePath, remotePath)
+	partPath := fullRemotePath + ".part"
+
+	// 创建远程目录
+	remoteDir := filepath.Dir(fullRemotePath)
+	if err := s.executeCommand(client, fmt.Sprintf("mkdir -p %s", remoteDir)); err != nil {
+		return fmt.Errorf("failed to create remote directory: %w", err)
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Close()
+
+	// 长度未知无法使用SCP协议，使用cat写入临时文件；数据源出错时SSH仍会正常关闭stdin，
+	// 所以只在读取到完整数据且cat成功退出后才单独执行重命名
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open stdin: %w", err)
+	}
+	if err := session.Start(fmt.Sprintf("cat > %s", partPath)); err != nil {
+		return fmt.Errorf("failed to start upload: %w", err)
+	}
+
+	// ctx取消时关闭会话，终止远程写入
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	_, err = io.Copy(stdin, reader)
+	if err != nil {
+		session.Close()
+	} else {
+		stdin.Close()
+	}
+	if waitErr := session.Wait(); err == nil {
+		err = waitErr
+	}
+	stop()
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err == nil {
+		err = s.executeCommand(client, fmt.Sprintf("mv %s %s", partPath, fullRemotePath))
+	}
+	if err != nil {
+		s.executeCommand(client, fmt.Sprintf("rm -f %s %s", partPath, fullRemotePath))
+		return fmt.Errorf("failed to upload stream: %w", err)
+	}
+
+	return nil
+}
+
 func (s *SSHStorage) Download(ctx context.Context, remotePath string, localPath string) error {
 	client, err := s.connectSSH()
 	if err != nil {
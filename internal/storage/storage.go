@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 )
 
@@ -10,6 +11,8 @@ import (
 type Storage interface {
 	// Upload 上传文件
 	Upload(ctx context.Context, localPath string, remotePath string) error
+	// UploadStream 从数据流上传文件，用于不落本地磁盘的流式备份
+	UploadStream(ctx context.Context, reader io.Reader, remotePath string) error
 	// Download 下载文件
 	Download(ctx context.Context, remotePath string, localPath string) error
 	// Delete 删除文件
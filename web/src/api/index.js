@@ -13,7 +13,8 @@ export const hostAPI = {
   create: (data) => request.post('/hosts', data),
   update: (id, data) => request.put(`/hosts/${id}`, data),
   delete: (id) => request.delete(`/hosts/${id}`),
-  test: (id) => request.post(`/hosts/${id}/test`)
+  test: (id) => request.post(`/hosts/${id}/test`),
+  replicaStatus: (id) => request.get(`/hosts/${id}/replica-status`)
 }
 
 // 任务API
@@ -25,6 +26,8 @@ export const taskAPI = {
   delete: (id) => request.delete(`/tasks/${id}`),
   run: (id) => request.post(`/tasks/${id}/run`),
   logs: (id, params) => request.get(`/tasks/${id}/logs`, { params }),
+  binlogs: (id, params) => request.get(`/tasks/${id}/binlogs`, { params }),
+  retentionPreview: (id, params) => request.get(`/tasks/${id}/retention/preview`, { params }),
   deleteBackup: (logId) => request.delete(`/backups/${logId}`)
 }
 
@@ -36,7 +39,10 @@ export const storageAPI = {
   update: (id, data) => request.put(`/storages/${id}`, data),
   delete: (id) => request.delete(`/storages/${id}`),
   test: (id) => request.post(`/storages/${id}/test`),
-  getDiskSpace: (id) => request.get(`/storages/${id}/diskspace`)
+  getDiskSpace: (id) => request.get(`/storages/${id}/diskspace`),
+  getQuota: (id) => request.get(`/storages/${id}/quota`),
+  enforceQuota: (id) => request.post(`/storages/${id}/quota/enforce`),
+  reconcile: (id) => request.post(`/storages/${id}/reconcile`)
 }
 
 // 通知API
@@ -51,13 +57,47 @@ export const notificationAPI = {
 
 // 日志API
 export const logAPI = {
-  list: (params) => request.get('/logs', { params })
+  list: (params) => request.get('/logs', { params }),
+  progress: (id) => request.get(`/logs/${id}/progress`),
+  cancel: (id) => request.post(`/logs/${id}/cancel`)
 }
 
 // 备份API
 export const backupAPI = {
   delete: (id) => request.delete(`/backups/${id}`),
-  download: (id) => request.get(`/backups/${id}/download`, { responseType: 'blob' })
+  download: (id) => request.get(`/backups/${id}/download`, { responseType: 'blob' }),
+  copies: (id) => request.get(`/backups/${id}/copies`),
+  restore: (id, data) => request.post(`/backups/${id}/restore`, data),
+  restoreTest: (id) => request.post(`/backups/${id}/restore-test`),
+  verify: (id) => request.post(`/backups/${id}/verify`),
+  migrate: (data) => request.post('/backups/migrate', data)
+}
+
+// 备份迁移API
+export const migrationAPI = {
+  list: (params) => request.get('/migrations', { params }),
+  get: (id) => request.get(`/migrations/${id}`)
+}
+
+// 存储对账API
+export const reconcileAPI = {
+  list: (params) => request.get('/reconciles', { params }),
+  get: (id) => request.get(`/reconciles/${id}`),
+  resolve: (id, data) => request.post(`/reconciles/${id}/resolve`, data)
+}
+
+// 恢复API
+export const restoreAPI = {
+  list: (params) => request.get('/restores', { params }),
+  get: (id) => request.get(`/restores/${id}`)
+}
+
+// 加密密钥API
+export const encryptionKeyAPI = {
+  list: () => request.get('/encryption-keys'),
+  create: (data) => request.post('/encryption-keys', data),
+  update: (id, data) => request.put(`/encryption-keys/${id}`, data),
+  delete: (id) => request.delete(`/encryption-keys/${id}`)
 }
 
 // 用户API